@@ -1,45 +1,283 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/trungnt1811/simple-order-book/internal/allocation"
+	"github.com/trungnt1811/simple-order-book/internal/breaker"
+	"github.com/trungnt1811/simple-order-book/internal/calendar"
+	"github.com/trungnt1811/simple-order-book/internal/clock"
 	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/fee"
+	"github.com/trungnt1811/simple-order-book/internal/instrument"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/journal"
+	"github.com/trungnt1811/simple-order-book/internal/model"
 	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/recovery"
+	"github.com/trungnt1811/simple-order-book/internal/replay"
+	"github.com/trungnt1811/simple-order-book/internal/risk"
+	"github.com/trungnt1811/simple-order-book/internal/session"
+	"github.com/trungnt1811/simple-order-book/internal/settlement"
+	"github.com/trungnt1811/simple-order-book/internal/storage/memory"
+	"github.com/trungnt1811/simple-order-book/internal/storage/sqlite"
 	"github.com/trungnt1811/simple-order-book/internal/util"
 	"github.com/trungnt1811/simple-order-book/worker"
 )
 
 func main() {
+	replayPath := flag.String("replay", "", "replay a JSONL command file (\"-\" for stdin) and exit")
+	outPath := flag.String("out", "-", "output file for replay results (\"-\" for stdout)")
+	journalDir := flag.String("journal-dir", "", "directory of the write-ahead command journal (disabled if empty)")
+	fsyncPolicy := flag.String("fsync", string(journal.FsyncAlways), "journal fsync policy: always, interval or never")
+	fsyncInterval := flag.Duration("fsync-interval", 100*time.Millisecond, "maximum delay between journal fsyncs with -fsync=interval")
+	segmentSize := flag.Int64("segment-size", 64<<20, "journal segment size in bytes before rotation")
+	snapshotDir := flag.String("snapshot-dir", "", "directory of order book snapshots (defaults to <journal-dir>/snapshots)")
+	snapshotInterval := flag.Duration("snapshot-interval", time.Minute, "interval between order book snapshots")
+	storageDriver := flag.String("storage", "", "order history storage: memory or sqlite (disabled if empty)")
+	sqlitePath := flag.String("sqlite-path", "orderbook.db", "SQLite database file with -storage=sqlite")
+	allocationAlgorithm := flag.String("allocation", string(constant.FIFOAllocation), "sharing of incoming orders between a price level: fifo, pro_rata, pro_rata_top or size_time")
+	accounts := flag.Bool("accounts", false, "require bids to be backed by cash and asks by inventory deposited in customer accounts")
+	feeSchedule := flag.String("fee-schedule", "", "JSON file of maker/taker fee rates, tiers and customer tiers (no fees if empty)")
+	instrumentRules := flag.String("instrument-rules", "", "JSON file of the instrument's tick size, price limits and price band (disabled if empty)")
+	calendarPath := flag.String("calendar", "", "JSON file of the trading session timezone, open, close and market phases (always open and day orders refused if empty)")
+	callAuction := flag.Duration("call-auction", 0, "interval between call auctions of a book trading in periodic auctions instead of continuously (disabled if 0)")
+	circuitBreaker := flag.String("circuit-breaker", "", "JSON file of the trade price move, window and halt duration that halt the book (disabled if empty)")
+	riskLimits := flag.String("risk-limits", "", "JSON file of pre-trade risk limits, default and per customer (disabled if empty)")
+	sessionTimeout := flag.Duration("session-timeout", 30*time.Second, "time without heartbeat before a session is dropped and its orders cancelled")
+	tradeHistory := flag.Int("trade-history", 100_000, "number of recent trades the daemon keeps in memory (all if 0)")
+	flag.Parse()
+
 	logger := util.SetupLogger()
 	defer logger.Sync() // Flushes buffer, if any
 
-	orderBook := module.NewOrderBookUCase(logger)
+	// The daemon and the replay match with the same options
+	opts := []module.Option{}
+	if *accounts {
+		opts = append(opts, module.WithAccounts())
+	}
+
+	// Allocation of incoming orders within a price level
+	allocator, err := allocation.New(constant.Allocation(*allocationAlgorithm))
+	if err != nil {
+		logger.Fatal("Failed to select allocation", zap.Error(err))
+	}
+	opts = append(opts, module.WithAllocator(allocator))
+
+	// Maker/taker fees
+	if *feeSchedule != "" {
+		schedule, err := fee.LoadSchedule(*feeSchedule)
+		if err != nil {
+			logger.Fatal("Failed to load fee schedule", zap.Error(err))
+		}
+		opts = append(opts, module.WithFeeSchedule(schedule))
+	}
+
+	// Session closes of day and good-till-date orders
+	var tradingCalendar *calendar.Calendar
+	if *calendarPath != "" {
+		cal, err := calendar.Load(*calendarPath)
+		if err != nil {
+			logger.Fatal("Failed to load calendar", zap.Error(err))
+		}
+		tradingCalendar = cal
+		opts = append(opts, module.WithCalendar(cal))
+	}
+
+	// Halts on sharp trade price moves
+	if *circuitBreaker != "" {
+		cb, err := breaker.Load(*circuitBreaker)
+		if err != nil {
+			logger.Fatal("Failed to load circuit breaker", zap.Error(err))
+		}
+		opts = append(opts, module.WithCircuitBreaker(cb))
+	}
+
+	// Pre-trade risk checks
+	var riskChecker interfaces.RiskChecker
+	if *riskLimits != "" {
+		checker, err := risk.LoadChecker(*riskLimits)
+		if err != nil {
+			logger.Fatal("Failed to load risk limits", zap.Error(err))
+		}
+		riskChecker = checker
+	}
+
+	// Tick size and price band validation
+	var tradingRules interfaces.TradingRules
+	if *instrumentRules != "" {
+		rules, err := instrument.LoadRules(*instrumentRules)
+		if err != nil {
+			logger.Fatal("Failed to load instrument rules", zap.Error(err))
+		}
+		tradingRules = rules
+
+		// Replay input and output and the logs use the instrument's currency
+		opts = append(opts, module.WithCurrency(rules.Currency))
+	}
+
+	// Batch mode: replay captured commands and exit
+	if *replayPath != "" {
+		if riskChecker != nil {
+			opts = append(opts, module.WithRiskChecker(riskChecker))
+		}
+		if tradingRules != nil {
+			opts = append(opts, module.WithTradingRules(tradingRules))
+		}
+		if err := runReplay(*replayPath, *outPath, logger, opts...); err != nil {
+			logger.Fatal("Replay failed", zap.Error(err))
+		}
+		return
+	}
+
+	// Order lifecycle events are published on the bus
+	bus := event.NewBus()
+	defer bus.Close() // Delivers the queued events before exiting
+
+	// Copy order history and fills to storage for reporting
+	if *storageDriver != "" {
+		store, err := openStorage(*storageDriver, *sqlitePath)
+		if err != nil {
+			logger.Fatal("Failed to open storage", zap.Error(err))
+		}
+		defer store.Close()
+
+		persister := worker.NewPersister(bus, store, logger)
+		stop := persister.Run()
+		defer stop()
+	}
+
+	// Settle trades in the double-entry ledger
+	ledger := settlement.NewLedger()
+	settler := worker.NewSettler(bus, ledger, logger)
+	stopSettler := settler.Run()
+	defer stopSettler()
+
+	// A long running daemon keeps only the recent trades
+	opts = append(opts, module.WithEventBus(bus), module.WithTradeHistory(*tradeHistory))
+
+	var orderBook interfaces.OrderBookUCase
+	if *journalDir != "" {
+		if *snapshotDir == "" {
+			*snapshotDir = filepath.Join(*journalDir, "snapshots")
+		}
+
+		// Restore the book from the last snapshot and the journal
+		var j *journal.Journal
+		var err error
+		orderBook, j, err = recovery.Recover(recovery.Config{
+			Journal: journal.Config{
+				Dir:           *journalDir,
+				SegmentSize:   *segmentSize,
+				Fsync:         journal.FsyncPolicy(*fsyncPolicy),
+				FsyncInterval: *fsyncInterval,
+			},
+			SnapshotDir: *snapshotDir,
+			Risk:        riskChecker,
+			Rules:       tradingRules,
+		}, logger, opts...)
+		if err != nil {
+			logger.Fatal("Failed to recover order book", zap.Error(err))
+		}
+		defer j.Close()
+
+		snapshotter := worker.NewSnapshotter(orderBook, j, *snapshotDir, *snapshotInterval, logger)
+		go snapshotter.Run()
+
+		// Take a final snapshot on shutdown to speed up the next start
+		defer func() {
+			if err := snapshotter.TakeSnapshot(); err != nil {
+				logger.Error("Failed to take snapshot", zap.Error(err))
+			}
+		}()
+	} else {
+		if riskChecker != nil {
+			opts = append(opts, module.WithRiskChecker(riskChecker))
+		}
+		if tradingRules != nil {
+			opts = append(opts, module.WithTradingRules(tradingRules))
+		}
+		orderBook = module.NewOrderBookUCase(logger, opts...)
+	}
+
+	// Reconcile the ledger against the trade log on shutdown
+	defer func() {
+		bus.Flush()
+		report := ledger.Reconcile(orderBook.GetTrades())
+		if !report.OK() {
+			logger.Error("Settlement ledger does not reconcile", zap.Any("report", report))
+			return
+		}
+		logger.Info("Settlement ledger reconciled", zap.Int("trades", report.Trades))
+		logger.Info("Fee totals of the trade history", zap.Any("fees", fee.Totals(orderBook.GetTrades())))
+	}()
 
 	cleaner := worker.NewCleaner(orderBook)
 	go cleaner.RemoveExpiredBuyOrders()
 	go cleaner.RemoveExpiredSellOrders()
+	if tradingCalendar != nil {
+		go cleaner.ExpireAtSessionClose(tradingCalendar)
+
+		// Move through the scheduled market phases
+		scheduler := worker.NewPhaseScheduler(orderBook, tradingCalendar, logger)
+		go scheduler.Run()
+	}
+
+	// Collect orders and uncross them periodically, in the pre-open phase
+	// unless the calendar schedules the call periods
+	if *callAuction > 0 {
+		if tradingCalendar == nil && orderBook.GetPhase() != constant.PreOpenPhase {
+			for _, phase := range []constant.MarketPhase{constant.ClosedPhase, constant.PreOpenPhase} {
+				if orderBook.GetPhase() == phase {
+					continue
+				}
+				if err := orderBook.SetPhase(phase, "call-auction"); err != nil {
+					logger.Fatal("Failed to enter the call period", zap.Error(err))
+				}
+			}
+		}
+		auctioneer := worker.NewAuctioneer(orderBook, *callAuction, logger)
+		go auctioneer.Run()
+	}
+
+	// Reopen the book when a circuit breaker halt is over
+	if *circuitBreaker != "" {
+		haltMonitor := worker.NewHaltMonitor(orderBook, time.Second, logger)
+		go haltMonitor.Run()
+	}
+
+	// Cancel the flagged orders of clients that stop sending heartbeats
+	sessions := session.NewManager(orderBook, *sessionTimeout, clock.NewRealClock(), logger)
+	sessionMonitor := worker.NewSessionMonitor(sessions, time.Second)
+	go sessionMonitor.Run()
 
 	var wg sync.WaitGroup
+	currency := orderBook.GetCurrency()
 
 	// Function to submit multiple orders concurrently
-	submitOrders := func(customerID uint, prices []uint, orderType constant.OrderType) {
+	submitOrders := func(customerID uint, prices []model.Price, orderType constant.OrderType) {
 		defer wg.Done()
 		for _, price := range prices {
 			orderBook.SubmitOrder(customerID, price, orderType, util.CreateGTT(1))
-			logger.Debug("Order submitted", zap.Uint("CustomerID", customerID), zap.Uint("Price", price), zap.String("OrderType", orderType.String()))
+			logger.Debug("Order submitted", zap.Uint("CustomerID", customerID), zap.String("Price", currency.Format(price)), zap.String("OrderType", orderType.String()))
 		}
 	}
 
 	// Start concurrent submissions
 	wg.Add(3)
-	go submitOrders(1, []uint{110, 109, 108}, constant.BuyOrder)
-	go submitOrders(2, []uint{99, 98, 97}, constant.BuyOrder)
-	go submitOrders(3, []uint{100, 101, 102}, constant.SellOrder)
+	go submitOrders(1, []model.Price{110, 109, 108}, constant.BuyOrder)
+	go submitOrders(2, []model.Price{99, 98, 97}, constant.BuyOrder)
+	go submitOrders(3, []model.Price{100, 101, 102}, constant.SellOrder)
 
 	// Wait for all submissions to complete
 	wg.Wait()
@@ -49,7 +287,7 @@ func main() {
 		orders := orderBook.QueryOrders(customerID)
 		logger.Debug("Queried active orders", zap.Uint("CustomerID", customerID), zap.Int("OrderCount", len(orders)))
 		for _, order := range orders {
-			logger.Info("Order details", zap.Uint("CustomerID", customerID), zap.Uint64("OrderID", order.ID), zap.Uint("Price", order.Price), zap.String("OrderType", order.OrderType.String()))
+			logger.Info("Order details", zap.Uint("CustomerID", customerID), zap.Uint64("OrderID", order.ID), zap.String("Price", currency.Format(order.Price)), zap.String("OrderType", order.OrderType.String()))
 		}
 	}
 
@@ -61,7 +299,7 @@ func main() {
 	cancelOrders := func(orderIDs []uint64) {
 		defer wg.Done()
 		for _, orderID := range orderIDs {
-			err := orderBook.CancelOrder(orderID)
+			err := orderBook.AdminCancelOrder(orderID, "demo")
 			if err != nil {
 				logger.Error("Failed to cancel order", zap.Uint64("OrderID", orderID), zap.Error(err))
 			} else {
@@ -88,3 +326,41 @@ func main() {
 	signal.Notify(sigC, syscall.SIGTERM, os.Interrupt)
 	<-sigC
 }
+
+// runReplay drives the commands in inPath through a fresh order book
+// configured with opts and writes the trades and final book state to outPath.
+func runReplay(inPath, outPath string, logger *zap.Logger, opts ...module.Option) error {
+	in := os.Stdin
+	if inPath != "-" {
+		f, err := os.Open(inPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	out := os.Stdout
+	if outPath != "-" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	return replay.Run(in, out, logger, opts...)
+}
+
+// openStorage opens the order history storage selected by driver.
+func openStorage(driver, sqlitePath string) (interfaces.Storage, error) {
+	switch driver {
+	case "memory":
+		return memory.NewStorage(), nil
+	case "sqlite":
+		return sqlite.NewStorage(sqlitePath)
+	default:
+		return nil, fmt.Errorf("unknown storage: %q", driver)
+	}
+}
@@ -0,0 +1,114 @@
+package allocation
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// New returns the allocator of an algorithm. Allocators share an incoming
+// quantity between the resting orders of one price level, given oldest
+// first. They return the share of every order in the order of the level,
+// never more than its displayed quantity, and in total the quantity or all
+// that is displayed, whichever is smaller.
+func New(algorithm constant.Allocation) (interfaces.Allocator, error) {
+	switch algorithm {
+	case constant.FIFOAllocation:
+		return FIFO{}, nil
+	case constant.ProRataAllocation:
+		return ProRata{}, nil
+	case constant.ProRataTopAllocation:
+		return ProRata{TopOrder: true}, nil
+	case constant.SizeTimeAllocation:
+		return SizeTime{}, nil
+	default:
+		return nil, fmt.Errorf("unknown allocation algorithm %q", algorithm)
+	}
+}
+
+// FIFO fills the orders in time priority.
+type FIFO struct{}
+
+func (FIFO) Allocate(quantity uint, level []*model.Order) []uint {
+	shares := make([]uint, len(level))
+	fillInTurn(quantity, level, inTimeOrder(len(level)), shares)
+	return shares
+}
+
+// SizeTime fills the orders showing the largest quantity first, and orders
+// of the same size in time priority.
+type SizeTime struct{}
+
+func (SizeTime) Allocate(quantity uint, level []*model.Order) []uint {
+	turn := inTimeOrder(len(level))
+	sort.SliceStable(turn, func(i, j int) bool {
+		return level[turn[i]].Displayed() > level[turn[j]].Displayed()
+	})
+
+	shares := make([]uint, len(level))
+	fillInTurn(quantity, level, turn, shares)
+	return shares
+}
+
+// ProRata shares the quantity in proportion to the displayed quantity of each
+// order, rounded down. The units left by rounding go to the orders in time
+// priority. With TopOrder, the oldest order is filled first and only the rest
+// of the quantity is shared.
+type ProRata struct {
+	TopOrder bool
+}
+
+func (p ProRata) Allocate(quantity uint, level []*model.Order) []uint {
+	shares := make([]uint, len(level))
+	first := 0
+	if p.TopOrder && len(level) > 0 {
+		shares[0] = min(quantity, level[0].Displayed())
+		quantity -= shares[0]
+		first = 1
+	}
+
+	var total uint64
+	for _, order := range level[first:] {
+		total += uint64(order.Displayed())
+	}
+	left := quantity
+	if uint64(quantity) < total {
+		for i := first; i < len(level); i++ {
+			// quantity * displayed / total in 128 bits, quantity < total keeps it in range
+			hi, lo := bits.Mul64(uint64(quantity), uint64(level[i].Displayed()))
+			share, _ := bits.Div64(hi, lo, total)
+			shares[i] = uint(share)
+			left -= shares[i]
+		}
+	}
+
+	// Hand out what rounding left, oldest first
+	fillInTurn(left, level, inTimeOrder(len(level)), shares)
+	return shares
+}
+
+// fillInTurn fills the orders of the level in turn, each up to its displayed
+// quantity, until the quantity runs out.
+func fillInTurn(quantity uint, level []*model.Order, turn []int, shares []uint) {
+	for _, i := range turn {
+		if quantity == 0 {
+			return
+		}
+		share := min(quantity, level[i].Displayed()-shares[i])
+		shares[i] += share
+		quantity -= share
+	}
+}
+
+// inTimeOrder returns the indices of a level of n orders, oldest first.
+func inTimeOrder(n int) []int {
+	turn := make([]int, n)
+	for i := range turn {
+		turn[i] = i
+	}
+	return turn
+}
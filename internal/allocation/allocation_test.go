@@ -0,0 +1,134 @@
+package allocation_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/allocation"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// level returns resting orders with the given displayed quantities, oldest first.
+func level(quantities ...uint) []*model.Order {
+	orders := make([]*model.Order, 0, len(quantities))
+	for i, quantity := range quantities {
+		orders = append(orders, &model.Order{ID: uint64(i + 1), Price: 100, Quantity: quantity, Remaining: quantity})
+	}
+	return orders
+}
+
+// iceberg returns a resting iceberg order showing display of its quantity.
+func iceberg(id uint64, quantity, display uint) *model.Order {
+	return &model.Order{ID: id, Price: 100, Quantity: quantity, Remaining: quantity, DisplayQuantity: display, Visible: display}
+}
+
+var algorithms = []constant.Allocation{
+	constant.FIFOAllocation,
+	constant.ProRataAllocation,
+	constant.ProRataTopAllocation,
+	constant.SizeTimeAllocation,
+}
+
+// TestConformance runs every allocation algorithm through the same scenarios.
+// Each must respect the shares specified for it and the rules shared by all.
+func TestConformance(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		quantity uint
+		level    []*model.Order
+		expected map[constant.Allocation][]uint
+	}{
+		{
+			"Shares Of A Level", 50, level(10, 30, 60),
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {10, 30, 10},
+				constant.ProRataAllocation:    {5, 15, 30},
+				constant.ProRataTopAllocation: {10, 14, 26},
+				constant.SizeTimeAllocation:   {0, 0, 50},
+			},
+		},
+		{
+			"Rounding Left Overs Go Oldest First", 2, level(1, 1, 1),
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {1, 1, 0},
+				constant.ProRataAllocation:    {1, 1, 0},
+				constant.ProRataTopAllocation: {1, 1, 0},
+				constant.SizeTimeAllocation:   {1, 1, 0},
+			},
+		},
+		{
+			"Equal Sizes Keep Time Priority", 7, level(5, 5),
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {5, 2},
+				constant.ProRataAllocation:    {4, 3},
+				constant.ProRataTopAllocation: {5, 2},
+				constant.SizeTimeAllocation:   {5, 2},
+			},
+		},
+		{
+			"Quantity Above The Level Fills Everything", 200, level(10, 30, 60),
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {10, 30, 60},
+				constant.ProRataAllocation:    {10, 30, 60},
+				constant.ProRataTopAllocation: {10, 30, 60},
+				constant.SizeTimeAllocation:   {10, 30, 60},
+			},
+		},
+		{
+			"Iceberg Counts Its Displayed Slice", 12, []*model.Order{iceberg(1, 100, 4), level(8)[0]},
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {4, 8},
+				constant.ProRataAllocation:    {4, 8},
+				constant.ProRataTopAllocation: {4, 8},
+				constant.SizeTimeAllocation:   {4, 8},
+			},
+		},
+		{
+			"Top Order Larger Than The Quantity", 6, level(10, 30),
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {6, 0},
+				constant.ProRataAllocation:    {2, 4},
+				constant.ProRataTopAllocation: {6, 0},
+				constant.SizeTimeAllocation:   {0, 6},
+			},
+		},
+		{
+			"Empty Level", 5, nil,
+			map[constant.Allocation][]uint{
+				constant.FIFOAllocation:       {},
+				constant.ProRataAllocation:    {},
+				constant.ProRataTopAllocation: {},
+				constant.SizeTimeAllocation:   {},
+			},
+		},
+	}
+
+	for _, algorithm := range algorithms {
+		allocator, err := allocation.New(algorithm)
+		require.NoError(t, err)
+
+		for _, sc := range scenarios {
+			t.Run(string(algorithm)+"/"+sc.name, func(t *testing.T) {
+				shares := allocator.Allocate(sc.quantity, sc.level)
+				require.Equal(t, sc.expected[algorithm], shares)
+
+				// Rules shared by every algorithm
+				var allocated, displayed uint
+				for i, order := range sc.level {
+					require.LessOrEqual(t, shares[i], order.Displayed(), "Share of order %d above its displayed quantity", order.ID)
+					allocated += shares[i]
+					displayed += order.Displayed()
+				}
+				require.Equal(t, min(sc.quantity, displayed), allocated)
+			})
+		}
+	}
+}
+
+// TestNew tests selecting an allocation algorithm by name.
+func TestNew(t *testing.T) {
+	_, err := allocation.New("lottery")
+	require.Error(t, err)
+}
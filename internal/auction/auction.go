@@ -0,0 +1,196 @@
+package auction
+
+import (
+	"sort"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// level is the cumulative demand and supply at a candidate price.
+type level struct {
+	price  model.Price
+	demand uint // Bid quantity at or above the price
+	supply uint // Ask quantity at or below the price
+}
+
+func (l level) volume() uint {
+	return min(l.demand, l.supply)
+}
+
+// surplus returns the demand left over at the price, negative for supply.
+func (l level) surplus() int64 {
+	return int64(l.demand) - int64(l.supply)
+}
+
+// ClearingPrice returns the single price at which the bids and asks trade
+// the most quantity. Ties are broken, in order, by:
+//   - the smallest surplus left unfilled
+//   - market pressure: the highest price when every candidate has a bid
+//     surplus, the lowest when every candidate has an ask surplus
+//   - the price closest to the reference price, then the lower price
+//
+// The zero Auction is returned when the orders do not cross.
+func ClearingPrice(bids, asks []*model.Order, reference model.Price) model.Auction {
+	levels := cumulate(bids, asks)
+
+	// Keep the candidates executing the most, then leaving the smallest surplus
+	var candidates []level
+	for _, l := range levels {
+		if l.volume() == 0 {
+			continue
+		}
+		if len(candidates) > 0 {
+			best := candidates[0]
+			if l.volume() < best.volume() || (l.volume() == best.volume() && abs(l.surplus()) > abs(best.surplus())) {
+				continue
+			}
+			if l.volume() > best.volume() || abs(l.surplus()) < abs(best.surplus()) {
+				candidates = candidates[:0]
+			}
+		}
+		candidates = append(candidates, l)
+	}
+	if len(candidates) == 0 {
+		return model.Auction{}
+	}
+
+	chosen := pick(candidates, reference)
+	result := model.Auction{Price: chosen.price, Volume: chosen.volume()}
+	if surplus := chosen.surplus(); surplus > 0 {
+		result.BidSurplus = uint(surplus)
+	} else {
+		result.AskSurplus = uint(-surplus)
+	}
+	return result
+}
+
+// Allocate returns the trades executing the auction volume at its price.
+// Bids and asks trade in price-time priority. A bid never trades with an ask
+// of the same customer, so slightly less than the volume may execute.
+func Allocate(bids, asks []*model.Order, result model.Auction) []model.AuctionFill {
+	if result.Volume == 0 {
+		return nil
+	}
+
+	eligibleBids := eligible(bids, func(o *model.Order) bool { return o.Price >= result.Price })
+	eligibleAsks := eligible(asks, func(o *model.Order) bool { return o.Price <= result.Price })
+	sortByPriority(eligibleBids, func(a, b model.Price) bool { return a > b })
+	sortByPriority(eligibleAsks, func(a, b model.Price) bool { return a < b })
+
+	// Quantities left to allocate per ask, the orders are not touched
+	askLeft := make([]uint, len(eligibleAsks))
+	for i, ask := range eligibleAsks {
+		askLeft[i] = ask.Remaining
+	}
+
+	fills := []model.AuctionFill{}
+	left := result.Volume
+	for _, bid := range eligibleBids {
+		bidLeft := bid.Remaining
+		for i, ask := range eligibleAsks {
+			if left == 0 || bidLeft == 0 {
+				break
+			}
+			if askLeft[i] == 0 || ask.CustomerID == bid.CustomerID {
+				continue
+			}
+			quantity := min(bidLeft, askLeft[i], left)
+			fills = append(fills, model.AuctionFill{Bid: bid, Ask: ask, Quantity: quantity})
+			bidLeft -= quantity
+			askLeft[i] -= quantity
+			left -= quantity
+		}
+	}
+	return fills
+}
+
+// cumulate returns the demand and supply at every price of the orders, lowest first.
+func cumulate(bids, asks []*model.Order) []level {
+	prices := make(map[model.Price]bool)
+	for _, order := range bids {
+		prices[order.Price] = true
+	}
+	for _, order := range asks {
+		prices[order.Price] = true
+	}
+
+	levels := make([]level, 0, len(prices))
+	for price := range prices {
+		l := level{price: price}
+		for _, bid := range bids {
+			if bid.Price >= price {
+				l.demand += bid.Remaining
+			}
+		}
+		for _, ask := range asks {
+			if ask.Price <= price {
+				l.supply += ask.Remaining
+			}
+		}
+		levels = append(levels, l)
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].price < levels[j].price
+	})
+	return levels
+}
+
+// pick applies the market pressure and reference price tie-breakers to
+// candidates sorted by price.
+func pick(candidates []level, reference model.Price) level {
+	bidPressure, askPressure := true, true
+	for _, l := range candidates {
+		bidPressure = bidPressure && l.surplus() > 0
+		askPressure = askPressure && l.surplus() < 0
+	}
+	switch {
+	case bidPressure:
+		return candidates[len(candidates)-1]
+	case askPressure || reference == 0:
+		return candidates[0]
+	}
+
+	chosen := candidates[0]
+	for _, l := range candidates[1:] {
+		if distance(l.price, reference) < distance(chosen.price, reference) {
+			chosen = l
+		}
+	}
+	return chosen
+}
+
+// eligible returns the orders passing the filter.
+func eligible(orders []*model.Order, filter func(*model.Order) bool) []*model.Order {
+	selected := []*model.Order{}
+	for _, order := range orders {
+		if filter(order) {
+			selected = append(selected, order)
+		}
+	}
+	return selected
+}
+
+// sortByPriority sorts orders by price, better first, then by time and ID.
+func sortByPriority(orders []*model.Order, better func(a, b model.Price) bool) {
+	sort.Slice(orders, func(i, j int) bool {
+		a, b := orders[i], orders[j]
+		if a.Price != b.Price {
+			return better(a.Price, b.Price)
+		}
+		if !a.Timestamp.Equal(b.Timestamp) {
+			return a.Timestamp.Before(b.Timestamp)
+		}
+		return a.ID < b.ID
+	})
+}
+
+func distance(a, b model.Price) model.Price {
+	return max(a, b) - min(a, b)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,117 @@
+package auction_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/auction"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+var startTime = time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+// order returns a resting order of a customer, id seconds after the start.
+func order(id uint64, customerID uint, orderType constant.OrderType, price model.Price, quantity uint) *model.Order {
+	return &model.Order{
+		ID:         id,
+		CustomerID: customerID,
+		OrderType:  orderType,
+		Price:      price,
+		Quantity:   quantity,
+		Remaining:  quantity,
+		Timestamp:  startTime.Add(time.Duration(id) * time.Second),
+	}
+}
+
+// TestClearingPrice tests the clearing price and its tie-breakers.
+func TestClearingPrice(t *testing.T) {
+	testCases := []struct {
+		name      string
+		bids      []*model.Order
+		asks      []*model.Order
+		reference model.Price
+		expected  model.Auction
+	}{
+		{
+			"No Cross",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 99, 5)},
+			[]*model.Order{order(2, 2, constant.SellOrder, 100, 5)},
+			0, model.Auction{},
+		},
+		{
+			"Maximum Volume",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 102, 4), order(2, 1, constant.BuyOrder, 101, 4)},
+			[]*model.Order{order(3, 2, constant.SellOrder, 100, 5), order(4, 2, constant.SellOrder, 101, 5)},
+			0, model.Auction{Price: 101, Volume: 8, AskSurplus: 2},
+		},
+		{
+			"Minimum Surplus",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 102, 5)},
+			[]*model.Order{order(2, 2, constant.SellOrder, 100, 5), order(3, 2, constant.SellOrder, 101, 3)},
+			0, model.Auction{Price: 100, Volume: 5},
+		},
+		{
+			"Bid Pressure Takes The Highest Price",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 102, 6)},
+			[]*model.Order{order(2, 2, constant.SellOrder, 100, 5)},
+			101, model.Auction{Price: 102, Volume: 5, BidSurplus: 1},
+		},
+		{
+			"Ask Pressure Takes The Lowest Price",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 102, 5)},
+			[]*model.Order{order(2, 2, constant.SellOrder, 100, 6)},
+			101, model.Auction{Price: 100, Volume: 5, AskSurplus: 1},
+		},
+		{
+			"Closest To The Reference Price",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 102, 5)},
+			[]*model.Order{order(2, 2, constant.SellOrder, 100, 5)},
+			105, model.Auction{Price: 102, Volume: 5},
+		},
+		{
+			"Lower Price Without Reference",
+			[]*model.Order{order(1, 1, constant.BuyOrder, 102, 5)},
+			[]*model.Order{order(2, 2, constant.SellOrder, 100, 5)},
+			0, model.Auction{Price: 100, Volume: 5},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, auction.ClearingPrice(tc.bids, tc.asks, tc.reference))
+		})
+	}
+}
+
+// TestAllocate tests how the auction volume is shared between the orders.
+func TestAllocate(t *testing.T) {
+	t.Run("Price Time Priority", func(t *testing.T) {
+		bids := []*model.Order{order(2, 2, constant.BuyOrder, 101, 3), order(1, 1, constant.BuyOrder, 102, 3)}
+		asks := []*model.Order{order(4, 3, constant.SellOrder, 101, 4), order(3, 2, constant.SellOrder, 100, 2)}
+		result := auction.ClearingPrice(bids, asks, 0)
+		require.Equal(t, model.Auction{Price: 101, Volume: 6}, result)
+
+		fills := auction.Allocate(bids, asks, result)
+		require.Equal(t, []model.AuctionFill{
+			{Bid: bids[1], Ask: asks[1], Quantity: 2},
+			{Bid: bids[1], Ask: asks[0], Quantity: 1},
+			{Bid: bids[0], Ask: asks[0], Quantity: 3},
+		}, fills)
+		require.Equal(t, uint(3), bids[0].Remaining, "Expected the orders to be left untouched")
+	})
+
+	t.Run("Never Matches A Customer With Itself", func(t *testing.T) {
+		bids := []*model.Order{order(1, 1, constant.BuyOrder, 101, 5)}
+		asks := []*model.Order{order(2, 1, constant.SellOrder, 100, 5), order(3, 2, constant.SellOrder, 101, 5)}
+		result := auction.ClearingPrice(bids, asks, 0)
+		require.Equal(t, model.Price(100), result.Price)
+		require.Empty(t, auction.Allocate(bids, asks, result))
+	})
+
+	t.Run("Nothing To Allocate", func(t *testing.T) {
+		require.Empty(t, auction.Allocate(nil, nil, model.Auction{}))
+	})
+}
@@ -0,0 +1,96 @@
+package breaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// Config is the circuit breaker of a book. Durations use time.ParseDuration
+// syntax, e.g. "5m".
+type Config struct {
+	MoveBps uint   `json:"move_bps"` // Trade price move that halts the book, in basis points
+	Window  string `json:"window"`   // Period over which the move is measured
+	Halt    string `json:"halt"`     // Time halted before the reopening auction, manual resume only if empty
+}
+
+// Breaker halts a book when its trade price moves too far, too fast.
+type Breaker struct {
+	moveBps uint
+	window  time.Duration
+	halt    time.Duration
+}
+
+// New validates the config and returns its breaker.
+func New(cfg Config) (*Breaker, error) {
+	if cfg.MoveBps == 0 {
+		return nil, fmt.Errorf("circuit breaker move_bps is required")
+	}
+	window, err := time.ParseDuration(cfg.Window)
+	if err != nil {
+		return nil, fmt.Errorf("circuit breaker window: %w", err)
+	}
+	if window <= 0 {
+		return nil, fmt.Errorf("circuit breaker window %s is not positive", cfg.Window)
+	}
+
+	var halt time.Duration
+	if cfg.Halt != "" {
+		if halt, err = time.ParseDuration(cfg.Halt); err != nil {
+			return nil, fmt.Errorf("circuit breaker halt: %w", err)
+		}
+		if halt <= 0 {
+			return nil, fmt.Errorf("circuit breaker halt %s is not positive", cfg.Halt)
+		}
+	}
+	return &Breaker{moveBps: cfg.MoveBps, window: window, halt: halt}, nil
+}
+
+// Load reads the circuit breaker of a book from a JSON file.
+func Load(path string) (*Breaker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("circuit breaker %s: %w", path, err)
+	}
+	breaker, err := New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("circuit breaker %s: %w", path, err)
+	}
+	return breaker, nil
+}
+
+// Tripped reports whether the last of the trades, in execution order, is
+// more than the allowed move away from any trade of the window before it.
+func (b *Breaker) Tripped(trades []*model.Trade) bool {
+	if len(trades) < 2 {
+		return false
+	}
+
+	last := trades[len(trades)-1]
+	start := last.Timestamp.Add(-b.window)
+	for i := len(trades) - 2; i >= 0 && trades[i].Timestamp.After(start); i-- {
+		if !last.Price.WithinBand(trades[i].Price, b.moveBps) {
+			return true
+		}
+	}
+	return false
+}
+
+// Window returns the period over which the trade price move is measured.
+func (b *Breaker) Window() time.Duration {
+	return b.window
+}
+
+// HaltDuration returns how long a tripped book stays halted, 0 if it waits
+// for a manual resume.
+func (b *Breaker) HaltDuration() time.Duration {
+	return b.halt
+}
@@ -0,0 +1,88 @@
+package breaker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/breaker"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+var startTime = time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+// trades returns one trade per price, a second apart.
+func trades(prices ...model.Price) []*model.Trade {
+	result := make([]*model.Trade, 0, len(prices))
+	for i, price := range prices {
+		result = append(result, &model.Trade{ID: uint64(i + 1), Price: price, Quantity: 1, Timestamp: startTime.Add(time.Duration(i) * time.Second)})
+	}
+	return result
+}
+
+// TestBreaker tests when the circuit breaker trips.
+func TestBreaker(t *testing.T) {
+	cb, err := breaker.New(breaker.Config{MoveBps: 1000, Window: "3s", Halt: "5m"})
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, cb.HaltDuration())
+
+	testCases := []struct {
+		name    string
+		trades  []*model.Trade
+		tripped bool
+	}{
+		{"No Trades", nil, false},
+		{"First Trade", trades(100), false},
+		{"Move Within The Limit", trades(100, 105, 110), false},
+		{"Move Beyond The Limit", trades(100, 105, 111), true},
+		{"Fall Beyond The Limit", trades(100, 95, 89), true},
+		{"Move Measured From Any Trade In The Window", trades(105, 100, 111), true},
+		{"Trades Before The Window Are Ignored", trades(100, 105, 108, 109, 112), false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.tripped, cb.Tripped(tc.trades))
+		})
+	}
+}
+
+// TestNew tests circuit breaker validation.
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  breaker.Config
+	}{
+		{"Missing Move", breaker.Config{Window: "1m"}},
+		{"Invalid Window", breaker.Config{MoveBps: 500, Window: "soon"}},
+		{"Zero Window", breaker.Config{MoveBps: 500, Window: "0s"}},
+		{"Negative Halt", breaker.Config{MoveBps: 500, Window: "1m", Halt: "-1m"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := breaker.New(tc.cfg)
+			require.Error(t, err)
+		})
+	}
+
+	t.Run("Manual Resume Without Halt Duration", func(t *testing.T) {
+		cb, err := breaker.New(breaker.Config{MoveBps: 500, Window: "1m"})
+		require.NoError(t, err)
+		require.Zero(t, cb.HaltDuration())
+	})
+}
+
+// TestLoad tests reading a circuit breaker from a JSON file.
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breaker.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"move_bps": 500, "window": "5m", "halt": "2m"}`), 0o644))
+	cb, err := breaker.Load(path)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, cb.HaltDuration())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"window": "5m"}`), 0o644))
+	_, err = breaker.Load(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,231 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// DateLayout is the layout of the dates of good-till-date orders.
+const DateLayout = "2006-01-02"
+
+// Config is the daily trading session of a book. Times of day are "15:04" in
+// the timezone of the book.
+type Config struct {
+	Timezone string        `json:"timezone"` // IANA name, e.g. "America/New_York", UTC if empty
+	Open     string        `json:"open"`
+	Close    string        `json:"close"`
+	Phases   []PhaseChange `json:"phases,omitempty"`   // Daily phase changes, continuous at the open and closed at the close if empty
+	Weekdays []string      `json:"weekdays,omitempty"` // Days with a session, e.g. "Monday", every day if empty
+	Holidays []string      `json:"holidays,omitempty"` // Dates in DateLayout without a session
+}
+
+// PhaseChange moves the book to a market phase at a time of day.
+type PhaseChange struct {
+	At    string               `json:"at"`
+	Phase constant.MarketPhase `json:"phase"`
+}
+
+// Calendar turns session times into instants in the timezone of the book.
+type Calendar struct {
+	location *time.Location
+	closes   time.Duration    // Session close, after midnight
+	phases   []scheduledPhase // Daily phase changes by time of day
+	weekdays [7]bool          // Days of the week with a session
+	holidays map[string]bool  // Dates in DateLayout without a session
+}
+
+// scheduledPhase is a parsed phase change.
+type scheduledPhase struct {
+	at    time.Duration
+	phase constant.MarketPhase
+}
+
+// New validates the config and returns its calendar.
+func New(cfg Config) (*Calendar, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("calendar timezone: %w", err)
+	}
+	opens, err := parseTimeOfDay(cfg.Open)
+	if err != nil {
+		return nil, fmt.Errorf("calendar open: %w", err)
+	}
+	closes, err := parseTimeOfDay(cfg.Close)
+	if err != nil {
+		return nil, fmt.Errorf("calendar close: %w", err)
+	}
+	if opens >= closes {
+		return nil, fmt.Errorf("calendar open %s not before close %s", cfg.Open, cfg.Close)
+	}
+
+	// Without a phase schedule the book trades continuously during the session
+	changes := cfg.Phases
+	if len(changes) == 0 {
+		changes = []PhaseChange{{At: cfg.Open, Phase: constant.ContinuousPhase}, {At: cfg.Close, Phase: constant.ClosedPhase}}
+	}
+	phases := make([]scheduledPhase, 0, len(changes))
+	seen := make(map[time.Duration]bool)
+	for _, change := range changes {
+		at, err := parseTimeOfDay(change.At)
+		if err != nil {
+			return nil, fmt.Errorf("calendar phase %s: %w", change.Phase, err)
+		}
+		if !change.Phase.IsValid() {
+			return nil, fmt.Errorf("calendar phase at %s: unknown phase %q", change.At, change.Phase)
+		}
+		if seen[at] {
+			return nil, fmt.Errorf("calendar phase at %s: two phases at the same time", change.At)
+		}
+		seen[at] = true
+		phases = append(phases, scheduledPhase{at: at, phase: change.Phase})
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		return phases[i].at < phases[j].at
+	})
+
+	calendar := &Calendar{location: location, closes: closes, phases: phases, holidays: make(map[string]bool)}
+	if len(cfg.Weekdays) == 0 {
+		calendar.weekdays = [7]bool{true, true, true, true, true, true, true}
+	}
+	for _, name := range cfg.Weekdays {
+		weekday, err := parseWeekday(name)
+		if err != nil {
+			return nil, fmt.Errorf("calendar weekdays: %w", err)
+		}
+		calendar.weekdays[weekday] = true
+	}
+	for _, date := range cfg.Holidays {
+		if _, err := time.Parse(DateLayout, date); err != nil {
+			return nil, fmt.Errorf("calendar holiday: %w", err)
+		}
+		calendar.holidays[date] = true
+	}
+
+	return calendar, nil
+}
+
+// Load reads a calendar config from a JSON file.
+func Load(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("calendar %s: %w", path, err)
+	}
+	calendar, err := New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("calendar %s: %w", path, err)
+	}
+	return calendar, nil
+}
+
+// Location returns the timezone of the book.
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// SessionClose returns the first session close after t: today's close while
+// today's session has not closed, otherwise the close of the next trading day.
+func (c *Calendar) SessionClose(t time.Time) time.Time {
+	for day := t; ; day = c.nextDay(day) {
+		if closing := c.at(day, c.closes); c.IsTradingDay(day) && closing.After(t) {
+			return closing
+		}
+	}
+}
+
+// CloseOn returns the session close on a date in DateLayout, in the timezone
+// of the book. The date must be a trading day.
+func (c *Calendar) CloseOn(date string) (time.Time, error) {
+	day, err := time.ParseInLocation(DateLayout, date, c.location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !c.IsTradingDay(day) {
+		return time.Time{}, fmt.Errorf("%s is not a trading day", date)
+	}
+	return c.at(day, c.closes), nil
+}
+
+// IsTradingDay reports whether the day of t in the timezone of the book has
+// a session: a trading weekday that is not a holiday.
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	local := t.In(c.location)
+	return c.weekdays[local.Weekday()] && !c.holidays[local.Format(DateLayout)]
+}
+
+// PhaseAt returns the scheduled market phase at t: the phase of the last
+// change at or before t, carried over from the last trading day early in the
+// day and on days without a session.
+func (c *Calendar) PhaseAt(t time.Time) constant.MarketPhase {
+	phase := c.phases[len(c.phases)-1].phase
+	if !c.IsTradingDay(t) {
+		return phase
+	}
+	for _, scheduled := range c.phases {
+		if c.at(t, scheduled.at).After(t) {
+			break
+		}
+		phase = scheduled.phase
+	}
+	return phase
+}
+
+// NextPhaseChange returns the first scheduled phase change after t, skipping
+// days without a session.
+func (c *Calendar) NextPhaseChange(t time.Time) (time.Time, constant.MarketPhase) {
+	for day := t; ; day = c.nextDay(day) {
+		if !c.IsTradingDay(day) {
+			continue
+		}
+		for _, scheduled := range c.phases {
+			if at := c.at(day, scheduled.at); at.After(t) {
+				return at, scheduled.phase
+			}
+		}
+	}
+}
+
+// nextDay returns noon of the day after t in the timezone of the book, clear
+// of daylight saving changes. The loops over days end because New requires a
+// trading weekday and the holidays are finite.
+func (c *Calendar) nextDay(t time.Time) time.Time {
+	year, month, day := t.In(c.location).Date()
+	return time.Date(year, month, day+1, 12, 0, 0, 0, c.location)
+}
+
+// at returns the instant at offset after midnight on the day of t in the
+// timezone of the book. The wall clock is kept across daylight saving changes.
+func (c *Calendar) at(t time.Time, offset time.Duration) time.Time {
+	year, month, day := t.In(c.location).Date()
+	hours, minutes := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+	return time.Date(year, month, day, hours, minutes, 0, 0, c.location)
+}
+
+// parseWeekday reads an English weekday name such as "Monday", in any case.
+func parseWeekday(name string) (time.Weekday, error) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(name, weekday.String()) {
+			return weekday, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", name)
+}
+
+// parseTimeOfDay reads a "15:04" time of day as the duration after midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,143 @@
+package calendar_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/calendar"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// TestCalendar tests session closes in the timezone of the book.
+func TestCalendar(t *testing.T) {
+	cal, err := calendar.New(calendar.Config{Timezone: "America/New_York", Open: "09:30", Close: "16:00"})
+	require.NoError(t, err)
+	newYork := cal.Location()
+
+	t.Run("Session Close", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			at    time.Time
+			close time.Time
+		}{
+			{"Before Open", time.Date(2024, 6, 3, 8, 0, 0, 0, newYork), time.Date(2024, 6, 3, 16, 0, 0, 0, newYork)},
+			{"During Session", time.Date(2024, 6, 3, 12, 0, 0, 0, newYork), time.Date(2024, 6, 3, 16, 0, 0, 0, newYork)},
+			{"Just Before Close", time.Date(2024, 6, 3, 15, 59, 59, 0, newYork), time.Date(2024, 6, 3, 16, 0, 0, 0, newYork)},
+			{"At Close", time.Date(2024, 6, 3, 16, 0, 0, 0, newYork), time.Date(2024, 6, 4, 16, 0, 0, 0, newYork)},
+			{"After Close", time.Date(2024, 6, 3, 16, 0, 1, 0, newYork), time.Date(2024, 6, 4, 16, 0, 0, 0, newYork)},
+			{"UTC Instant", time.Date(2024, 6, 3, 21, 0, 0, 0, time.UTC), time.Date(2024, 6, 4, 16, 0, 0, 0, newYork)},
+			{"Across Daylight Saving", time.Date(2024, 3, 9, 17, 0, 0, 0, newYork), time.Date(2024, 3, 10, 16, 0, 0, 0, newYork)},
+		}
+		for _, tc := range testCases {
+			require.True(t, tc.close.Equal(cal.SessionClose(tc.at)), "%s: got %s", tc.name, cal.SessionClose(tc.at))
+		}
+	})
+
+	t.Run("Close On Date", func(t *testing.T) {
+		closing, err := cal.CloseOn("2024-12-20")
+		require.NoError(t, err)
+		require.True(t, time.Date(2024, 12, 20, 21, 0, 0, 0, time.UTC).Equal(closing))
+
+		_, err = cal.CloseOn("20/12/2024")
+		require.Error(t, err)
+	})
+
+	t.Run("Default Phases", func(t *testing.T) {
+		require.Equal(t, constant.ClosedPhase, cal.PhaseAt(time.Date(2024, 6, 3, 9, 29, 0, 0, newYork)))
+		require.Equal(t, constant.ContinuousPhase, cal.PhaseAt(time.Date(2024, 6, 3, 9, 30, 0, 0, newYork)))
+		require.Equal(t, constant.ClosedPhase, cal.PhaseAt(time.Date(2024, 6, 3, 16, 0, 0, 0, newYork)))
+	})
+
+	t.Run("Phase Schedule", func(t *testing.T) {
+		scheduled, err := calendar.New(calendar.Config{Open: "09:30", Close: "16:00", Phases: []calendar.PhaseChange{
+			{At: "16:05", Phase: constant.ClosedPhase},
+			{At: "08:00", Phase: constant.PreOpenPhase},
+			{At: "09:29", Phase: constant.OpeningAuctionPhase},
+			{At: "09:30", Phase: constant.ContinuousPhase},
+			{At: "16:00", Phase: constant.ClosingPhase},
+		}})
+		require.NoError(t, err)
+
+		testCases := []struct {
+			at    time.Time
+			phase constant.MarketPhase
+			next  time.Time
+		}{
+			{time.Date(2024, 6, 3, 3, 0, 0, 0, time.UTC), constant.ClosedPhase, time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC)},
+			{time.Date(2024, 6, 3, 8, 0, 0, 0, time.UTC), constant.PreOpenPhase, time.Date(2024, 6, 3, 9, 29, 0, 0, time.UTC)},
+			{time.Date(2024, 6, 3, 9, 29, 30, 0, time.UTC), constant.OpeningAuctionPhase, time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC)},
+			{time.Date(2024, 6, 3, 12, 0, 0, 0, time.UTC), constant.ContinuousPhase, time.Date(2024, 6, 3, 16, 0, 0, 0, time.UTC)},
+			{time.Date(2024, 6, 3, 16, 1, 0, 0, time.UTC), constant.ClosingPhase, time.Date(2024, 6, 3, 16, 5, 0, 0, time.UTC)},
+			{time.Date(2024, 6, 3, 20, 0, 0, 0, time.UTC), constant.ClosedPhase, time.Date(2024, 6, 4, 8, 0, 0, 0, time.UTC)},
+		}
+		for _, tc := range testCases {
+			require.Equal(t, tc.phase, scheduled.PhaseAt(tc.at), "Phase at %s", tc.at)
+			next, _ := scheduled.NextPhaseChange(tc.at)
+			require.True(t, tc.next.Equal(next), "Next change after %s: got %s", tc.at, next)
+		}
+	})
+
+	t.Run("Trading Days", func(t *testing.T) {
+		exchange, err := calendar.New(calendar.Config{
+			Timezone: "America/New_York", Open: "09:30", Close: "16:00",
+			Weekdays: []string{"monday", "Tuesday", "Wednesday", "Thursday", "Friday"},
+			Holidays: []string{"2024-07-04"},
+		})
+		require.NoError(t, err)
+
+		// Friday after the close rolls over the weekend, Wednesday over the holiday
+		friday := time.Date(2024, 6, 7, 17, 0, 0, 0, newYork)
+		require.True(t, time.Date(2024, 6, 10, 16, 0, 0, 0, newYork).Equal(exchange.SessionClose(friday)))
+		wednesday := time.Date(2024, 7, 3, 16, 0, 0, 0, newYork)
+		require.True(t, time.Date(2024, 7, 5, 16, 0, 0, 0, newYork).Equal(exchange.SessionClose(wednesday)))
+
+		require.True(t, exchange.IsTradingDay(friday))
+		require.False(t, exchange.IsTradingDay(time.Date(2024, 6, 8, 12, 0, 0, 0, newYork)))
+		require.False(t, exchange.IsTradingDay(time.Date(2024, 7, 4, 12, 0, 0, 0, newYork)))
+
+		_, err = exchange.CloseOn("2024-07-04")
+		require.Error(t, err)
+		_, err = exchange.CloseOn("2024-06-08")
+		require.Error(t, err)
+		_, err = exchange.CloseOn("2024-07-05")
+		require.NoError(t, err)
+
+		// The book stays closed until the next trading day's open
+		saturday := time.Date(2024, 6, 8, 12, 0, 0, 0, newYork)
+		require.Equal(t, constant.ClosedPhase, exchange.PhaseAt(saturday))
+		next, phase := exchange.NextPhaseChange(saturday)
+		require.True(t, time.Date(2024, 6, 10, 9, 30, 0, 0, newYork).Equal(next), "got %s", next)
+		require.Equal(t, constant.ContinuousPhase, phase)
+	})
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		_, err := calendar.New(calendar.Config{Timezone: "Mars/Olympus", Open: "09:30", Close: "16:00"})
+		require.Error(t, err)
+		_, err = calendar.New(calendar.Config{Open: "16:00", Close: "09:30"})
+		require.Error(t, err)
+		_, err = calendar.New(calendar.Config{Open: "9am", Close: "16:00"})
+		require.Error(t, err)
+		_, err = calendar.New(calendar.Config{Open: "09:30", Close: "16:00", Phases: []calendar.PhaseChange{{At: "09:30", Phase: "lunch"}}})
+		require.Error(t, err)
+		_, err = calendar.New(calendar.Config{Open: "09:30", Close: "16:00", Phases: []calendar.PhaseChange{
+			{At: "09:30", Phase: constant.ContinuousPhase}, {At: "09:30", Phase: constant.ClosedPhase},
+		}})
+		require.Error(t, err)
+		_, err = calendar.New(calendar.Config{Open: "09:30", Close: "16:00", Weekdays: []string{"Funday"}})
+		require.Error(t, err)
+		_, err = calendar.New(calendar.Config{Open: "09:30", Close: "16:00", Holidays: []string{"25/12/2024"}})
+		require.Error(t, err)
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "calendar.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"timezone":"Europe/London","open":"08:00","close":"16:30"}`), 0o644))
+		loaded, err := calendar.Load(path)
+		require.NoError(t, err)
+		require.Equal(t, "Europe/London", loaded.Location().String())
+	})
+}
@@ -0,0 +1,100 @@
+package clock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// realClock reads the wall clock.
+type realClock struct{}
+
+// NewRealClock creates a clock backed by time.Now.
+func NewRealClock() interfaces.Clock {
+	return realClock{}
+}
+
+// Now returns the current wall-clock time.
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Manual is a clock that only moves when told to, for tests.
+type Manual struct {
+	now time.Time
+	mtx sync.RWMutex
+}
+
+// NewManual creates a manual clock set to now.
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *Manual) Now() time.Time {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *Manual) Set(now time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *Manual) Advance(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Replay is a clock driven by the timestamps of replayed commands.
+// Once GoLive is called it delegates to a live clock instead, so a book
+// recovered from a journal can keep running on wall-clock time.
+type Replay struct {
+	now    time.Time
+	live   interfaces.Clock
+	isLive bool
+	mtx    sync.RWMutex
+}
+
+// NewReplay creates a replay clock that switches to live after GoLive.
+// A nil live clock defaults to the wall clock.
+func NewReplay(live interfaces.Clock) *Replay {
+	if live == nil {
+		live = NewRealClock()
+	}
+	return &Replay{live: live}
+}
+
+// Now returns the timestamp of the current command, or the live time after GoLive.
+func (c *Replay) Now() time.Time {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	if c.isLive {
+		return c.live.Now()
+	}
+	return c.now
+}
+
+// Set moves the clock to the timestamp of the next command.
+// A zero timestamp keeps the previous time.
+func (c *Replay) Set(now time.Time) {
+	if now.IsZero() {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.now = now
+}
+
+// GoLive switches the clock to the live clock for good.
+func (c *Replay) GoLive() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.isLive = true
+}
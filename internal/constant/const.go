@@ -1,5 +1,11 @@
 package constant
 
+import (
+	"fmt"
+
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
 type OrderType bool
 
 const (
@@ -16,3 +22,137 @@ func (o OrderType) String() string {
 		return "SellOrder"
 	}
 }
+
+// MarshalText encodes the OrderType as "buy" or "sell".
+func (o OrderType) MarshalText() ([]byte, error) {
+	if o == BuyOrder {
+		return []byte("buy"), nil
+	}
+	return []byte("sell"), nil
+}
+
+// UnmarshalText decodes an OrderType from "buy" or "sell".
+func (o *OrderType) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "buy":
+		*o = BuyOrder
+	case "sell":
+		*o = SellOrder
+	default:
+		return fmt.Errorf("%w: %q", reject.ErrInvalidSide, text)
+	}
+	return nil
+}
+
+type CommandAction string
+
+const (
+	SubmitCommand     CommandAction = "submit"
+	CancelCommand     CommandAction = "cancel"
+	AmendCommand      CommandAction = "amend"
+	ExpireCommand     CommandAction = "expire"      // Sweep of expired orders on one side
+	MassCancelCommand CommandAction = "mass_cancel" // Admin cancel of every order matching a filter
+	DisconnectCommand CommandAction = "disconnect"  // Cancel-on-disconnect of one customer's orders
+	DepositCommand    CommandAction = "deposit"     // Cash and inventory added to an account
+	WithdrawCommand   CommandAction = "withdraw"    // Cash and inventory taken from an account
+	PhaseCommand      CommandAction = "phase"       // Move to another market phase
+	UncrossCommand    CommandAction = "uncross"     // Call auction of the resting orders
+	HaltCommand       CommandAction = "halt"        // Manual trading halt
+	ResumeCommand     CommandAction = "resume"      // End of a halt with a reopening auction
+)
+
+type OrderStatus string
+
+const (
+	OrderOpen      OrderStatus = "open"
+	OrderFilled    OrderStatus = "filled"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderExpired   OrderStatus = "expired"
+)
+
+type PegType string
+
+const (
+	PrimaryPeg  PegType = "primary"  // Pegged to the best price of its own side
+	MarketPeg   PegType = "market"   // Pegged to the best price of the opposite side
+	MidpointPeg PegType = "midpoint" // Pegged to the midpoint of the best bid and offer
+)
+
+type Allocation string
+
+const (
+	FIFOAllocation       Allocation = "fifo"         // Oldest order first
+	ProRataAllocation    Allocation = "pro_rata"     // In proportion to the displayed quantities
+	ProRataTopAllocation Allocation = "pro_rata_top" // Oldest order filled first, the rest pro rata
+	SizeTimeAllocation   Allocation = "size_time"    // Largest displayed quantity first, then oldest
+)
+
+type TimeInForce string
+
+const (
+	GoodTillCancel TimeInForce = "gtc" // Until cancelled, or its GTT if it has one
+	GoodForDay     TimeInForce = "day" // Until the close of the current session
+	GoodTillDate   TimeInForce = "gtd" // Until the session close on its expire date
+)
+
+type MarketPhase string
+
+const (
+	ClosedPhase         MarketPhase = "closed"          // No order entry
+	PreOpenPhase        MarketPhase = "pre_open"        // Orders are entered and cancelled without matching
+	OpeningAuctionPhase MarketPhase = "opening_auction" // Order book frozen while it is uncrossed
+	ContinuousPhase     MarketPhase = "continuous"      // Orders match as they arrive
+	HaltedPhase         MarketPhase = "halted"          // Trading stopped, orders can only be cancelled
+	ClosingPhase        MarketPhase = "closing"         // Orders can only be cancelled until the close
+)
+
+// CircuitBreakerOperator is the operator of the halts and resumptions
+// decided by the circuit breaker.
+const CircuitBreakerOperator = "circuit_breaker"
+
+// phaseTransitions lists the phases each phase can move to. Any phase can close.
+var phaseTransitions = map[MarketPhase][]MarketPhase{
+	ClosedPhase:         {PreOpenPhase, OpeningAuctionPhase, ContinuousPhase},
+	PreOpenPhase:        {OpeningAuctionPhase, ContinuousPhase, ClosedPhase},
+	OpeningAuctionPhase: {ContinuousPhase, ClosedPhase},
+	ContinuousPhase:     {HaltedPhase, ClosingPhase, ClosedPhase},
+	HaltedPhase:         {OpeningAuctionPhase, ContinuousPhase, ClosingPhase, ClosedPhase},
+	ClosingPhase:        {ContinuousPhase, ClosedPhase},
+}
+
+// IsValid reports whether the phase is known.
+func (p MarketPhase) IsValid() bool {
+	_, ok := phaseTransitions[p]
+	return ok
+}
+
+// CanMoveTo reports whether the phase can move to next.
+func (p MarketPhase) CanMoveTo(next MarketPhase) bool {
+	for _, allowed := range phaseTransitions[p] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptsOrders reports whether new and amended orders are accepted.
+func (p MarketPhase) AcceptsOrders() bool {
+	return p == PreOpenPhase || p == ContinuousPhase
+}
+
+// AcceptsCancels reports whether customers can cancel their orders.
+func (p MarketPhase) AcceptsCancels() bool {
+	return p == PreOpenPhase || p == ContinuousPhase || p == HaltedPhase || p == ClosingPhase
+}
+
+// IsCall reports whether orders are collected for an auction instead of
+// being matched.
+func (p MarketPhase) IsCall() bool {
+	return p == PreOpenPhase || p == OpeningAuctionPhase
+}
+
+// Matches reports whether incoming orders are matched.
+func (p MarketPhase) Matches() bool {
+	return p == ContinuousPhase
+}
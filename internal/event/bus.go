@@ -0,0 +1,157 @@
+package event
+
+import (
+	"sync"
+)
+
+// Bus is an in-process publish/subscribe hub for order book events.
+// Publish never blocks on subscribers: every subscriber has its own
+// unbounded queue drained by a dedicated goroutine, so a slow consumer
+// delays only itself and still sees every event in publication order.
+type Bus struct {
+	subscribers map[uint64]*subscriber
+	nextID      uint64
+	mtx         sync.RWMutex
+}
+
+// NewBus creates a new event bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[uint64]*subscriber),
+	}
+}
+
+// Subscribe registers handler for every event published from now on.
+// Handlers run sequentially on the subscriber's own goroutine.
+// The returned function unsubscribes after the queued events are handled.
+func (b *Bus) Subscribe(handler func(Event)) (unsubscribe func()) {
+	sub := newSubscriber(handler)
+
+	b.mtx.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = sub
+	b.mtx.Unlock()
+
+	go sub.run()
+
+	return func() {
+		b.mtx.Lock()
+		delete(b.subscribers, id)
+		b.mtx.Unlock()
+		sub.close()
+	}
+}
+
+// Publish queues the events for every subscriber.
+func (b *Bus) Publish(events ...Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mtx.RLock()
+	defer b.mtx.RUnlock()
+	for _, sub := range b.subscribers {
+		sub.enqueue(events)
+	}
+}
+
+// Flush waits until every subscriber has handled the events published so far.
+func (b *Bus) Flush() {
+	b.mtx.RLock()
+	subscribers := make([]*subscriber, 0, len(b.subscribers))
+	for _, sub := range b.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	b.mtx.RUnlock()
+
+	for _, sub := range subscribers {
+		sub.flush()
+	}
+}
+
+// Close unsubscribes everyone after their queued events are handled.
+func (b *Bus) Close() {
+	b.mtx.Lock()
+	subscribers := b.subscribers
+	b.subscribers = make(map[uint64]*subscriber)
+	b.mtx.Unlock()
+
+	for _, sub := range subscribers {
+		sub.close()
+	}
+}
+
+// subscriber owns the queue of one handler.
+type subscriber struct {
+	handler func(Event)
+	queue   []Event
+	busy    bool // Handler is processing a batch taken from the queue
+	closed  bool
+	done    chan struct{}
+	mtx     sync.Mutex
+	cond    *sync.Cond
+}
+
+func newSubscriber(handler func(Event)) *subscriber {
+	sub := &subscriber{handler: handler, done: make(chan struct{})}
+	sub.cond = sync.NewCond(&sub.mtx)
+	return sub
+}
+
+// enqueue appends events to the queue and wakes the subscriber.
+func (s *subscriber) enqueue(events []Event) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.closed {
+		return
+	}
+	s.queue = append(s.queue, events...)
+	s.cond.Broadcast()
+}
+
+// run delivers queued events until the subscriber is closed and drained.
+func (s *subscriber) run() {
+	defer close(s.done)
+	for {
+		s.mtx.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if len(s.queue) == 0 && s.closed {
+			s.mtx.Unlock()
+			return
+		}
+		batch := s.queue
+		s.queue = nil
+		s.busy = true
+		s.mtx.Unlock()
+
+		for _, event := range batch {
+			s.handler(event)
+		}
+
+		s.mtx.Lock()
+		s.busy = false
+		s.cond.Broadcast()
+		s.mtx.Unlock()
+	}
+}
+
+// flush waits until the queue is empty and the handler is idle.
+func (s *subscriber) flush() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for (len(s.queue) > 0 || s.busy) && !s.closed {
+		s.cond.Wait()
+	}
+}
+
+// close stops accepting events and waits for the queue to drain.
+func (s *subscriber) close() {
+	s.mtx.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mtx.Unlock()
+	<-s.done
+}
@@ -0,0 +1,84 @@
+package event_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// accepted returns an OrderAccepted event for the order ID.
+func accepted(orderID uint64) event.Event {
+	return event.OrderAccepted{Order: model.Order{ID: orderID}}
+}
+
+// TestBus tests publishing events to subscribers.
+func TestBus(t *testing.T) {
+	t.Run("Subscribers Receive Events In Order", func(t *testing.T) {
+		bus := event.NewBus()
+		defer bus.Close()
+
+		received := [2][]uint64{}
+		for i := range received {
+			bus.Subscribe(func(e event.Event) {
+				received[i] = append(received[i], e.(event.OrderAccepted).Order.ID)
+			})
+		}
+
+		bus.Publish(accepted(1), accepted(2))
+		bus.Publish(accepted(3))
+		bus.Flush()
+
+		for i := range received {
+			require.Equal(t, []uint64{1, 2, 3}, received[i], "Subscriber %d", i)
+		}
+	})
+
+	t.Run("Slow Subscriber Does Not Block Publish", func(t *testing.T) {
+		bus := event.NewBus()
+		defer bus.Close()
+
+		release := make(chan struct{})
+		var slow []uint64
+		bus.Subscribe(func(e event.Event) {
+			<-release
+			slow = append(slow, e.(event.OrderAccepted).Order.ID)
+		})
+
+		var fast []uint64
+		var mtx sync.Mutex
+		bus.Subscribe(func(e event.Event) {
+			mtx.Lock()
+			defer mtx.Unlock()
+			fast = append(fast, e.(event.OrderAccepted).Order.ID)
+		})
+
+		// Publishing returns while the slow subscriber is stuck on the first event
+		for i := uint64(1); i <= 100; i++ {
+			bus.Publish(accepted(i))
+		}
+
+		close(release)
+		bus.Flush()
+		require.Len(t, slow, 100)
+		require.Len(t, fast, 100)
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		bus := event.NewBus()
+		defer bus.Close()
+
+		count := 0
+		unsubscribe := bus.Subscribe(func(event.Event) { count++ })
+		bus.Publish(accepted(1))
+		unsubscribe()
+		require.Equal(t, 1, count, "Expected queued events to be handled before unsubscribing")
+
+		bus.Publish(accepted(2))
+		bus.Flush()
+		require.Equal(t, 1, count)
+	})
+}
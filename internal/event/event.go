@@ -0,0 +1,181 @@
+package event
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// Type identifies the kind of an event.
+type Type string
+
+const (
+	OrderAcceptedType        Type = "order_accepted"
+	OrderRejectedType        Type = "order_rejected"
+	OrderAmendedType         Type = "order_amended"
+	OrderFilledType          Type = "order_filled"
+	OrderPartiallyFilledType Type = "order_partially_filled"
+	OrderCancelledType       Type = "order_cancelled"
+	OrderExpiredType         Type = "order_expired"
+	StopTriggeredType        Type = "stop_triggered"
+	OrderRepricedType        Type = "order_repriced"
+	TradeExecutedType        Type = "trade_executed"
+	BookChangedType          Type = "book_changed"
+	PhaseChangedType         Type = "phase_changed"
+	AuctionIndicativeType    Type = "auction_indicative"
+	AuctionUncrossedType     Type = "auction_uncrossed"
+	TradingHaltedType        Type = "trading_halted"
+	TradingResumedType       Type = "trading_resumed"
+)
+
+// Event is implemented by every event published by the order book.
+// Subscribers switch on the concrete type.
+type Event interface {
+	EventType() Type
+	EventHeader() Header
+}
+
+// Header holds the fields shared by all events.
+type Header struct {
+	Seq       uint64    `json:"seq,omitempty"` // Journal sequence number of the command that caused the event
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// EventHeader returns the shared event fields.
+func (h Header) EventHeader() Header {
+	return h
+}
+
+// OrderAccepted is published when a new order passes validation, before it is matched.
+type OrderAccepted struct {
+	Header
+	Order model.Order `json:"order"`
+}
+
+// OrderRejected is published when an order or command fails validation.
+type OrderRejected struct {
+	Header
+	Request model.OrderRequest `json:"request"`
+	OrderID uint64             `json:"order_id,omitempty"` // Set when a command on an existing order is rejected
+	Code    reject.Code        `json:"code"`
+	Reason  string             `json:"reason"`
+}
+
+// OrderAmended is published when an order is replaced with a new price or GTT.
+type OrderAmended struct {
+	Header
+	Order model.Order `json:"order"`
+}
+
+// OrderFilled is published when an order has no remaining quantity.
+type OrderFilled struct {
+	Header
+	Order model.Order `json:"order"`
+}
+
+// OrderPartiallyFilled is published when a fill leaves quantity remaining.
+type OrderPartiallyFilled struct {
+	Header
+	Order        model.Order `json:"order"`
+	FillQuantity uint        `json:"fill_quantity"`
+}
+
+// OrderCancelled is published when an order is cancelled.
+type OrderCancelled struct {
+	Header
+	Order       model.Order `json:"order"`
+	CancelledBy string      `json:"cancelled_by"` // "customer:<id>", "admin:<operator>", "disconnect", "unfilled" or "repriced"
+}
+
+// OrderExpired is published when a resting order reaches its GTT.
+type OrderExpired struct {
+	Header
+	Order model.Order `json:"order"`
+}
+
+// StopTriggered is published when the last trade price reaches the stop
+// price of a stop order, just before the order is matched.
+type StopTriggered struct {
+	Header
+	Order model.Order `json:"order"`
+}
+
+// OrderRepriced is published when a pegged order follows the top of the book
+// to a new price, just before the order is matched.
+type OrderRepriced struct {
+	Header
+	Order model.Order `json:"order"`
+}
+
+// TradeExecuted is published for every trade.
+type TradeExecuted struct {
+	Header
+	Trade model.Trade `json:"trade"`
+}
+
+// BookChanged is published once per command that changed the book, with the new top of book.
+// A zero price means the side is empty.
+type BookChanged struct {
+	Header
+	BestBid         model.Price `json:"best_bid"`
+	BestBidQuantity uint        `json:"best_bid_quantity"`
+	BestAsk         model.Price `json:"best_ask"`
+	BestAskQuantity uint        `json:"best_ask_quantity"`
+}
+
+// PhaseChanged is published when the book moves to another market phase.
+type PhaseChanged struct {
+	Header
+	Previous constant.MarketPhase `json:"previous"`
+	Phase    constant.MarketPhase `json:"phase"`
+	Operator string               `json:"operator"` // Admin who moved the book, or "schedule"
+}
+
+// AuctionIndicative is published once per command that changed the book
+// during a call period, with the price and volume an uncross would execute now.
+type AuctionIndicative struct {
+	Header
+	Auction model.Auction `json:"auction"`
+}
+
+// TradingHalted is published when the book is halted, by an admin or the
+// circuit breaker.
+type TradingHalted struct {
+	Header
+	Reason   string     `json:"reason,omitempty"`
+	Operator string     `json:"operator"`        // Admin who halted the book, or "circuit_breaker"
+	Until    *time.Time `json:"until,omitempty"` // Automatic resumption, nil if an admin must resume
+}
+
+// TradingResumed is published when the book leaves the halted phase, before
+// the reopening auction.
+type TradingResumed struct {
+	Header
+	Operator string               `json:"operator"`
+	Phase    constant.MarketPhase `json:"phase"`
+}
+
+// AuctionUncrossed is published when a call auction executes, before its trades.
+type AuctionUncrossed struct {
+	Header
+	Auction model.Auction `json:"auction"`
+}
+
+func (OrderAccepted) EventType() Type        { return OrderAcceptedType }
+func (OrderRejected) EventType() Type        { return OrderRejectedType }
+func (OrderAmended) EventType() Type         { return OrderAmendedType }
+func (OrderFilled) EventType() Type          { return OrderFilledType }
+func (OrderPartiallyFilled) EventType() Type { return OrderPartiallyFilledType }
+func (OrderCancelled) EventType() Type       { return OrderCancelledType }
+func (OrderExpired) EventType() Type         { return OrderExpiredType }
+func (StopTriggered) EventType() Type        { return StopTriggeredType }
+func (OrderRepriced) EventType() Type        { return OrderRepricedType }
+func (TradeExecuted) EventType() Type        { return TradeExecutedType }
+func (BookChanged) EventType() Type          { return BookChangedType }
+func (PhaseChanged) EventType() Type         { return PhaseChangedType }
+func (AuctionIndicative) EventType() Type    { return AuctionIndicativeType }
+func (AuctionUncrossed) EventType() Type     { return AuctionUncrossedType }
+func (TradingHalted) EventType() Type        { return TradingHaltedType }
+func (TradingResumed) EventType() Type       { return TradingResumedType }
@@ -0,0 +1,145 @@
+package fee
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// Rates are the fees charged on a trade, in basis points of its notional.
+// The minimum fee applies to every side with a non-zero rate.
+type Rates struct {
+	MakerBps uint   `json:"maker_bps"`
+	TakerBps uint   `json:"taker_bps"`
+	MinFee   uint64 `json:"min_fee"`
+}
+
+// Config holds the default rates, named tiers and the tier of each customer.
+type Config struct {
+	Default   Rates             `json:"default"`
+	Tiers     map[string]Rates  `json:"tiers"`
+	Customers map[string]string `json:"customers"` // Tier name keyed by customer ID
+}
+
+// schedule computes the fees of trades from the tier of each customer.
+type schedule struct {
+	defaults  Rates
+	tiers     map[string]Rates
+	customers map[uint]string
+	mtx       sync.RWMutex
+}
+
+// NewSchedule creates a new fee schedule charging defaults to every
+// customer without a tier.
+func NewSchedule(defaults Rates) *schedule {
+	return &schedule{
+		defaults:  defaults,
+		tiers:     make(map[string]Rates),
+		customers: make(map[uint]string),
+	}
+}
+
+// LoadSchedule creates a fee schedule from a JSON config file.
+func LoadSchedule(path string) (*schedule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("fee config %s: %w", path, err)
+	}
+
+	s := NewSchedule(cfg.Default)
+	for name, rates := range cfg.Tiers {
+		s.SetTier(name, rates)
+	}
+	for key, tier := range cfg.Customers {
+		customerID, err := strconv.ParseUint(key, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("fee config %s: invalid customer ID %q", path, key)
+		}
+		if err := s.AssignTier(uint(customerID), tier); err != nil {
+			return nil, fmt.Errorf("fee config %s: %w", path, err)
+		}
+	}
+	return s, nil
+}
+
+// SetTier adds or replaces a named tier.
+func (s *schedule) SetTier(name string, rates Rates) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.tiers[name] = rates
+}
+
+// AssignTier moves a customer to a tier.
+func (s *schedule) AssignTier(customerID uint, tier string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.tiers[tier]; !ok {
+		return fmt.Errorf("unknown fee tier: %q", tier)
+	}
+	s.customers[customerID] = tier
+	return nil
+}
+
+// Rates returns the rates charged to a customer.
+func (s *schedule) Rates(customerID uint) Rates {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	if tier, ok := s.customers[customerID]; ok {
+		return s.tiers[tier]
+	}
+	return s.defaults
+}
+
+// Fees returns the fees of the maker and the taker of a trade, each at the
+// rate of their own tier.
+func (s *schedule) Fees(trade model.Trade) (makerFee, takerFee uint64) {
+	notional := trade.Notional()
+	maker := s.Rates(trade.MakerCustomerID)
+	taker := s.Rates(trade.TakerCustomerID)
+	return compute(notional, maker.MakerBps, maker.MinFee), compute(notional, taker.TakerBps, taker.MinFee)
+}
+
+// compute returns the fee on notional at bps, rounded half up, and at least minFee.
+func compute(notional uint64, bps uint, minFee uint64) uint64 {
+	if bps == 0 {
+		return 0
+	}
+
+	// Multiply in 128 bits so large notionals cannot overflow
+	hi, lo := bits.Mul64(notional, uint64(bps))
+	lo, carry := bits.Add64(lo, 5000, 0)
+	hi += carry
+	if hi >= 10000 {
+		return math.MaxUint64
+	}
+	fee, _ := bits.Div64(hi, lo, 10000)
+	return max(fee, minFee)
+}
+
+// Totals returns the fees charged to each customer in the trades, for billing.
+func Totals(trades []*model.Trade) map[uint]uint64 {
+	totals := make(map[uint]uint64)
+	for _, trade := range trades {
+		if trade.MakerFee > 0 {
+			totals[trade.MakerCustomerID] += trade.MakerFee
+		}
+		if trade.TakerFee > 0 {
+			totals[trade.TakerCustomerID] += trade.TakerFee
+		}
+	}
+	return totals
+}
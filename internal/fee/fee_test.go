@@ -0,0 +1,81 @@
+package fee_test
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/fee"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// trade returns a trade where customer 1 takes from customer 2.
+func trade(price model.Price, quantity uint) model.Trade {
+	return model.Trade{TakerCustomerID: 1, MakerCustomerID: 2, Price: price, Quantity: quantity}
+}
+
+// TestSchedule tests fee computation for makers and takers.
+func TestSchedule(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rates    fee.Rates
+		trade    model.Trade
+		makerFee uint64
+		takerFee uint64
+	}{
+		{"No Fees", fee.Rates{}, trade(100, 10), 0, 0},
+		{"Maker and Taker Rates", fee.Rates{MakerBps: 10, TakerBps: 25}, trade(1000, 40), 40, 100},
+		{"Rounded Half Up", fee.Rates{MakerBps: 5, TakerBps: 15}, trade(100, 1), 0, 0},
+		{"Rounded Up at Half", fee.Rates{MakerBps: 50, TakerBps: 150}, trade(100, 1), 1, 2},
+		{"Minimum Fee", fee.Rates{MakerBps: 10, TakerBps: 25, MinFee: 50}, trade(1000, 40), 50, 100},
+		{"Minimum Fee Needs a Rate", fee.Rates{TakerBps: 25, MinFee: 50}, trade(100, 1), 0, 50},
+		{"Large Notional", fee.Rates{MakerBps: 10000}, trade(math.MaxUint64, 1), math.MaxUint64, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			makerFee, takerFee := fee.NewSchedule(tc.rates).Fees(tc.trade)
+			require.Equal(t, tc.makerFee, makerFee)
+			require.Equal(t, tc.takerFee, takerFee)
+		})
+	}
+
+	t.Run("Customer Tiers", func(t *testing.T) {
+		schedule := fee.NewSchedule(fee.Rates{MakerBps: 10, TakerBps: 20})
+		schedule.SetTier("vip", fee.Rates{TakerBps: 5})
+		require.NoError(t, schedule.AssignTier(1, "vip"))
+		require.Error(t, schedule.AssignTier(2, "gold"), "Expected unknown tier to be refused")
+
+		// Each side pays at the rate of its own tier
+		makerFee, takerFee := schedule.Fees(trade(1000, 10))
+		require.Equal(t, uint64(10), makerFee)
+		require.Equal(t, uint64(5), takerFee)
+	})
+
+	t.Run("Load Config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "fees.json")
+		config := `{"default":{"maker_bps":10,"taker_bps":20},"tiers":{"vip":{"taker_bps":5,"min_fee":1}},"customers":{"2":"vip"}}`
+		require.NoError(t, os.WriteFile(path, []byte(config), 0o644))
+
+		schedule, err := fee.LoadSchedule(path)
+		require.NoError(t, err)
+		require.Equal(t, fee.Rates{MakerBps: 10, TakerBps: 20}, schedule.Rates(1))
+		require.Equal(t, fee.Rates{TakerBps: 5, MinFee: 1}, schedule.Rates(2))
+
+		require.NoError(t, os.WriteFile(path, []byte(`{"customers":{"2":"gold"}}`), 0o644))
+		_, err = fee.LoadSchedule(path)
+		require.Error(t, err)
+	})
+}
+
+// TestTotals tests summing fees per customer for billing.
+func TestTotals(t *testing.T) {
+	trades := []*model.Trade{
+		{TakerCustomerID: 1, MakerCustomerID: 2, MakerFee: 3, TakerFee: 7},
+		{TakerCustomerID: 2, MakerCustomerID: 3, TakerFee: 4},
+	}
+	require.Equal(t, map[uint]uint64{1: 7, 2: 7}, fee.Totals(trades))
+}
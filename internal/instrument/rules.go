@@ -0,0 +1,91 @@
+package instrument
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// Rules are the trading rules of an instrument. Prices are in minor units of
+// the currency. A zero rule is not enforced.
+type Rules struct {
+	Symbol         string         `json:"symbol"`
+	Currency       model.Currency `json:"currency"`
+	TickSize       model.Price    `json:"tick_size"` // Prices must be a multiple of the tick size
+	MinPrice       model.Price    `json:"min_price"`
+	MaxPrice       model.Price    `json:"max_price"`
+	BandBps        uint           `json:"band_bps"`        // Dynamic band around the reference price, in basis points
+	ReferencePrice model.Price    `json:"reference_price"` // Reference price until the first trade, e.g. the previous close
+}
+
+// LoadRules reads the trading rules of an instrument from a JSON file.
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return Rules{}, fmt.Errorf("instrument rules %s: %w", path, err)
+	}
+	if rules.Currency.Scale > model.MaxScale {
+		return Rules{}, fmt.Errorf("instrument rules %s: currency scale %d above %d", path, rules.Currency.Scale, model.MaxScale)
+	}
+	if rules.MaxPrice > 0 && rules.MinPrice > rules.MaxPrice {
+		return Rules{}, fmt.Errorf("instrument rules %s: min price %d above max price %d", path, rules.MinPrice, rules.MaxPrice)
+	}
+	return rules, nil
+}
+
+// RoundToTick returns the price rounded up or down to a multiple of the tick
+// size. Rounding down never goes below one tick, rounding up never overflows.
+func (r Rules) RoundToTick(price model.Price, up bool) model.Price {
+	if r.TickSize == 0 || price%r.TickSize == 0 {
+		return price
+	}
+	down := price - price%r.TickSize
+	if up && down <= ^model.Price(0)-r.TickSize {
+		return down + r.TickSize
+	}
+	return max(down, r.TickSize)
+}
+
+// CheckPrice checks a price against the rules. The band is centred on
+// lastTradePrice, or on the reference price before the first trade.
+func (r Rules) CheckPrice(price, lastTradePrice model.Price) error {
+	if err := r.CheckStopPrice(price); err != nil {
+		return err
+	}
+
+	// Check the dynamic band around the reference price
+	reference := lastTradePrice
+	if reference == 0 {
+		reference = r.ReferencePrice
+	}
+	if r.BandBps > 0 && reference > 0 && !price.WithinBand(reference, r.BandBps) {
+		return fmt.Errorf("%w: %d is more than %d bps from %d", reject.ErrPriceOutOfBand, price, r.BandBps, reference)
+	}
+	return nil
+}
+
+// CheckStopPrice checks the tick size and the static price limits of a stop
+// price. The band does not apply: a stop is placed away from the last trade.
+func (r Rules) CheckStopPrice(price model.Price) error {
+	// Check the tick size
+	if r.TickSize > 0 && price%r.TickSize != 0 {
+		return fmt.Errorf("%w: %d is not a multiple of %d", reject.ErrOffTick, price, r.TickSize)
+	}
+
+	// Check the static price limits
+	if r.MinPrice > 0 && price < r.MinPrice {
+		return fmt.Errorf("%w: %d below minimum %d", reject.ErrPriceOutOfRange, price, r.MinPrice)
+	}
+	if r.MaxPrice > 0 && price > r.MaxPrice {
+		return fmt.Errorf("%w: %d above maximum %d", reject.ErrPriceOutOfRange, price, r.MaxPrice)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package instrument_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/instrument"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// TestRules tests each trading rule.
+func TestRules(t *testing.T) {
+	testCases := []struct {
+		name      string
+		rules     instrument.Rules
+		price     model.Price
+		lastTrade model.Price
+		err       error
+	}{
+		{"No Rules", instrument.Rules{}, 1_000_000, 0, nil},
+		{"On Tick", instrument.Rules{TickSize: 5}, 105, 0, nil},
+		{"Off Tick", instrument.Rules{TickSize: 5}, 103, 0, reject.ErrOffTick},
+		{"Below Min Price", instrument.Rules{MinPrice: 10}, 9, 0, reject.ErrPriceOutOfRange},
+		{"At Max Price", instrument.Rules{MaxPrice: 5000}, 5000, 0, nil},
+		{"Above Max Price", instrument.Rules{MaxPrice: 5000}, 5001, 0, reject.ErrPriceOutOfRange},
+		{"Within Band", instrument.Rules{BandBps: 1000}, 110, 100, nil},
+		{"Above Band", instrument.Rules{BandBps: 1000}, 111, 100, reject.ErrPriceOutOfBand},
+		{"Below Band", instrument.Rules{BandBps: 1000}, 89, 100, reject.ErrPriceOutOfBand},
+		{"Band Around Reference Price", instrument.Rules{BandBps: 1000, ReferencePrice: 200}, 230, 0, reject.ErrPriceOutOfBand},
+		{"Last Trade Replaces Reference", instrument.Rules{BandBps: 1000, ReferencePrice: 200}, 105, 100, nil},
+		{"No Band Without Reference", instrument.Rules{BandBps: 1000}, 1_000_000, 0, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rules.CheckPrice(tc.price, tc.lastTrade)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Stop Price", func(t *testing.T) {
+		rules := instrument.Rules{TickSize: 5, MinPrice: 10, MaxPrice: 5000, BandBps: 1000, ReferencePrice: 100}
+		require.NoError(t, rules.CheckStopPrice(4000), "Expected no band on a stop price")
+		require.ErrorIs(t, rules.CheckStopPrice(103), reject.ErrOffTick)
+		require.ErrorIs(t, rules.CheckStopPrice(5), reject.ErrPriceOutOfRange)
+		require.ErrorIs(t, rules.CheckStopPrice(5005), reject.ErrPriceOutOfRange)
+	})
+
+	t.Run("Round To Tick", func(t *testing.T) {
+		rules := instrument.Rules{TickSize: 5}
+		require.Equal(t, model.Price(105), rules.RoundToTick(105, false))
+		require.Equal(t, model.Price(100), rules.RoundToTick(103, false))
+		require.Equal(t, model.Price(105), rules.RoundToTick(103, true))
+		require.Equal(t, model.Price(5), rules.RoundToTick(3, false), "Expected at least one tick")
+		require.Equal(t, model.Price(103), instrument.Rules{}.RoundToTick(103, true))
+	})
+
+	t.Run("Load Rules", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		config := `{"symbol":"TEXTBOOK","tick_size":5,"min_price":5,"max_price":50000,"band_bps":2000}`
+		require.NoError(t, os.WriteFile(path, []byte(config), 0o644))
+
+		rules, err := instrument.LoadRules(path)
+		require.NoError(t, err)
+		require.Equal(t, instrument.Rules{Symbol: "TEXTBOOK", TickSize: 5, MinPrice: 5, MaxPrice: 50000, BandBps: 2000}, rules)
+
+		require.NoError(t, os.WriteFile(path, []byte(`{"min_price":100,"max_price":10}`), 0o644))
+		_, err = instrument.LoadRules(path)
+		require.Error(t, err)
+	})
+}
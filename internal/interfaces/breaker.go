@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+type CircuitBreaker interface {
+	Tripped(trades []*model.Trade) bool
+	Window() time.Duration
+	HaltDuration() time.Duration
+}
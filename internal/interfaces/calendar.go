@@ -0,0 +1,14 @@
+package interfaces
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+type TradingCalendar interface {
+	SessionClose(t time.Time) time.Time
+	CloseOn(date string) (time.Time, error)
+	PhaseAt(t time.Time) constant.MarketPhase
+	NextPhaseChange(t time.Time) (time.Time, constant.MarketPhase)
+}
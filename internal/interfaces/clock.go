@@ -0,0 +1,7 @@
+package interfaces
+
+import "time"
+
+type Clock interface {
+	Now() time.Time
+}
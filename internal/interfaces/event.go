@@ -0,0 +1,11 @@
+package interfaces
+
+import "github.com/trungnt1811/simple-order-book/internal/event"
+
+type EventPublisher interface {
+	Publish(events ...event.Event)
+}
+
+type EventSubscriber interface {
+	Subscribe(handler func(event.Event)) (unsubscribe func())
+}
@@ -0,0 +1,7 @@
+package interfaces
+
+import "github.com/trungnt1811/simple-order-book/internal/model"
+
+type FeeSchedule interface {
+	Fees(trade model.Trade) (makerFee, takerFee uint64)
+}
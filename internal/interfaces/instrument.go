@@ -0,0 +1,9 @@
+package interfaces
+
+import "github.com/trungnt1811/simple-order-book/internal/model"
+
+type TradingRules interface {
+	CheckPrice(price, lastTradePrice model.Price) error
+	CheckStopPrice(price model.Price) error
+	RoundToTick(price model.Price, up bool) model.Price
+}
@@ -8,9 +8,26 @@ import (
 )
 
 type OrderBookUCase interface {
-	SubmitOrder(customerID uint, price uint, orderType constant.OrderType, gtt *time.Time) error
-	CancelOrder(orderID uint64) error
+	SubmitOrder(customerID uint, price model.Price, orderType constant.OrderType, gtt *time.Time) error
+	PlaceOrder(req model.OrderRequest) (uint64, error)
+	CancelOrder(customerID uint, orderID uint64) error
+	AdminCancelOrder(orderID uint64, operator string) error
+	MassCancel(filter model.MassCancelFilter, operator string) ([]uint64, error)
+	CancelOnDisconnect(customerID uint) ([]uint64, error)
+	AmendOrder(customerID uint, orderID uint64, price model.Price, gtt *time.Time) error
+	Deposit(customerID uint, cash uint64, inventory uint) error
+	Withdraw(customerID uint, cash uint64, inventory uint) error
+	SetPhase(phase constant.MarketPhase, operator string) error
+	GetPhase() constant.MarketPhase
+	GetCurrency() model.Currency
+	Uncross(operator string) (model.Auction, error)
+	IndicativeAuction() model.Auction
+	Halt(reason, operator string) error
+	Resume(operator string) error
+	GetHaltedUntil() *time.Time
+	GetAccount(customerID uint) model.Account
 	QueryOrders(customerID uint) []*model.Order
+	Depth(levels int) model.Depth
 	RemoveExpiredBuyOrders()
 	RemoveExpiredSellOrders()
 	GetNextOrderID() uint64
@@ -18,4 +35,7 @@ type OrderBookUCase interface {
 	GetBuyOrders() model.OrderHeap
 	GetOrders() map[uint64]*model.Order
 	GetCustomerOrders() map[uint]map[uint64]*model.Order
+	GetTrades() []*model.Trade
+	GetState() model.BookState
+	Restore(state model.BookState) error
 }
@@ -0,0 +1,7 @@
+package interfaces
+
+import "github.com/trungnt1811/simple-order-book/internal/model"
+
+type RiskChecker interface {
+	Check(req model.OrderRequest, exposure model.RiskExposure) error
+}
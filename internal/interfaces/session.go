@@ -0,0 +1,9 @@
+package interfaces
+
+type SessionManager interface {
+	Connect(customerID uint)
+	Heartbeat(customerID uint) error
+	Disconnect(customerID uint) ([]uint64, error)
+	ExpireSessions()
+	Active(customerID uint) bool
+}
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+type Storage interface {
+	SaveOrder(record *model.OrderRecord) error
+	GetOrder(orderID uint64) (*model.OrderRecord, error)
+	ListCustomerOrders(customerID uint) ([]*model.OrderRecord, error)
+	SaveTrade(trade *model.Trade) error
+	ListCustomerTrades(customerID uint) ([]*model.Trade, error)
+	SaveCustomer(customer *model.Customer) error
+	GetCustomer(customerID uint) (*model.Customer, error)
+	Close() error
+}
@@ -0,0 +1,510 @@
+package journal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// FsyncPolicy controls when appended records are flushed to stable storage.
+type FsyncPolicy string
+
+const (
+	FsyncAlways   FsyncPolicy = "always"   // Sync after every record
+	FsyncInterval FsyncPolicy = "interval" // Sync at most once per Config.FsyncInterval, and within it when idle
+	FsyncNever    FsyncPolicy = "never"    // Leave flushing to the operating system
+)
+
+const (
+	segmentExt = ".wal"
+
+	// Each record is framed as: length (4) | crc32c (4) | seq (8) | payload.
+	// The checksum covers the sequence number and the payload.
+	headerSize = 16
+
+	// maxRecordSize bounds the payload length read from a header, so a
+	// corrupted length cannot trigger a huge allocation.
+	maxRecordSize = 16 << 20 // 16 MiB
+
+	defaultSegmentSize   = 64 << 20 // 64 MiB
+	defaultFsyncInterval = 100 * time.Millisecond
+)
+
+// ErrCorrupted is returned when a record fails its checksum anywhere other
+// than at the tail of the newest segment.
+var ErrCorrupted = errors.New("journal corrupted")
+
+// ErrSyncFailed is returned by every append after a segment failed to sync.
+// Records written before the failure may not be durable, so the journal stops
+// accepting commands until it is reopened and recovered.
+var ErrSyncFailed = errors.New("journal sync failed")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Config configures a journal.
+type Config struct {
+	Dir           string
+	SegmentSize   int64 // Rotate to a new segment once the current one reaches this size
+	Fsync         FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+// Journal is an append-only, checksummed command log split into segments.
+type Journal struct {
+	cfg      Config
+	file     *os.File
+	size     int64
+	lastSeq  uint64
+	lastSync time.Time
+	dirty    bool  // Records were written since the last sync
+	failed   error // Sync failure that stopped the journal
+	stop     chan struct{}
+	done     chan struct{}
+	mtx      sync.Mutex
+	logger   *zap.Logger
+}
+
+// Open opens the journal in cfg.Dir, creating the directory if needed.
+// A torn record at the end of the newest segment, left by a crash during a
+// write, is truncated; a bad record anywhere else is reported as ErrCorrupted.
+func Open(cfg Config, logger *zap.Logger) (*Journal, error) {
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = defaultSegmentSize
+	}
+	if cfg.Fsync == "" {
+		cfg.Fsync = FsyncAlways
+	}
+	if cfg.FsyncInterval <= 0 {
+		cfg.FsyncInterval = defaultFsyncInterval
+	}
+	switch cfg.Fsync {
+	case FsyncAlways, FsyncInterval, FsyncNever:
+	default:
+		return nil, fmt.Errorf("invalid fsync policy: %q", cfg.Fsync)
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	j := &Journal{cfg: cfg, logger: logger}
+
+	segments, err := listSegments(cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate every segment and find the last sequence number
+	for i, segment := range segments {
+		if i == 0 {
+			// Older segments may have been removed after a snapshot
+			firstSeq, err := segmentFirstSeq(segment)
+			if err != nil {
+				return nil, err
+			}
+			j.lastSeq = firstSeq - 1
+		}
+		last := i == len(segments)-1
+		lastSeq, validSize, err := scanSegment(segment, j.lastSeq, nil)
+		if err != nil {
+			if !last || !errors.Is(err, errTornWrite) {
+				return nil, err
+			}
+			// Drop the torn tail of the newest segment
+			logger.Warn("Truncating torn journal record", zap.String("segment", segment), zap.Int64("offset", validSize))
+			if err := os.Truncate(segment, validSize); err != nil {
+				return nil, err
+			}
+		}
+		j.lastSeq = lastSeq
+		if last {
+			if err := j.openSegment(segment, validSize); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if j.file == nil {
+		if err := j.rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Fsync == FsyncInterval {
+		j.stop = make(chan struct{})
+		j.done = make(chan struct{})
+		go j.flushIdle(j.stop)
+	}
+	return j, nil
+}
+
+// LastSeq returns the sequence number of the last appended record.
+func (j *Journal) LastSeq() uint64 {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+	return j.lastSeq
+}
+
+// Append assigns the next sequence number to cmd and writes it to the journal.
+// The record is durable according to the configured fsync policy when Append returns.
+func (j *Journal) Append(cmd *model.Command) error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if j.file == nil {
+		return errors.New("journal closed")
+	}
+	if j.failed != nil {
+		return j.failed
+	}
+
+	seq := j.lastSeq + 1
+	cmd.Seq = seq
+	payload, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	record := encodeRecord(seq, payload)
+	if j.size > 0 && j.size+int64(len(record)) > j.cfg.SegmentSize {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := j.file.Write(record); err != nil {
+		// Drop any partially written record so later appends stay readable
+		if truncErr := j.file.Truncate(j.size); truncErr != nil {
+			j.logger.Error("Failed to truncate partial journal record", zap.Error(truncErr))
+		}
+		return err
+	}
+	prevSize, prevSeq := j.size, j.lastSeq
+	j.size += int64(len(record))
+	j.lastSeq = seq
+	j.dirty = true
+
+	if err := j.maybeSync(); err != nil {
+		// The command is refused, so its record must not be recovered
+		if truncErr := j.file.Truncate(prevSize); truncErr != nil {
+			j.logger.Error("Failed to truncate unsynced journal record", zap.Error(truncErr))
+		}
+		j.size, j.lastSeq = prevSize, prevSeq
+		return err
+	}
+	return nil
+}
+
+// Read calls fn for every record with a sequence number greater than afterSeq,
+// in order. It fails with ErrCorrupted if a checksum does not match or if the
+// records after afterSeq are not all present.
+func (j *Journal) Read(afterSeq uint64, fn func(model.Command) error) error {
+	segments, err := listSegments(j.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	prevSeq := afterSeq
+	started := false
+	for i, segment := range segments {
+		// Skip segments that only hold records up to afterSeq
+		if i+1 < len(segments) {
+			nextFirstSeq, err := segmentFirstSeq(segments[i+1])
+			if err != nil {
+				return err
+			}
+			if nextFirstSeq <= afterSeq+1 {
+				continue
+			}
+		}
+
+		if !started {
+			firstSeq, err := segmentFirstSeq(segment)
+			if err != nil {
+				return err
+			}
+			if firstSeq > afterSeq+1 {
+				return fmt.Errorf("%w: missing records %d to %d", ErrCorrupted, afterSeq+1, firstSeq-1)
+			}
+			prevSeq = firstSeq - 1
+			started = true
+		}
+
+		prevSeq, _, err = scanSegment(segment, prevSeq, func(cmd model.Command) error {
+			if cmd.Seq <= afterSeq {
+				return nil
+			}
+			return fn(cmd)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if prevSeq < afterSeq {
+		return fmt.Errorf("%w: journal ends at %d, expected at least %d", ErrCorrupted, prevSeq, afterSeq)
+	}
+	return nil
+}
+
+// Compact removes segments that only contain records up to seq, typically
+// once a snapshot covering them has been saved. The active segment is kept.
+func (j *Journal) Compact(seq uint64) error {
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	segments, err := listSegments(j.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(segments); i++ {
+		nextFirstSeq, err := segmentFirstSeq(segments[i+1])
+		if err != nil {
+			return err
+		}
+		if nextFirstSeq > seq+1 {
+			break
+		}
+		if err := os.Remove(segments[i]); err != nil {
+			return err
+		}
+		j.logger.Debug("Journal segment removed", zap.String("segment", segments[i]))
+	}
+	return nil
+}
+
+// Close stops the idle flush, then syncs and closes the current segment.
+func (j *Journal) Close() error {
+	j.mtx.Lock()
+	stop := j.stop
+	j.stop = nil
+	j.mtx.Unlock()
+	if stop != nil {
+		close(stop)
+		<-j.done
+	}
+
+	j.mtx.Lock()
+	defer j.mtx.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.sync()
+	if closeErr := j.file.Close(); err == nil {
+		err = closeErr
+	}
+	j.file = nil
+	return err
+}
+
+// maybeSync flushes the current segment according to the fsync policy.
+func (j *Journal) maybeSync() error {
+	switch j.cfg.Fsync {
+	case FsyncAlways:
+		return j.sync()
+	case FsyncInterval:
+		if time.Since(j.lastSync) < j.cfg.FsyncInterval {
+			return nil
+		}
+		return j.sync()
+	default:
+		return nil
+	}
+}
+
+// sync flushes the current segment. A failure is sticky: the kernel may have
+// dropped the dirty pages, so earlier records cannot be trusted to be durable.
+func (j *Journal) sync() error {
+	if j.failed != nil {
+		return j.failed
+	}
+	if err := j.file.Sync(); err != nil {
+		j.failed = fmt.Errorf("%w: %v", ErrSyncFailed, err)
+		j.logger.Error("Journal sync failed, refusing further appends", zap.Error(err))
+		return j.failed
+	}
+	j.lastSync = time.Now()
+	j.dirty = false
+	return nil
+}
+
+// flushIdle syncs records left unsynced by the interval policy once the
+// interval has passed, so they do not wait for the next append.
+func (j *Journal) flushIdle(stop <-chan struct{}) {
+	defer close(j.done)
+
+	ticker := time.NewTicker(j.cfg.FsyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			j.mtx.Lock()
+			if j.file != nil && j.dirty && j.failed == nil {
+				_ = j.sync() // Logged, and returned by the next append
+			}
+			j.mtx.Unlock()
+		}
+	}
+}
+
+// rotate closes the current segment and starts a new one named after the next sequence number.
+func (j *Journal) rotate() error {
+	if j.file != nil {
+		if err := j.sync(); err != nil {
+			return err
+		}
+		if err := j.file.Close(); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(j.cfg.Dir, segmentName(j.lastSeq+1))
+	if err := j.openSegment(path, 0); err != nil {
+		return err
+	}
+	j.logger.Debug("Journal segment opened", zap.String("segment", path))
+
+	// Make the new segment's directory entry durable
+	return syncDir(j.cfg.Dir)
+}
+
+// openSegment opens path for appending at offset size.
+func (j *Journal) openSegment(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	j.file = f
+	j.size = size
+	return nil
+}
+
+// segmentName returns the file name of a segment whose first record has seq.
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%s", seq, segmentExt)
+}
+
+// segmentFirstSeq parses the sequence number of the first record from a segment path.
+func segmentFirstSeq(path string) (uint64, error) {
+	var seq uint64
+	name := strings.TrimSuffix(filepath.Base(path), segmentExt)
+	if _, err := fmt.Sscanf(name, "%d", &seq); err != nil || seq == 0 {
+		return 0, fmt.Errorf("invalid segment name: %s", filepath.Base(path))
+	}
+	return seq, nil
+}
+
+// listSegments returns the segment paths in dir, oldest first.
+func listSegments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), segmentExt) {
+			continue
+		}
+		segments = append(segments, filepath.Join(dir, entry.Name()))
+	}
+	// Zero-padded names sort in sequence order
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// encodeRecord frames a payload with its length, checksum and sequence number.
+func encodeRecord(seq uint64, payload []byte) []byte {
+	record := make([]byte, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint64(record[8:16], seq)
+	copy(record[headerSize:], payload)
+	binary.LittleEndian.PutUint32(record[4:8], crc32.Checksum(record[8:], crcTable))
+	return record
+}
+
+// errTornWrite marks an incomplete or checksum-failing record.
+var errTornWrite = fmt.Errorf("%w: torn write", ErrCorrupted)
+
+// scanSegment reads every record in path, checking checksums and that
+// sequence numbers follow prevSeq without gaps. fn, if not nil, is called
+// for each decoded command. It returns the last sequence number and the
+// size of the valid prefix of the segment.
+func scanSegment(path string, prevSeq uint64, fn func(model.Command) error) (uint64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return prevSeq, 0, err
+	}
+	defer f.Close()
+
+	var offset int64
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(f, header); err != nil {
+			if err == io.EOF {
+				return prevSeq, offset, nil
+			}
+			return prevSeq, offset, errTornWrite
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:4])
+		checksum := binary.LittleEndian.Uint32(header[4:8])
+		seq := binary.LittleEndian.Uint64(header[8:16])
+		if length > maxRecordSize {
+			return prevSeq, offset, errTornWrite
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			return prevSeq, offset, errTornWrite
+		}
+
+		crc := crc32.Update(crc32.Checksum(header[8:16], crcTable), crcTable, payload)
+		if crc != checksum {
+			return prevSeq, offset, errTornWrite
+		}
+		if seq != prevSeq+1 {
+			return prevSeq, offset, fmt.Errorf("%w: %s: expected seq %d, got %d", ErrCorrupted, filepath.Base(path), prevSeq+1, seq)
+		}
+
+		if fn != nil {
+			var cmd model.Command
+			if err := json.Unmarshal(payload, &cmd); err != nil {
+				return prevSeq, offset, fmt.Errorf("%w: %s: seq %d: %v", ErrCorrupted, filepath.Base(path), seq, err)
+			}
+			if err := fn(cmd); err != nil {
+				return prevSeq, offset, err
+			}
+		}
+
+		prevSeq = seq
+		offset += int64(headerSize) + int64(length)
+	}
+}
+
+// syncDir flushes directory metadata so newly created segments survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
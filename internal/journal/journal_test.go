@@ -0,0 +1,179 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/journal"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/util"
+)
+
+// submitCommand returns a submit command for tests.
+func submitCommand(price model.Price) *model.Command {
+	return &model.Command{
+		Timestamp:  time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC),
+		Action:     constant.SubmitCommand,
+		CustomerID: 1,
+		Price:      price,
+		OrderType:  constant.BuyOrder,
+	}
+}
+
+// segments returns the segment files in dir.
+func segments(t *testing.T, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	require.NoError(t, err)
+	return matches
+}
+
+// TestJournal tests appending to and reopening the journal.
+func TestJournal(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Append assigns sequence numbers", func(t *testing.T) {
+		j, err := journal.Open(journal.Config{Dir: t.TempDir()}, logger)
+		require.NoError(t, err)
+		defer j.Close()
+
+		for i := uint64(1); i <= 3; i++ {
+			cmd := submitCommand(100)
+			require.NoError(t, j.Append(cmd))
+			require.Equal(t, i, cmd.Seq, "Expected sequence number %d", i)
+		}
+		require.Equal(t, uint64(3), j.LastSeq())
+	})
+
+	t.Run("Reopen continues the sequence", func(t *testing.T) {
+		dir := t.TempDir()
+		j, err := journal.Open(journal.Config{Dir: dir}, logger)
+		require.NoError(t, err)
+		require.NoError(t, j.Append(submitCommand(100)))
+		require.NoError(t, j.Append(submitCommand(101)))
+		require.NoError(t, j.Close())
+
+		j, err = journal.Open(journal.Config{Dir: dir}, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, uint64(2), j.LastSeq())
+
+		cmd := submitCommand(102)
+		require.NoError(t, j.Append(cmd))
+		require.Equal(t, uint64(3), cmd.Seq)
+	})
+
+	t.Run("Segments rotate by size", func(t *testing.T) {
+		dir := t.TempDir()
+		j, err := journal.Open(journal.Config{Dir: dir, SegmentSize: 256, Fsync: journal.FsyncNever}, logger)
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, j.Append(submitCommand(100)))
+		}
+		require.NoError(t, j.Close())
+		require.Greater(t, len(segments(t, dir)), 1, "Expected more than one segment")
+
+		// All segments are read back in order
+		j, err = journal.Open(journal.Config{Dir: dir, SegmentSize: 256}, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, uint64(10), j.LastSeq())
+	})
+
+	t.Run("Torn tail is truncated", func(t *testing.T) {
+		dir := t.TempDir()
+		j, err := journal.Open(journal.Config{Dir: dir}, logger)
+		require.NoError(t, err)
+		require.NoError(t, j.Append(submitCommand(100)))
+		require.NoError(t, j.Append(submitCommand(101)))
+		require.NoError(t, j.Close())
+
+		// Simulate a crash in the middle of the last write
+		segment := segments(t, dir)[0]
+		info, err := os.Stat(segment)
+		require.NoError(t, err)
+		require.NoError(t, os.Truncate(segment, info.Size()-3))
+
+		j, err = journal.Open(journal.Config{Dir: dir}, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, uint64(1), j.LastSeq(), "Expected the torn record to be dropped")
+
+		cmd := submitCommand(102)
+		require.NoError(t, j.Append(cmd))
+		require.Equal(t, uint64(2), cmd.Seq)
+	})
+
+	t.Run("Corruption in an older segment", func(t *testing.T) {
+		dir := t.TempDir()
+		j, err := journal.Open(journal.Config{Dir: dir, SegmentSize: 256}, logger)
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			require.NoError(t, j.Append(submitCommand(100)))
+		}
+		require.NoError(t, j.Close())
+
+		// Flip a payload byte in the first segment
+		segment := segments(t, dir)[0]
+		data, err := os.ReadFile(segment)
+		require.NoError(t, err)
+		data[20] ^= 0xff
+		require.NoError(t, os.WriteFile(segment, data, 0o644))
+
+		_, err = journal.Open(journal.Config{Dir: dir, SegmentSize: 256}, logger)
+		require.ErrorIs(t, err, journal.ErrCorrupted)
+	})
+
+	t.Run("Read after a sequence number", func(t *testing.T) {
+		dir := t.TempDir()
+		j, err := journal.Open(journal.Config{Dir: dir, SegmentSize: 256}, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		for i := uint(1); i <= 10; i++ {
+			require.NoError(t, j.Append(submitCommand(model.Price(100+i))))
+		}
+
+		prices := []model.Price{}
+		err = j.Read(6, func(cmd model.Command) error {
+			prices = append(prices, cmd.Price)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []model.Price{107, 108, 109, 110}, prices)
+
+		// Compacted records can no longer be read
+		require.NoError(t, j.Compact(6))
+		require.NoError(t, j.Read(6, func(model.Command) error { return nil }))
+		require.ErrorIs(t, j.Read(0, func(model.Command) error { return nil }), journal.ErrCorrupted)
+	})
+
+	t.Run("Interval policy flushes while idle", func(t *testing.T) {
+		dir := t.TempDir()
+		cfg := journal.Config{Dir: dir, Fsync: journal.FsyncInterval, FsyncInterval: time.Millisecond}
+		j, err := journal.Open(cfg, logger)
+		require.NoError(t, err)
+		require.NoError(t, j.Append(submitCommand(100)))
+		require.NoError(t, j.Append(submitCommand(101)))
+
+		// The idle flush runs alongside appends and stops with the journal
+		time.Sleep(5 * time.Millisecond)
+		require.NoError(t, j.Append(submitCommand(102)))
+		require.NoError(t, j.Close())
+		require.NoError(t, j.Close())
+
+		j, err = journal.Open(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, uint64(3), j.LastSeq())
+	})
+
+	t.Run("Invalid fsync policy", func(t *testing.T) {
+		_, err := journal.Open(journal.Config{Dir: t.TempDir(), Fsync: "sometimes"}, logger)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,23 @@
+package model
+
+// Account holds the cash and inventory of a customer. Reserved amounts back
+// the customer's resting orders: cash for bids, inventory for asks. Trading
+// fees are taken from the cash that is not reserved.
+type Account struct {
+	CustomerID        uint   `json:"customer_id"`
+	Cash              uint64 `json:"cash"`
+	CashReserved      uint64 `json:"cash_reserved"`
+	Inventory         uint   `json:"inventory"`
+	InventoryReserved uint   `json:"inventory_reserved"`
+	FeesOwed          uint64 `json:"fees_owed,omitempty"` // Fees the cash could not cover, paid from the next cash credited
+}
+
+// AvailableCash returns the cash not backing resting bids.
+func (a Account) AvailableCash() uint64 {
+	return a.Cash - a.CashReserved
+}
+
+// AvailableInventory returns the inventory not backing resting asks.
+func (a Account) AvailableInventory() uint {
+	return a.Inventory - a.InventoryReserved
+}
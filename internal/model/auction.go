@@ -0,0 +1,16 @@
+package model
+
+// Auction is the outcome of uncrossing the book at a single price.
+type Auction struct {
+	Price      Price `json:"price"`       // Clearing price, 0 if the book does not cross
+	Volume     uint  `json:"volume"`      // Quantity executed at the clearing price
+	BidSurplus uint  `json:"bid_surplus"` // Bid quantity at or above the price left unfilled
+	AskSurplus uint  `json:"ask_surplus"` // Ask quantity at or below the price left unfilled
+}
+
+// AuctionFill is one trade of an auction between a bid and an ask.
+type AuctionFill struct {
+	Bid      *Order
+	Ask      *Order
+	Quantity uint
+}
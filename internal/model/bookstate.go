@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// BookState is a point-in-time copy of an order book, used for snapshots.
+type BookState struct {
+	Seq            uint64               `json:"seq"` // Last journaled command reflected in the state
+	NextOrderID    uint64               `json:"next_order_id"`
+	NextTradeID    uint64               `json:"next_trade_id"`
+	LastTradePrice Price                `json:"last_trade_price,omitempty"`
+	Phase          constant.MarketPhase `json:"phase,omitempty"`
+	HaltedUntil    *time.Time           `json:"halted_until,omitempty"`   // End of a circuit breaker halt
+	BreakerTrades  []*Trade             `json:"breaker_trades,omitempty"` // Trades in the circuit breaker's window
+	Orders         []*Order             `json:"orders"`                   // Resting orders sorted by ID
+	Accounts       []*Account           `json:"accounts,omitempty"`       // Customer accounts sorted by customer ID
+}
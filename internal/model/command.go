@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// Command is a single instruction to the order book, as captured in a JSONL stream.
+type Command struct {
+	Seq        uint64                 `json:"seq,omitempty"`
+	Timestamp  time.Time              `json:"ts"`
+	Action     constant.CommandAction `json:"action"`
+	CustomerID uint                   `json:"customer_id,omitempty"`
+	Admin      string                 `json:"admin,omitempty"` // Operator of an admin command
+	OrderID    uint64                 `json:"order_id,omitempty"`
+	Price      Price                  `json:"price,omitempty"`
+	StopPrice  Price                  `json:"stop_price,omitempty"`
+	Quantity   uint                   `json:"quantity,omitempty"` // Order quantity, or inventory of a deposit or withdrawal
+	Cash       uint64                 `json:"cash,omitempty"`     // Cash of a deposit or withdrawal
+	OrderType  constant.OrderType     `json:"side"`
+	GTT        *time.Time             `json:"gtt,omitempty"`
+	Filter     *MassCancelFilter      `json:"filter,omitempty"` // Orders selected by a mass cancel
+	Phase      constant.MarketPhase   `json:"phase,omitempty"`  // Phase moved to by a phase command
+	Reason     string                 `json:"reason,omitempty"` // Reason of a halt
+
+	KeepOnDisconnect bool `json:"keep_on_disconnect,omitempty"`
+	DisplayQuantity  uint `json:"display_quantity,omitempty"`
+
+	Peg       constant.PegType `json:"peg,omitempty"`
+	PegOffset int64            `json:"peg_offset,omitempty"`
+
+	AllOrNone   bool `json:"all_or_none,omitempty"`
+	MinQuantity uint `json:"min_quantity,omitempty"`
+
+	TimeInForce constant.TimeInForce `json:"time_in_force,omitempty"`
+	ExpireDate  string               `json:"expire_date,omitempty"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Customer holds the reference data of a marketplace customer.
+type Customer struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// PriceLevel is the displayed quantity of the orders resting at one price.
+type PriceLevel struct {
+	Price    Price `json:"price"`
+	Quantity uint  `json:"quantity"`
+	Orders   int   `json:"orders"`
+}
+
+// Depth is the displayed order book, best price first on each side.
+// Hidden iceberg reserves and stop orders are not part of it. Prices are in
+// minor units of the currency.
+type Depth struct {
+	Currency Currency     `json:"currency"`
+	Bids     []PriceLevel `json:"bids"`
+	Asks     []PriceLevel `json:"asks"`
+}
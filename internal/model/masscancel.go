@@ -0,0 +1,31 @@
+package model
+
+import (
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// MassCancelFilter selects the resting orders of a mass cancel.
+// Unset fields match every order, so an empty filter matches the whole book.
+type MassCancelFilter struct {
+	CustomerID *uint               `json:"customer_id,omitempty"`
+	Side       *constant.OrderType `json:"side,omitempty"`
+	MinPrice   Price               `json:"min_price,omitempty"` // Inclusive, 0 for no lower bound
+	MaxPrice   Price               `json:"max_price,omitempty"` // Inclusive, 0 for no upper bound
+}
+
+// Matches reports whether the order is selected by the filter.
+func (f MassCancelFilter) Matches(order *Order) bool {
+	if f.CustomerID != nil && order.CustomerID != *f.CustomerID {
+		return false
+	}
+	if f.Side != nil && order.OrderType != *f.Side {
+		return false
+	}
+	if order.Price < f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != 0 && order.Price > f.MaxPrice {
+		return false
+	}
+	return true
+}
@@ -7,10 +7,123 @@ import (
 )
 
 type Order struct {
-	ID         uint64
-	CustomerID uint
-	Price      uint
-	Timestamp  time.Time
-	OrderType  constant.OrderType
-	GTT        *time.Time // Good Til Time
+	ID         uint64             `json:"id"`
+	CustomerID uint               `json:"customer_id"`
+	Price      Price              `json:"price"`                // Limit price, 0 for a market order, current price of a pegged order
+	StopPrice  Price              `json:"stop_price,omitempty"` // Trigger price of a stop order
+	Quantity   uint               `json:"quantity"`             // Original quantity
+	Remaining  uint               `json:"remaining"`            // Quantity still open
+	Timestamp  time.Time          `json:"timestamp"`
+	OrderType  constant.OrderType `json:"side"`
+	GTT        *time.Time         `json:"gtt,omitempty"` // Good Til Time
+
+	CancelOnDisconnect bool `json:"cancel_on_disconnect,omitempty"` // Cancelled when the customer's session drops
+	Triggered          bool `json:"triggered,omitempty"`            // The stop price was reached
+	DisplayQuantity    uint `json:"display_quantity,omitempty"`     // Peak shown by an iceberg order, 0 to show everything
+	Visible            uint `json:"visible,omitempty"`              // Shown quantity of an iceberg order, the rest is hidden
+
+	Peg       constant.PegType `json:"peg,omitempty"`        // Reference price the order follows
+	PegOffset int64            `json:"peg_offset,omitempty"` // Added to the reference price
+	PegLimit  Price            `json:"peg_limit,omitempty"`  // Worst price of a pegged order, 0 for none
+
+	AllOrNone   bool `json:"all_or_none,omitempty"`  // Fills its whole remaining quantity in one match or not at all
+	MinQuantity uint `json:"min_quantity,omitempty"` // Smallest quantity one match may fill
+
+	TimeInForce constant.TimeInForce `json:"time_in_force,omitempty"` // Day and good-till-date orders expire at a session close
+}
+
+// HasSessionExpiry reports whether the order expires at a session close.
+func (o *Order) HasSessionExpiry() bool {
+	return o.TimeInForce == constant.GoodForDay || o.TimeInForce == constant.GoodTillDate
+}
+
+// IsPendingStop reports whether the order waits in the trigger book.
+func (o *Order) IsPendingStop() bool {
+	return o.StopPrice > 0 && !o.Triggered
+}
+
+// IsIceberg reports whether the order shows only part of its quantity.
+func (o *Order) IsIceberg() bool {
+	return o.DisplayQuantity > 0
+}
+
+// Displayed returns the quantity others can see and trade against before an
+// iceberg order is replenished.
+func (o *Order) Displayed() uint {
+	if o.IsIceberg() {
+		return o.Visible
+	}
+	return o.Remaining
+}
+
+// Fill takes quantity off the remaining and displayed quantity.
+func (o *Order) Fill(quantity uint) {
+	o.Remaining -= quantity
+	if o.IsIceberg() {
+		o.Visible -= min(quantity, o.Visible)
+	}
+}
+
+// Replenish shows the next slice of an iceberg order's hidden reserve.
+func (o *Order) Replenish() {
+	o.Visible = min(o.DisplayQuantity, o.Remaining)
+}
+
+// MinFill returns the smallest quantity a single match may fill: the whole
+// remaining quantity of an all-or-none order, else its minimum quantity.
+func (o *Order) MinFill() uint {
+	if o.AllOrNone {
+		return o.Remaining
+	}
+	return min(o.MinQuantity, o.Remaining)
+}
+
+// IsConstrained reports whether a match may have to leave the order untouched.
+func (o *Order) IsConstrained() bool {
+	return o.MinFill() > 1
+}
+
+// IsMarket reports whether the order trades at any price.
+func (o *Order) IsMarket() bool {
+	return o.Price == 0
+}
+
+// IsPegged reports whether the order's price follows the top of the book.
+func (o *Order) IsPegged() bool {
+	return o.Peg != ""
+}
+
+// ReservedPrice returns the price a buy order's cash is reserved at: the
+// limit of a pegged order, which bounds every price it moves to, or its price.
+func (o *Order) ReservedPrice() Price {
+	return max(o.Price, o.PegLimit)
+}
+
+// RemainingNotional returns the reserved price times remaining quantity. It
+// cannot overflow: the notional of an order is checked when it is accepted,
+// amended and, for a pegged order, repriced.
+func (o *Order) RemainingNotional() uint64 {
+	return uint64(o.ReservedPrice()) * uint64(o.Remaining)
+}
+
+// OrderRequest describes a new order before the book accepts it.
+type OrderRequest struct {
+	CustomerID uint               `json:"customer_id"`
+	Price      Price              `json:"price"`                // 0 with a stop price for a stop market order, limit of a pegged order
+	StopPrice  Price              `json:"stop_price,omitempty"` // Makes the order a stop order
+	Quantity   uint               `json:"quantity"`
+	OrderType  constant.OrderType `json:"side"`
+	GTT        *time.Time         `json:"gtt,omitempty"`
+
+	KeepOnDisconnect bool `json:"keep_on_disconnect,omitempty"` // Opt out of cancel-on-disconnect
+	DisplayQuantity  uint `json:"display_quantity,omitempty"`   // Makes the order an iceberg showing this much at a time
+
+	Peg       constant.PegType `json:"peg,omitempty"`        // Makes the order follow a reference price
+	PegOffset int64            `json:"peg_offset,omitempty"` // Added to the reference price of a pegged order
+
+	AllOrNone   bool `json:"all_or_none,omitempty"`  // Only fill the whole quantity at once
+	MinQuantity uint `json:"min_quantity,omitempty"` // Only fill at least this much at once
+
+	TimeInForce constant.TimeInForce `json:"time_in_force,omitempty"` // Sets the GTT to a session close, unless a GTT is given
+	ExpireDate  string               `json:"expire_date,omitempty"`   // Last trading date of a good-till-date order, "2006-01-02"
 }
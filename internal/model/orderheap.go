@@ -4,8 +4,9 @@ import "github.com/trungnt1811/simple-order-book/internal/constant"
 
 // OrderHeap is a priority queue for orders, implemented as a container/heap.
 type OrderHeap struct {
-	Orders []*Order
-	Type   constant.OrderType // Max heap for buy order, min heap for sell order
+	Orders      []*Order
+	Type        constant.OrderType // Max heap for buy order, min heap for sell order
+	ByStopPrice bool               // Trigger book: orders by stop price, the next to trigger first
 }
 
 // Len returns the number of orders in the heap.
@@ -15,13 +16,22 @@ func (h OrderHeap) Len() int {
 
 // Less compares two orders in the heap.
 func (h OrderHeap) Less(i, j int) bool {
-	if h.Orders[i].Price == h.Orders[j].Price {
+	pi, pj := h.Orders[i].Price, h.Orders[j].Price
+	if h.ByStopPrice {
+		pi, pj = h.Orders[i].StopPrice, h.Orders[j].StopPrice
+	}
+	if pi == pj {
+		// Orders with identical timestamps fall back to submission order
+		if h.Orders[i].Timestamp.Equal(h.Orders[j].Timestamp) {
+			return h.Orders[i].ID < h.Orders[j].ID
+		}
 		return h.Orders[i].Timestamp.Before(h.Orders[j].Timestamp)
 	}
-	if h.Type == constant.BuyOrder {
-		return h.Orders[i].Price > h.Orders[j].Price
+	// Buy stops trigger as the price rises, so the lowest stop comes first
+	if (h.Type == constant.BuyOrder) != h.ByStopPrice {
+		return pi > pj
 	}
-	return h.Orders[i].Price < h.Orders[j].Price
+	return pi < pj
 }
 
 // Swap swaps two orders in the heap.
@@ -12,7 +12,7 @@ import (
 
 type expectedOrder struct {
 	CustomerID uint
-	Price      uint
+	Price      model.Price
 }
 
 // TestOrderHeap tests the OrderHeap implementation.
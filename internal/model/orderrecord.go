@@ -0,0 +1,15 @@
+package model
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// OrderRecord is the persisted history entry of an order.
+type OrderRecord struct {
+	Order
+	Status      constant.OrderStatus `json:"status"`
+	CancelledBy string               `json:"cancelled_by,omitempty"` // "customer:<id>", "admin:<operator>", "disconnect", "unfilled" or "repriced"
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
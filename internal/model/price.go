@@ -0,0 +1,113 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPriceSyntax = errors.New("invalid price syntax")
+	ErrOverflow    = errors.New("value overflows 64 bits")
+)
+
+// MaxScale is the largest number of decimal places a currency can have.
+const MaxScale = 18
+
+// Price is a fixed-point price counted in minor units of the instrument's
+// currency, e.g. 1249 is $12.49 when the currency has a scale of 2.
+type Price uint64
+
+// Notional returns price times quantity in minor units, or ErrOverflow if
+// it does not fit in 64 bits.
+func (p Price) Notional(quantity uint) (uint64, error) {
+	hi, lo := bits.Mul64(uint64(p), uint64(quantity))
+	if hi != 0 {
+		return 0, fmt.Errorf("%w: %d x %d", ErrOverflow, p, quantity)
+	}
+	return lo, nil
+}
+
+// AddNotional returns a + b, or ErrOverflow if the sum does not fit in 64 bits.
+func AddNotional(a, b uint64) (uint64, error) {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return 0, fmt.Errorf("%w: %d + %d", ErrOverflow, a, b)
+	}
+	return sum, nil
+}
+
+// Offset returns the price moved by offset minor units, kept between 1 and
+// the largest price.
+func (p Price) Offset(offset int64) Price {
+	if offset < 0 {
+		down := Price(-(offset + 1)) + 1
+		if down >= p {
+			return 1
+		}
+		return p - down
+	}
+	if Price(offset) > ^Price(0)-p {
+		return ^Price(0)
+	}
+	return p + Price(offset)
+}
+
+// WithinBand reports whether the price is at most bps basis points away from
+// the reference price.
+func (p Price) WithinBand(reference Price, bps uint) bool {
+	deviation := max(p, reference) - min(p, reference)
+
+	// Compare deviation * 10000 with reference * bps in 128 bits
+	devHi, devLo := bits.Mul64(uint64(deviation), 10000)
+	refHi, refLo := bits.Mul64(uint64(reference), uint64(bps))
+	return devHi < refHi || (devHi == refHi && devLo <= refLo)
+}
+
+// Currency is the currency of an instrument's prices and the number of
+// decimal places of its minor unit, e.g. USD with a scale of 2 counts cents.
+type Currency struct {
+	Code  string `json:"code"`
+	Scale uint8  `json:"scale"`
+}
+
+// Parse reads an exact decimal amount such as "12.49" as a price. Digits
+// beyond the scale must be zero: prices are never rounded.
+func (c Currency) Parse(s string) (Price, error) {
+	if c.Scale > MaxScale {
+		return 0, fmt.Errorf("currency %s: scale %d above %d", c.Code, c.Scale, MaxScale)
+	}
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || strings.Trim(whole, "0123456789") != "" || strings.Trim(fraction, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: %q", ErrPriceSyntax, s)
+	}
+	if len(fraction) > int(c.Scale) {
+		if strings.Trim(fraction[c.Scale:], "0") != "" {
+			return 0, fmt.Errorf("%w: %q has more than %d decimal places", ErrPriceSyntax, s, c.Scale)
+		}
+		fraction = fraction[:c.Scale]
+	}
+	fraction += strings.Repeat("0", int(c.Scale)-len(fraction))
+
+	value, err := strconv.ParseUint(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrOverflow, s)
+	}
+	return Price(value), nil
+}
+
+// Format writes the price as an exact decimal amount, e.g. "12.49".
+func (c Currency) Format(p Price) string {
+	digits := strconv.FormatUint(uint64(p), 10)
+	if c.Scale == 0 {
+		return digits
+	}
+	if pad := int(c.Scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(c.Scale)
+	return digits[:point] + "." + digits[point:]
+}
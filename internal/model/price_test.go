@@ -0,0 +1,84 @@
+package model_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// TestPrice tests parsing, formatting and notional arithmetic of prices.
+func TestPrice(t *testing.T) {
+	usd := model.Currency{Code: "USD", Scale: 2}
+
+	t.Run("Parse", func(t *testing.T) {
+		testCases := []struct {
+			input string
+			price model.Price
+			err   error
+		}{
+			{"12.49", 1249, nil},
+			{"12.5", 1250, nil},
+			{"12", 1200, nil},
+			{"0.07", 7, nil},
+			{"12.490", 1249, nil},
+			{"12.491", 0, model.ErrPriceSyntax},
+			{"-1.00", 0, model.ErrPriceSyntax},
+			{".50", 0, model.ErrPriceSyntax},
+			{"1e3", 0, model.ErrPriceSyntax},
+			{"", 0, model.ErrPriceSyntax},
+			{"184467440737095516.16", 0, model.ErrOverflow},
+		}
+		for _, tc := range testCases {
+			price, err := usd.Parse(tc.input)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err, "Parsing %q", tc.input)
+				continue
+			}
+			require.NoError(t, err, "Parsing %q", tc.input)
+			require.Equal(t, tc.price, price, "Parsing %q", tc.input)
+		}
+	})
+
+	t.Run("Format", func(t *testing.T) {
+		require.Equal(t, "12.49", usd.Format(1249))
+		require.Equal(t, "0.07", usd.Format(7))
+		require.Equal(t, "0.00", usd.Format(0))
+		require.Equal(t, "1000", model.Currency{Code: "JPY"}.Format(1000))
+		require.Equal(t, "184467440737095516.15", usd.Format(math.MaxUint64))
+
+		// Formatting and parsing round-trip
+		price, err := usd.Parse(usd.Format(math.MaxUint64))
+		require.NoError(t, err)
+		require.Equal(t, model.Price(math.MaxUint64), price)
+	})
+
+	t.Run("Notional", func(t *testing.T) {
+		notional, err := model.Price(1249).Notional(3)
+		require.NoError(t, err)
+		require.Equal(t, uint64(3747), notional)
+
+		_, err = model.Price(math.MaxUint64 / 2).Notional(3)
+		require.ErrorIs(t, err, model.ErrOverflow)
+
+		_, err = model.AddNotional(math.MaxUint64, 1)
+		require.ErrorIs(t, err, model.ErrOverflow)
+	})
+
+	t.Run("Offset", func(t *testing.T) {
+		require.Equal(t, model.Price(105), model.Price(100).Offset(5))
+		require.Equal(t, model.Price(95), model.Price(100).Offset(-5))
+		require.Equal(t, model.Price(1), model.Price(100).Offset(-100))
+		require.Equal(t, model.Price(1), model.Price(100).Offset(math.MinInt64))
+		require.Equal(t, model.Price(math.MaxUint64), model.Price(math.MaxUint64-1).Offset(2))
+	})
+
+	t.Run("Within Band", func(t *testing.T) {
+		require.True(t, model.Price(110).WithinBand(100, 1000))
+		require.False(t, model.Price(111).WithinBand(100, 1000))
+		require.False(t, model.Price(89).WithinBand(100, 1000))
+		require.False(t, model.Price(math.MaxUint64).WithinBand(math.MaxUint64/2, 5000), "Expected no overflow")
+	})
+}
@@ -0,0 +1,9 @@
+package model
+
+// RiskExposure is the state of a customer in the book that pre-trade risk
+// checks compare a new order against.
+type RiskExposure struct {
+	OpenOrders     int    // Resting orders of the customer
+	BidNotional    uint64 // Sum of price * remaining quantity of the customer's resting buy orders
+	LastTradePrice Price  // Price of the last trade in the book, 0 before the first trade
+}
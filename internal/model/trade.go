@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+)
+
+// Trade is an execution between an incoming (taker) order and a resting (maker) order.
+type Trade struct {
+	ID              uint64             `json:"id"`
+	TakerOrderID    uint64             `json:"taker_order_id"`
+	MakerOrderID    uint64             `json:"maker_order_id"`
+	TakerCustomerID uint               `json:"taker_customer_id"`
+	MakerCustomerID uint               `json:"maker_customer_id"`
+	TakerSide       constant.OrderType `json:"taker_side"`
+	Price           Price              `json:"price"` // The maker's price, or the clearing price in an auction
+	Quantity        uint               `json:"quantity"`
+	MakerFee        uint64             `json:"maker_fee,omitempty"`
+	TakerFee        uint64             `json:"taker_fee,omitempty"`
+	Timestamp       time.Time          `json:"timestamp"`
+}
+
+// Notional returns price times quantity. It cannot overflow: a trade is
+// bounded by its orders, whose notional is checked whenever their price is set.
+func (t Trade) Notional() uint64 {
+	return uint64(t.Price) * uint64(t.Quantity)
+}
+
+// BuyerCustomerID returns the customer on the buy side of the trade.
+func (t Trade) BuyerCustomerID() uint {
+	if t.TakerSide == constant.BuyOrder {
+		return t.TakerCustomerID
+	}
+	return t.MakerCustomerID
+}
+
+// SellerCustomerID returns the customer on the sell side of the trade.
+func (t Trade) SellerCustomerID() uint {
+	if t.TakerSide == constant.BuyOrder {
+		return t.MakerCustomerID
+	}
+	return t.TakerCustomerID
+}
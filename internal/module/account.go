@@ -0,0 +1,238 @@
+package module
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// WithAccounts enforces customer balances: bids reserve cash and asks
+// reserve inventory when accepted, the reservation is released when the
+// order leaves the book and consumed when it trades.
+func WithAccounts() Option {
+	return func(ob *OrderBook) {
+		ob.accountsEnabled = true
+	}
+}
+
+// Deposit adds cash and inventory to the account of a customer.
+func (ob *OrderBook) Deposit(customerID uint, cash uint64, inventory uint) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+
+	// Validate amounts
+	if cash == 0 && inventory == 0 {
+		return fmt.Errorf("%w: nothing to deposit", reject.ErrInvalidQuantity)
+	}
+	if balance := ob.Accounts[customerID]; balance != nil {
+		if _, err := model.AddNotional(balance.Cash, cash); err != nil {
+			return fmt.Errorf("%w: %v", reject.ErrInvalidQuantity, err)
+		}
+	}
+
+	// Journal the accepted command before touching the account
+	if err := ob.journalCommand(&model.Command{
+		Timestamp:  ob.clock.Now(),
+		Action:     constant.DepositCommand,
+		CustomerID: customerID,
+		Cash:       cash,
+		Quantity:   inventory,
+	}); err != nil {
+		return err
+	}
+
+	account := ob.account(customerID)
+	creditCash(account, cash)
+	account.Inventory += inventory
+
+	ob.logger.Debug("Deposit", zap.Uint("customerID", customerID), zap.Uint64("cash", cash), zap.Uint("inventory", inventory))
+	return nil
+}
+
+// Withdraw takes cash and inventory out of the account of a customer.
+// Only amounts not reserved by resting orders can be withdrawn.
+func (ob *OrderBook) Withdraw(customerID uint, cash uint64, inventory uint) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+
+	// Validate amounts
+	if cash == 0 && inventory == 0 {
+		return fmt.Errorf("%w: nothing to withdraw", reject.ErrInvalidQuantity)
+	}
+	account := ob.Accounts[customerID]
+	if account == nil {
+		account = &model.Account{CustomerID: customerID}
+	}
+	if cash > account.AvailableCash() {
+		return fmt.Errorf("%w: %d available, %d requested", reject.ErrNoFunds, account.AvailableCash(), cash)
+	}
+	if inventory > account.AvailableInventory() {
+		return fmt.Errorf("%w: %d available, %d requested", reject.ErrNoInventory, account.AvailableInventory(), inventory)
+	}
+
+	// Journal the accepted command before touching the account
+	if err := ob.journalCommand(&model.Command{
+		Timestamp:  ob.clock.Now(),
+		Action:     constant.WithdrawCommand,
+		CustomerID: customerID,
+		Cash:       cash,
+		Quantity:   inventory,
+	}); err != nil {
+		return err
+	}
+
+	account = ob.account(customerID)
+	account.Cash -= cash
+	account.Inventory -= inventory
+
+	ob.logger.Debug("Withdraw", zap.Uint("customerID", customerID), zap.Uint64("cash", cash), zap.Uint("inventory", inventory))
+	return nil
+}
+
+// GetAccount returns a copy of the account of a customer.
+func (ob *OrderBook) GetAccount(customerID uint) model.Account {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+
+	if account, ok := ob.Accounts[customerID]; ok {
+		return *account
+	}
+	return model.Account{CustomerID: customerID}
+}
+
+// account returns the account of a customer, creating it if needed.
+func (ob *OrderBook) account(customerID uint) *model.Account {
+	account, ok := ob.Accounts[customerID]
+	if !ok {
+		account = &model.Account{CustomerID: customerID}
+		ob.Accounts[customerID] = account
+	}
+	return account
+}
+
+// accountStates returns copies of the accounts sorted by customer ID.
+func (ob *OrderBook) accountStates() []*model.Account {
+	accounts := make([]*model.Account, 0, len(ob.Accounts))
+	for _, account := range ob.Accounts {
+		copied := *account
+		accounts = append(accounts, &copied)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].CustomerID < accounts[j].CustomerID
+	})
+	return accounts
+}
+
+// checkFunds returns an error if the customer cannot back the order.
+// The reservation of an order being replaced counts as available.
+func (ob *OrderBook) checkFunds(req model.OrderRequest, replaced *model.Order) error {
+	if !ob.accountsEnabled {
+		return nil
+	}
+
+	account := ob.Accounts[req.CustomerID]
+	if account == nil {
+		account = &model.Account{CustomerID: req.CustomerID}
+	}
+
+	if req.OrderType == constant.BuyOrder {
+		// The cost of a market buy or an uncapped pegged buy is unknown until it trades
+		if req.Price == 0 {
+			return fmt.Errorf("%w: market and pegged buy orders need a limit price", reject.ErrNoFunds)
+		}
+		available := account.AvailableCash()
+		if replaced != nil {
+			available += replaced.RemainingNotional()
+		}
+		if required, _ := req.Price.Notional(req.Quantity); required > available {
+			return fmt.Errorf("%w: %d available, %d required", reject.ErrNoFunds, available, required)
+		}
+		return nil
+	}
+
+	available := account.AvailableInventory()
+	if replaced != nil {
+		available += replaced.Remaining
+	}
+	if req.Quantity > available {
+		return fmt.Errorf("%w: %d available, %d required", reject.ErrNoInventory, available, req.Quantity)
+	}
+	return nil
+}
+
+// reserve sets aside the cash or inventory backing the remaining quantity of an order.
+func (ob *OrderBook) reserve(order *model.Order) {
+	if !ob.accountsEnabled {
+		return
+	}
+
+	account := ob.account(order.CustomerID)
+	if order.OrderType == constant.BuyOrder {
+		account.CashReserved += order.RemainingNotional()
+	} else {
+		account.InventoryReserved += order.Remaining
+	}
+}
+
+// release returns the reservation of the remaining quantity of an order.
+func (ob *OrderBook) release(order *model.Order) {
+	if !ob.accountsEnabled || order.Remaining == 0 {
+		return
+	}
+
+	account := ob.account(order.CustomerID)
+	if order.OrderType == constant.BuyOrder {
+		account.CashReserved -= order.RemainingNotional()
+	} else {
+		account.InventoryReserved -= order.Remaining
+	}
+}
+
+// settleTrade moves cash from the buyer to the seller and inventory the other
+// way, consuming the reservations of both orders, then charges the maker and
+// taker fees. A buyer whose limit is above the trade price gets the
+// difference back.
+func (ob *OrderBook) settleTrade(trade *model.Trade, buy, sell *model.Order) {
+	if !ob.accountsEnabled {
+		return
+	}
+
+	cost := trade.Notional()
+
+	buyer := ob.account(buy.CustomerID)
+	buyer.CashReserved -= uint64(buy.ReservedPrice()) * uint64(trade.Quantity)
+	buyer.Cash -= cost
+	buyer.Inventory += trade.Quantity
+
+	seller := ob.account(sell.CustomerID)
+	seller.InventoryReserved -= trade.Quantity
+	seller.Inventory -= trade.Quantity
+	creditCash(seller, cost)
+
+	buyerFee, sellerFee := trade.TakerFee, trade.MakerFee
+	if trade.TakerSide == constant.SellOrder {
+		buyerFee, sellerFee = sellerFee, buyerFee
+	}
+	chargeFee(buyer, buyerFee)
+	chargeFee(seller, sellerFee)
+}
+
+// creditCash adds cash to an account, paying the fees it owes first.
+func creditCash(account *model.Account, cash uint64) {
+	paid := min(cash, account.FeesOwed)
+	account.FeesOwed -= paid
+	account.Cash += cash - paid
+}
+
+// chargeFee takes a fee from the cash not reserved by resting bids, so the
+// reservations stay covered. What that cash cannot pay is owed.
+func chargeFee(account *model.Account, fee uint64) {
+	paid := min(fee, account.AvailableCash())
+	account.Cash -= paid
+	account.FeesOwed += fee - paid
+}
@@ -0,0 +1,179 @@
+package module_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/clock"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/fee"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+	"github.com/trungnt1811/simple-order-book/internal/util"
+)
+
+// requireReservations checks the reservations of every account match the
+// resting orders of the customer.
+func requireReservations(t *testing.T, orderBook interfaces.OrderBookUCase, customerIDs ...uint) {
+	for _, customerID := range customerIDs {
+		var cash uint64
+		var inventory uint
+		for _, order := range orderBook.GetCustomerOrders()[customerID] {
+			if order.OrderType == constant.BuyOrder {
+				cash += uint64(order.ReservedPrice()) * uint64(order.Remaining)
+			} else {
+				inventory += order.Remaining
+			}
+		}
+		account := orderBook.GetAccount(customerID)
+		require.Equal(t, cash, account.CashReserved, "Cash reserved by customer %d", customerID)
+		require.Equal(t, inventory, account.InventoryReserved, "Inventory reserved by customer %d", customerID)
+	}
+}
+
+// TestOrderBookUCase_Accounts tests funds and inventory reservation.
+func TestOrderBookUCase_Accounts(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	buyer, seller := uint(1), uint(2)
+
+	// newBook returns a book with accounts enforced and funded customers
+	newBook := func(t *testing.T, clk *clock.Manual) interfaces.OrderBookUCase {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithAccounts())
+		require.NoError(t, orderBook.Deposit(buyer, 1000, 0))
+		require.NoError(t, orderBook.Deposit(seller, 0, 10))
+		return orderBook
+	}
+
+	t.Run("Insufficient Funds And Inventory", func(t *testing.T) {
+		orderBook := newBook(t, clock.NewManual(startTime))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 101, Quantity: 10, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrNoFunds)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: seller, Price: 100, Quantity: 11, OrderType: constant.SellOrder})
+		require.ErrorIs(t, err, reject.ErrNoInventory)
+
+		// A customer cannot sell what they do not own
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 100, Quantity: 1, OrderType: constant.SellOrder})
+		require.ErrorIs(t, err, reject.ErrNoInventory)
+		require.Equal(t, 0, len(orderBook.GetOrders()))
+	})
+
+	t.Run("Reserve And Release", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := newBook(t, clk)
+
+		orderID, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 100, Quantity: 6, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: seller, Price: 120, Quantity: 4, OrderType: constant.SellOrder, GTT: createGTT(clk, 1)})
+		require.NoError(t, err)
+		require.Equal(t, uint64(400), orderBook.GetAccount(buyer).AvailableCash())
+		require.Equal(t, uint(6), orderBook.GetAccount(seller).AvailableInventory())
+		requireReservations(t, orderBook, buyer, seller)
+
+		// Reserved cash cannot be used twice or withdrawn
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 100, Quantity: 5, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrNoFunds)
+		require.ErrorIs(t, orderBook.Withdraw(buyer, 401, 0), reject.ErrNoFunds)
+
+		// Amending re-reserves at the new price
+		require.NoError(t, orderBook.AmendOrder(buyer, orderID, 110, nil))
+		require.Equal(t, uint64(340), orderBook.GetAccount(buyer).AvailableCash())
+		require.ErrorIs(t, orderBook.AmendOrder(buyer, orderID, 200, nil), reject.ErrNoFunds)
+
+		// Cancel and expiry release the reservations
+		require.NoError(t, orderBook.CancelOrder(buyer, orderID))
+		clk.Advance(2 * time.Hour)
+		orderBook.RemoveExpiredSellOrders()
+		require.Equal(t, uint64(1000), orderBook.GetAccount(buyer).AvailableCash())
+		require.Equal(t, uint(10), orderBook.GetAccount(seller).AvailableInventory())
+		requireReservations(t, orderBook, buyer, seller)
+
+		require.NoError(t, orderBook.Withdraw(buyer, 1000, 0))
+		require.Equal(t, uint64(0), orderBook.GetAccount(buyer).Cash)
+	})
+
+	t.Run("Settle Trades", func(t *testing.T) {
+		orderBook := newBook(t, clock.NewManual(startTime))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: seller, Price: 90, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		// The buyer reserves 5 at 100 and trades 3 at 90
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 100, Quantity: 5, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		account := orderBook.GetAccount(buyer)
+		require.Equal(t, uint64(730), account.Cash)
+		require.Equal(t, uint64(200), account.CashReserved, "Expected the unfilled 2 at 100 to stay reserved")
+		require.Equal(t, uint(3), account.Inventory)
+
+		account = orderBook.GetAccount(seller)
+		require.Equal(t, uint64(270), account.Cash)
+		require.Equal(t, uint(7), account.Inventory)
+		require.Equal(t, uint(0), account.InventoryReserved)
+		requireReservations(t, orderBook, buyer, seller)
+
+		// The bought inventory can be sold
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 150, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		requireReservations(t, orderBook, buyer, seller)
+	})
+
+	t.Run("Accounts Survive Snapshots", func(t *testing.T) {
+		orderBook := newBook(t, clock.NewManual(startTime))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 100, Quantity: 2, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		restored := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithAccounts())
+		require.NoError(t, restored.Restore(orderBook.GetState()))
+		require.Equal(t, orderBook.GetAccount(buyer), restored.GetAccount(buyer))
+		requireReservations(t, restored, buyer, seller)
+	})
+
+	t.Run("Fees Come Out Of Cash", func(t *testing.T) {
+		schedule := fee.NewSchedule(fee.Rates{MakerBps: 10, TakerBps: 30})
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithAccounts(), module.WithFeeSchedule(schedule))
+		require.NoError(t, orderBook.Deposit(buyer, 10000, 0))
+		require.NoError(t, orderBook.Deposit(seller, 0, 10))
+
+		// The seller makes, the buyer takes 5 at 1000
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: seller, Price: 1000, Quantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 1000, Quantity: 5, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		require.Equal(t, uint64(10000-5000-15), orderBook.GetAccount(buyer).Cash)
+		require.Equal(t, uint64(5000-5), orderBook.GetAccount(seller).Cash)
+		requireReservations(t, orderBook, buyer, seller)
+	})
+
+	t.Run("Fees The Cash Cannot Cover Are Owed", func(t *testing.T) {
+		schedule := fee.NewSchedule(fee.Rates{MakerBps: 10, TakerBps: 10, MinFee: 120})
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithAccounts(), module.WithFeeSchedule(schedule))
+		require.NoError(t, orderBook.Deposit(buyer, 200, 0))
+		require.NoError(t, orderBook.Deposit(seller, 0, 10))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: seller, Price: 50, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 40, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: buyer, Price: 50, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		// The resting bid keeps its 40 reserved, the rest of the fee is owed
+		require.Equal(t, model.Account{CustomerID: buyer, Cash: 40, CashReserved: 40, Inventory: 1, FeesOwed: 10}, orderBook.GetAccount(buyer))
+		require.Equal(t, model.Account{CustomerID: seller, Inventory: 9, FeesOwed: 70}, orderBook.GetAccount(seller))
+		requireReservations(t, orderBook, buyer, seller)
+
+		// The next cash credited pays the owed fees first
+		require.NoError(t, orderBook.Deposit(seller, 100, 0))
+		require.Equal(t, model.Account{CustomerID: seller, Cash: 30, Inventory: 9}, orderBook.GetAccount(seller))
+		require.ErrorIs(t, orderBook.Withdraw(seller, 31, 0), reject.ErrNoFunds)
+	})
+}
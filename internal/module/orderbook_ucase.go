@@ -3,39 +3,174 @@ package module
 import (
 	"container/heap"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/trungnt1811/simple-order-book/internal/allocation"
+	"github.com/trungnt1811/simple-order-book/internal/auction"
+	"github.com/trungnt1811/simple-order-book/internal/clock"
 	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/event"
 	"github.com/trungnt1811/simple-order-book/internal/interfaces"
 	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
 )
 
 // TODO: Consider implementing a fine-grained locking mechanism
 
 // orderBook manages buy and sell orders.
 type OrderBook struct {
-	BuyOrders      *model.OrderHeap
-	SellOrders     *model.OrderHeap
-	Orders         map[uint64]*model.Order          // All orders by ID
-	CustomerOrders map[uint]map[uint64]*model.Order // Orders by customer ID and order ID
-	NextOrderID    uint64
-	mtx            sync.RWMutex
-	logger         *zap.Logger
+	BuyOrders       *model.OrderHeap
+	SellOrders      *model.OrderHeap
+	BuyStops        *model.OrderHeap                 // Buy stop orders waiting for their trigger
+	SellStops       *model.OrderHeap                 // Sell stop orders waiting for their trigger
+	Orders          map[uint64]*model.Order          // All orders by ID
+	CustomerOrders  map[uint]map[uint64]*model.Order // Orders by customer ID and order ID
+	Trades          []*model.Trade                   // Executed trades in execution order
+	Accounts        map[uint]*model.Account          // Cash and inventory by customer ID
+	NextOrderID     uint64
+	NextTradeID     uint64
+	LastSeq         uint64      // Sequence number of the last journaled command
+	LastTradePrice  model.Price // Price of the last trade, 0 before the first trade
+	Phase           constant.MarketPhase
+	HaltedUntil     *time.Time     // End of a circuit breaker halt, nil for a manual halt
+	BreakerTrades   []*model.Trade // Trades in the circuit breaker's window since the last reopening
+	mtx             sync.RWMutex
+	logger          *zap.Logger
+	clock           interfaces.Clock
+	journal         interfaces.Journal
+	events          interfaces.EventPublisher
+	risk            interfaces.RiskChecker
+	fees            interfaces.FeeSchedule
+	rules           interfaces.TradingRules
+	calendar        interfaces.TradingCalendar
+	allocator       interfaces.Allocator
+	breaker         interfaces.CircuitBreaker
+	tradeHistory    int                     // Number of trades kept in memory, 0 for all
+	currency        model.Currency          // Currency of the prices, minor units without one
+	accountsEnabled bool                    // Orders must be backed by the customer's account
+	pendingEvents   []event.Event           // Events of the current command, published when it completes
+	bookChanged     bool                    // The current command added, filled or removed a resting order
+	pegged          map[uint64]*model.Order // Resting pegged orders by ID
+	pegBid, pegAsk  model.Price             // Best limit prices the pegged orders were last repriced against
+	constrained     map[uint64]*model.Order // Resting orders with a fill constraint by ID
+	buyGained       bool                    // Buy orders were added since the constrained sells were last retried
+	sellGained      bool                    // Sell orders were added since the constrained buys were last retried
+}
+
+// Option configures optional dependencies of the order book.
+type Option func(*OrderBook)
+
+// WithClock overrides the clock used for order timestamps and GTT checks.
+// It allows tests and the batch replay to drive the book deterministically.
+func WithClock(clock interfaces.Clock) Option {
+	return func(ob *OrderBook) {
+		ob.clock = clock
+	}
+}
+
+// WithJournal records every accepted command in the journal before it mutates the book.
+func WithJournal(journal interfaces.Journal) Option {
+	return func(ob *OrderBook) {
+		ob.journal = journal
+	}
+}
+
+// WithEventBus publishes the lifecycle events of every order to the bus.
+func WithEventBus(events interfaces.EventPublisher) Option {
+	return func(ob *OrderBook) {
+		ob.events = events
+	}
+}
+
+// WithRiskChecker runs pre-trade risk checks on new and amended orders.
+func WithRiskChecker(risk interfaces.RiskChecker) Option {
+	return func(ob *OrderBook) {
+		ob.risk = risk
+	}
+}
+
+// WithFeeSchedule charges maker and taker fees on every trade.
+func WithFeeSchedule(fees interfaces.FeeSchedule) Option {
+	return func(ob *OrderBook) {
+		ob.fees = fees
+	}
+}
+
+// WithTradingRules validates order prices against the instrument's trading rules.
+func WithTradingRules(rules interfaces.TradingRules) Option {
+	return func(ob *OrderBook) {
+		ob.rules = rules
+	}
+}
+
+// WithCalendar lets day and good-till-date orders expire at the close of a
+// trading session.
+func WithCalendar(calendar interfaces.TradingCalendar) Option {
+	return func(ob *OrderBook) {
+		ob.calendar = calendar
+	}
+}
+
+// WithCircuitBreaker halts the book when its trade price moves too far
+// within the breaker's window.
+func WithCircuitBreaker(breaker interfaces.CircuitBreaker) Option {
+	return func(ob *OrderBook) {
+		ob.breaker = breaker
+	}
+}
+
+// WithAllocator shares incoming orders between the resting orders of a price
+// level with another algorithm than time priority.
+func WithAllocator(allocator interfaces.Allocator) Option {
+	return func(ob *OrderBook) {
+		ob.allocator = allocator
+	}
+}
+
+// WithTradeHistory keeps only the last limit trades in memory, so a long
+// running book does not grow without bound. A zero limit keeps every trade.
+func WithTradeHistory(limit int) Option {
+	return func(ob *OrderBook) {
+		ob.tradeHistory = limit
+	}
+}
+
+// WithCurrency sets the currency the book's prices are counted in. The book
+// itself only sees minor units; API layers use it to parse and format prices.
+func WithCurrency(currency model.Currency) Option {
+	return func(ob *OrderBook) {
+		ob.currency = currency
+	}
 }
 
 // NewOrderBookUCase creates a new order book ucase.
-func NewOrderBookUCase(logger *zap.Logger) interfaces.OrderBookUCase {
-	return &OrderBook{
+func NewOrderBookUCase(logger *zap.Logger, opts ...Option) interfaces.OrderBookUCase {
+	ob := &OrderBook{
 		BuyOrders:      &model.OrderHeap{Type: constant.BuyOrder},
 		SellOrders:     &model.OrderHeap{Type: constant.SellOrder},
+		BuyStops:       &model.OrderHeap{Type: constant.BuyOrder, ByStopPrice: true},
+		SellStops:      &model.OrderHeap{Type: constant.SellOrder, ByStopPrice: true},
 		Orders:         make(map[uint64]*model.Order),
 		CustomerOrders: make(map[uint]map[uint64]*model.Order),
+		Accounts:       make(map[uint]*model.Account),
+		pegged:         make(map[uint64]*model.Order),
+		constrained:    make(map[uint64]*model.Order),
 		NextOrderID:    1,
+		NextTradeID:    1,
+		Phase:          constant.ContinuousPhase,
 		logger:         logger,
+		clock:          clock.NewRealClock(),
+		allocator:      allocation.FIFO{},
 	}
+	for _, opt := range opts {
+		opt(ob)
+	}
+	return ob
 }
 
 // GetNextOrderID returns the next available order ID.
@@ -68,247 +203,1862 @@ func (ob *OrderBook) GetCustomerOrders() map[uint]map[uint64]*model.Order {
 	return ob.CustomerOrders
 }
 
-// SubmitOrder submit an order.
-func (ob *OrderBook) SubmitOrder(customerID uint, price uint, orderType constant.OrderType, gtt *time.Time) error {
+// GetTrades returns the trades executed by the order book, oldest first: all
+// of them, or at least the last ones of the trade history. Trades are never
+// modified once recorded, so the returned slice can be read while the book
+// keeps matching.
+func (ob *OrderBook) GetTrades() []*model.Trade {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+	return ob.Trades[:len(ob.Trades):len(ob.Trades)]
+}
+
+// GetState returns a consistent copy of the book for snapshotting.
+func (ob *OrderBook) GetState() model.BookState {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+
+	orders := make([]*model.Order, 0, len(ob.Orders))
+	for _, order := range ob.Orders {
+		copied := *order
+		orders = append(orders, &copied)
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
+
+	return model.BookState{
+		Seq:            ob.LastSeq,
+		NextOrderID:    ob.NextOrderID,
+		NextTradeID:    ob.NextTradeID,
+		LastTradePrice: ob.LastTradePrice,
+		Phase:          ob.Phase,
+		HaltedUntil:    ob.HaltedUntil,
+		BreakerTrades:  append([]*model.Trade(nil), ob.BreakerTrades...),
+		Orders:         orders,
+		Accounts:       ob.accountStates(),
+	}
+}
+
+// Restore loads a snapshot into an empty order book, keeping the original
+// timestamps so time priority is preserved.
+func (ob *OrderBook) Restore(state model.BookState) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+
+	if len(ob.Orders) > 0 || len(ob.Accounts) > 0 || ob.LastSeq > 0 {
+		return fmt.Errorf("order book is not empty")
+	}
+
+	for _, order := range state.Orders {
+		if order.ID >= state.NextOrderID {
+			return fmt.Errorf("order ID %d not below next order ID %d", order.ID, state.NextOrderID)
+		}
+		ob.addOrder(order)
+	}
+	for _, account := range state.Accounts {
+		copied := *account
+		ob.Accounts[account.CustomerID] = &copied
+	}
+	ob.NextOrderID = state.NextOrderID
+	ob.NextTradeID = state.NextTradeID
+	ob.LastTradePrice = state.LastTradePrice
+	if state.Phase != "" {
+		ob.Phase = state.Phase
+	}
+	ob.HaltedUntil = state.HaltedUntil
+	ob.BreakerTrades = append([]*model.Trade(nil), state.BreakerTrades...)
+	ob.LastSeq = state.Seq
+	ob.bookChanged = false
+
+	ob.logger.Info("Order book restored", zap.Uint64("seq", state.Seq), zap.Int("orders", len(state.Orders)))
+	return nil
+}
+
+// SubmitOrder submit an order for a single unit.
+func (ob *OrderBook) SubmitOrder(customerID uint, price model.Price, orderType constant.OrderType, gtt *time.Time) error {
+	_, err := ob.PlaceOrder(model.OrderRequest{
+		CustomerID: customerID,
+		Price:      price,
+		Quantity:   1,
+		OrderType:  orderType,
+		GTT:        gtt,
+	})
+	return err
+}
+
+// PlaceOrder submits an order and returns its ID.
+// The order is matched against the opposite side until it is filled or no
+// longer crosses; any remaining quantity rests in the book.
+func (ob *OrderBook) PlaceOrder(req model.OrderRequest) (uint64, error) {
 	ob.mtx.Lock()
 	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	timestamp := ob.clock.Now()
+
+	// Refuse orders in market phases without order entry
+	if err := ob.checkPhase(ob.Phase.AcceptsOrders()); err != nil {
+		return 0, ob.rejectOrder(req, 0, err)
+	}
 
 	// Validate inputs
-	if orderType != constant.BuyOrder && orderType != constant.SellOrder {
-		err := fmt.Errorf("invalid order type")
-		ob.logger.Error("Invalid order type", zap.Error(err))
-		return err
+	if req.OrderType != constant.BuyOrder && req.OrderType != constant.SellOrder {
+		ob.logger.Error("Invalid order type", zap.Error(reject.ErrInvalidSide))
+		return 0, ob.rejectOrder(req, 0, reject.ErrInvalidSide)
 	}
 
-	// Validate price
-	if price == 0 {
-		err := fmt.Errorf("invalid price")
-		ob.logger.Error("Invalid price", zap.Error(err))
-		return err
+	// Validate price, only stop orders can be market orders and a pegged
+	// order's price is an optional limit
+	if req.Price == 0 && req.StopPrice == 0 && req.Peg == "" {
+		ob.logger.Error("Invalid price", zap.Error(reject.ErrInvalidPrice))
+		return 0, ob.rejectOrder(req, 0, reject.ErrInvalidPrice)
+	}
+	if req.Price != 0 {
+		if err := ob.checkPrice(req.Price); err != nil {
+			return 0, ob.rejectOrder(req, 0, err)
+		}
+	}
+	if req.StopPrice != 0 {
+		if err := ob.checkStopPrice(req.StopPrice); err != nil {
+			return 0, ob.rejectOrder(req, 0, err)
+		}
+	}
+
+	// Validate peg, a pegged order starts from the current reference price
+	var pegPrice model.Price
+	if req.Peg != "" {
+		price, err := ob.checkPeg(req)
+		if err != nil {
+			ob.logger.Error("Invalid peg", zap.Error(err))
+			return 0, ob.rejectOrder(req, 0, err)
+		}
+		if err := ob.checkPrice(price); err != nil {
+			return 0, ob.rejectOrder(req, 0, err)
+		}
+		pegPrice = price
+	}
+
+	// Validate quantity
+	if req.Quantity == 0 {
+		ob.logger.Error("Invalid quantity", zap.Error(reject.ErrInvalidQuantity))
+		return 0, ob.rejectOrder(req, 0, reject.ErrInvalidQuantity)
+	}
+
+	// An iceberg cannot show more than its quantity
+	if req.DisplayQuantity > req.Quantity {
+		err := fmt.Errorf("%w: display quantity %d above quantity %d", reject.ErrInvalidQuantity, req.DisplayQuantity, req.Quantity)
+		return 0, ob.rejectOrder(req, 0, err)
+	}
+
+	// A fill constraint must be satisfiable by the quantity traded at once
+	if req.MinQuantity > req.Quantity {
+		err := fmt.Errorf("%w: minimum quantity %d above quantity %d", reject.ErrInvalidQuantity, req.MinQuantity, req.Quantity)
+		return 0, ob.rejectOrder(req, 0, err)
+	}
+	if (req.AllOrNone || req.MinQuantity > 1) && req.DisplayQuantity > 0 {
+		err := fmt.Errorf("%w: an iceberg cannot have a fill constraint", reject.ErrInvalidQuantity)
+		return 0, ob.rejectOrder(req, 0, err)
+	}
+
+	// Refuse orders whose notional does not fit in 64 bits
+	if _, err := max(req.Price, pegPrice).Notional(req.Quantity); err != nil {
+		ob.logger.Error("Notional overflow", zap.Error(err))
+		return 0, ob.rejectOrder(req, 0, fmt.Errorf("%w: %v", reject.ErrNotionalOverflow, err))
+	}
+
+	// Day and good-till-date orders get the session close as their GTT
+	gtt, err := ob.sessionExpiry(req, timestamp)
+	if err != nil {
+		ob.logger.Error("Invalid time in force", zap.Error(err))
+		return 0, ob.rejectOrder(req, 0, err)
+	}
+	req.GTT = gtt
+
+	// Refuse orders whose GTT (Good Til Time) has already passed
+	if req.GTT != nil && !req.GTT.After(timestamp) {
+		ob.logger.Debug("Order expired on arrival", zap.Uint("customerID", req.CustomerID))
+		return 0, ob.rejectOrder(req, 0, reject.ErrExpiredOnArrival)
+	}
+
+	// Run pre-trade risk checks, a pegged order at its starting price like
+	// a repriced one
+	riskReq := req
+	riskReq.Price = max(req.Price, pegPrice)
+	if err := ob.checkRisk(riskReq, nil); err != nil {
+		return 0, ob.rejectOrder(req, 0, err)
+	}
+
+	// Check the customer can back the order
+	if err := ob.checkFunds(req, nil); err != nil {
+		return 0, ob.rejectOrder(req, 0, err)
+	}
+
+	// Journal the accepted command before touching the book
+	if err := ob.journalCommand(&model.Command{
+		Timestamp:  timestamp,
+		Action:     constant.SubmitCommand,
+		CustomerID: req.CustomerID,
+		Price:      req.Price,
+		StopPrice:  req.StopPrice,
+		Quantity:   req.Quantity,
+		OrderType:  req.OrderType,
+		GTT:        req.GTT,
+
+		KeepOnDisconnect: req.KeepOnDisconnect,
+		DisplayQuantity:  req.DisplayQuantity,
+		Peg:              req.Peg,
+		PegOffset:        req.PegOffset,
+		AllOrNone:        req.AllOrNone,
+		MinQuantity:      req.MinQuantity,
+		TimeInForce:      req.TimeInForce,
+		ExpireDate:       req.ExpireDate,
+	}); err != nil {
+		return 0, err
 	}
 
 	// Create a new order
 	order := &model.Order{
 		ID:         ob.NextOrderID,
-		CustomerID: customerID,
-		Price:      price,
-		Timestamp:  time.Now(),
-		GTT:        gtt,
-		OrderType:  orderType,
+		CustomerID: req.CustomerID,
+		Price:      req.Price,
+		StopPrice:  req.StopPrice,
+		Quantity:   req.Quantity,
+		Remaining:  req.Quantity,
+		Timestamp:  timestamp,
+		GTT:        req.GTT,
+		OrderType:  req.OrderType,
+
+		// Orders are cancelled when their customer's session drops unless opted out
+		CancelOnDisconnect: !req.KeepOnDisconnect,
+		DisplayQuantity:    req.DisplayQuantity,
+		Peg:                req.Peg,
+		PegOffset:          req.PegOffset,
+		AllOrNone:          req.AllOrNone,
+		MinQuantity:        req.MinQuantity,
+		TimeInForce:        req.TimeInForce,
+	}
+	if order.IsIceberg() {
+		order.Replenish()
+	}
+	if order.IsPegged() {
+		order.Price = pegPrice
+		order.PegLimit = req.Price
 	}
 
 	ob.NextOrderID++
+	ob.reserve(order)
+	ob.emit(event.OrderAccepted{Header: ob.header(timestamp), Order: *order})
 
-	// Try to match the order
-	ob.matchOrder(order, orderType)
-	return nil
+	// Try to match the order, then trigger the stops and pegs it moved
+	ob.submitOrder(order)
+	ob.runTriggers(timestamp)
+	return order.ID, nil
 }
 
-// CancelOrder cancels an existing order by ID.
-func (ob *OrderBook) CancelOrder(orderID uint64) error {
+// CancelOrder cancels an order on behalf of the customer who owns it.
+func (ob *OrderBook) CancelOrder(customerID uint, orderID uint64) error {
 	ob.mtx.Lock()
 	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	req := model.OrderRequest{CustomerID: customerID}
+
+	// Refuse cancels in market phases that freeze the book
+	if err := ob.checkPhase(ob.Phase.AcceptsCancels()); err != nil {
+		return ob.rejectOrder(req, orderID, err)
+	}
 
 	// Check if the order exists in the order book
 	order, exists := ob.Orders[orderID]
 	if !exists {
 		ob.logger.Debug("Order not found", zap.Uint64("orderID", orderID))
-		return fmt.Errorf("order not found: %d", orderID)
+		return ob.rejectOrder(req, orderID, fmt.Errorf("%w: %d", reject.ErrUnknownOrder, orderID))
 	}
 
-	// Remove the order
-	ob.removeOrder(order)
+	// Only the owner may cancel the order
+	if order.CustomerID != customerID {
+		ob.logger.Warn("Cancel by non-owner", zap.Uint64("orderID", orderID), zap.Uint("customerID", customerID))
+		return ob.rejectOrder(req, orderID, fmt.Errorf("%w: %d", reject.ErrNotOwner, orderID))
+	}
 
-	ob.logger.Debug("Order cancelled", zap.Uint64("orderID", orderID))
-	return nil
+	return ob.cancelOrder(order, &model.Command{CustomerID: customerID}, fmt.Sprintf("customer:%d", customerID))
 }
 
-// QueryOrders returns all active orders for a given customer ID.
-func (ob *OrderBook) QueryOrders(customerID uint) []*model.Order {
-	ob.mtx.RLock()
-	defer ob.mtx.RUnlock()
+// AdminCancelOrder cancels any order on behalf of support staff.
+// The operator is recorded as the canceller of the order.
+func (ob *OrderBook) AdminCancelOrder(orderID uint64, operator string) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
 
-	activeOrders := []*model.Order{}
-	currentTime := time.Now()
+	// Validate operator
+	if operator == "" {
+		return fmt.Errorf("operator is required")
+	}
 
-	// Filter and collect only the active orders
-	if customerOrders, ok := ob.CustomerOrders[customerID]; ok {
-		for _, order := range customerOrders {
-			if order.GTT == nil || order.GTT.After(currentTime) {
-				activeOrders = append(activeOrders, order)
-			}
-		}
+	// Check if the order exists in the order book
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		ob.logger.Debug("Order not found", zap.Uint64("orderID", orderID))
+		return ob.rejectOrder(model.OrderRequest{}, orderID, fmt.Errorf("%w: %d", reject.ErrUnknownOrder, orderID))
 	}
 
-	// Return the list of active orders for the customer
-	return activeOrders
+	ob.logger.Info("Admin cancel", zap.Uint64("orderID", orderID), zap.String("operator", operator))
+	return ob.cancelOrder(order, &model.Command{Admin: operator}, "admin:"+operator)
 }
 
-// RemoveExpiredBuyOrders removes expired buy orders from the order book.
-// It locks the order book to ensure thread safety, checks each buy order
-// for expiration, and removes it if expired. Orders that are not expired
-// are temporarily removed and reinserted after the process.
-func (ob *OrderBook) RemoveExpiredBuyOrders() {
-	currentTime := time.Now()
-
-	// Lock the order book to ensure thread safety
+// MassCancel cancels every resting order matching the filter on behalf of
+// support staff and returns the cancelled IDs in ascending order. The orders
+// are selected and removed under a single lock, as one journaled command.
+func (ob *OrderBook) MassCancel(filter model.MassCancelFilter, operator string) ([]uint64, error) {
 	ob.mtx.Lock()
 	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
 
-	// Create a slice to store orders that are not expired
-	skippedOrders := []*model.Order{}
+	// Validate operator
+	if operator == "" {
+		return nil, fmt.Errorf("operator is required")
+	}
 
-	// Iterate over buy orders
-	for ob.BuyOrders.Len() > 0 {
-		// Remove the top order from the heap
-		order := heap.Pop(ob.BuyOrders).(*model.Order)
+	// Validate price range
+	if filter.MaxPrice != 0 && filter.MinPrice > filter.MaxPrice {
+		err := fmt.Errorf("%w: min price %d above max price %d", reject.ErrInvalidPrice, filter.MinPrice, filter.MaxPrice)
+		return nil, ob.rejectOrder(model.OrderRequest{}, 0, err)
+	}
 
-		// Check if the order is expired
-		if order.GTT.Before(currentTime) {
-			// Remove expired order
-			ob.removeOrder(order)
-			continue
+	// Select the matching orders, a customer filter only scans that customer's orders
+	candidates := ob.Orders
+	if filter.CustomerID != nil {
+		candidates = ob.CustomerOrders[*filter.CustomerID]
+	}
+	orders := []*model.Order{}
+	for _, order := range candidates {
+		if filter.Matches(order) {
+			orders = append(orders, order)
 		}
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
 
-		// Add non-expired order to the skippedOrders slice
-		skippedOrders = append(skippedOrders, order)
+	// Nothing to do, keep the journal free of empty commands
+	orderIDs := make([]uint64, 0, len(orders))
+	if len(orders) == 0 {
+		return orderIDs, nil
 	}
 
-	// Reinsert any skipped orders before exiting the function
-	ob.reinsertSkippedOrders(ob.BuyOrders, skippedOrders)
-}
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: timestamp,
+		Action:    constant.MassCancelCommand,
+		Admin:     operator,
+		Filter:    &filter,
+	}); err != nil {
+		return nil, err
+	}
 
-// RemoveExpiredSellOrders removes expired sell orders from the order book.
-// It locks the order book to ensure thread safety, checks each sell order
-// for expiration, and removes it if expired. Orders that are not expired
-// are temporarily removed and reinserted after the process.
-func (ob *OrderBook) RemoveExpiredSellOrders() {
-	currentTime := time.Now()
+	// Remove the orders
+	cancelledBy := "admin:" + operator
+	for _, order := range orders {
+		ob.removeOrder(order)
+		ob.emit(event.OrderCancelled{Header: ob.header(timestamp), Order: *order, CancelledBy: cancelledBy})
+		orderIDs = append(orderIDs, order.ID)
+	}
 
-	// Lock the order book to ensure thread safety
-	ob.mtx.Lock()
-	defer ob.mtx.Unlock()
+	// Reprice the pegged orders following the orders removed
+	ob.runTriggers(timestamp)
 
-	// Create a slice to store orders that are not expired
-	skippedOrders := []*model.Order{}
+	ob.logger.Info("Mass cancel", zap.String("operator", operator), zap.Int("orders", len(orderIDs)))
+	return orderIDs, nil
+}
 
-	// Iterate over sell orders
-	for ob.SellOrders.Len() > 0 {
-		// Remove the top order from the heap
-		order := heap.Pop(ob.SellOrders).(*model.Order)
+// CancelOnDisconnect cancels the orders of a customer whose session dropped,
+// except those placed with KeepOnDisconnect, and returns their IDs in
+// ascending order.
+func (ob *OrderBook) CancelOnDisconnect(customerID uint) ([]uint64, error) {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
 
-		// Check if the order is expired
-		if order.GTT.Before(currentTime) {
-			// Remove expired order
-			ob.removeOrder(order)
-			continue
+	// Select the flagged orders of the customer
+	orders := []*model.Order{}
+	for _, order := range ob.CustomerOrders[customerID] {
+		if order.CancelOnDisconnect {
+			orders = append(orders, order)
 		}
-
-		// Add non-expired order to the skippedOrders slice
-		skippedOrders = append(skippedOrders, order)
 	}
+	sort.Slice(orders, func(i, j int) bool {
+		return orders[i].ID < orders[j].ID
+	})
 
-	// Reinsert any skipped orders before exiting the function
-	ob.reinsertSkippedOrders(ob.SellOrders, skippedOrders)
-}
-
-// matchOrder attempts to match a new order with existing orders
-func (ob *OrderBook) matchOrder(order *model.Order, orderType constant.OrderType) {
-	currentTime := time.Now()
+	// Nothing to do, keep the journal free of empty commands
+	orderIDs := make([]uint64, 0, len(orders))
+	if len(orders) == 0 {
+		return orderIDs, nil
+	}
 
-	// If the order's GTT (Good Til Time) is set and it is before the current time, the order is expired.
-	// Return immediately as expired orders cannot be matched.
-	if order.GTT != nil && order.GTT.Before(currentTime) {
-		ob.logger.Debug("Order expired before matching", zap.Uint64("orderID", order.ID))
-		return
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	if err := ob.journalCommand(&model.Command{
+		Timestamp:  timestamp,
+		Action:     constant.DisconnectCommand,
+		CustomerID: customerID,
+	}); err != nil {
+		return nil, err
 	}
 
-	var targetOrders, oppositeOrders *model.OrderHeap
-	if orderType == constant.BuyOrder {
-		targetOrders = ob.BuyOrders
-		oppositeOrders = ob.SellOrders
-	} else {
-		targetOrders = ob.SellOrders
-		oppositeOrders = ob.BuyOrders
+	// Remove the orders
+	for _, order := range orders {
+		ob.removeOrder(order)
+		ob.emit(event.OrderCancelled{Header: ob.header(timestamp), Order: *order, CancelledBy: "disconnect"})
+		orderIDs = append(orderIDs, order.ID)
 	}
 
-	skippedOrders := []*model.Order{}
+	// Reprice the pegged orders following the orders removed
+	ob.runTriggers(timestamp)
 
-	// Attempt to match the order with existing opposite orders
-	for oppositeOrders.Len() > 0 {
-		// Retrieve the top opposite order
-		oppositeOrder := heap.Pop(oppositeOrders).(*model.Order)
+	ob.logger.Info("Cancelled orders on disconnect", zap.Uint("customerID", customerID), zap.Int("orders", len(orderIDs)))
+	return orderIDs, nil
+}
 
-		// Skip if the opposite order is not present in the Orders map
-		if _, exists := ob.Orders[oppositeOrder.ID]; !exists {
-			continue
-		}
+// cancelOrder journals the cancel command and removes the order.
+func (ob *OrderBook) cancelOrder(order *model.Order, cmd *model.Command, cancelledBy string) error {
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	cmd.Timestamp = timestamp
+	cmd.Action = constant.CancelCommand
+	cmd.OrderID = order.ID
+	if err := ob.journalCommand(cmd); err != nil {
+		return err
+	}
 
-		// Skip if the opposite order belongs to the same customer
-		if oppositeOrder.CustomerID == order.CustomerID {
-			// Temporarily store the order
-			skippedOrders = append(skippedOrders, oppositeOrder)
-			continue
-		}
+	// Remove the order
+	ob.removeOrder(order)
+	ob.emit(event.OrderCancelled{Header: ob.header(timestamp), Order: *order, CancelledBy: cancelledBy})
 
-		// Check if the opposite order is still active based on its GTT (Good Til Time)
-		if oppositeOrder.GTT == nil || oppositeOrder.GTT.After(currentTime) {
-			// Check if the order prices can match
-			if (orderType == constant.BuyOrder && oppositeOrder.Price <= order.Price) ||
-				(orderType == constant.SellOrder && oppositeOrder.Price >= order.Price) {
-				// A match is found, execute the trade
-				ob.logger.Info("MATCHED ORDERS!!!",
-					zap.Uint64("orderID", order.ID),
-					zap.String("orderType", order.OrderType.String()),
-					zap.Uint64("oppositeOrderID", oppositeOrder.ID),
-					zap.Uint("price", oppositeOrder.Price),
-				)
+	// Reprice the pegged orders following the order removed
+	ob.runTriggers(timestamp)
 
-				// Remove the matched opposite order
-				ob.removeOrder(oppositeOrder)
+	ob.logger.Debug("Order cancelled", zap.Uint64("orderID", order.ID), zap.String("cancelledBy", cancelledBy))
+	return nil
+}
 
-				// Reinsert any skipped orders before returning
-				ob.reinsertSkippedOrders(oppositeOrders, skippedOrders)
-				return // Exit after successful match
-			}
-		} else {
-			// Remove expired opposite orders
-			ob.removeOrder(oppositeOrder)
-			continue
-		}
+// AmendOrder replaces the price and GTT of an existing order.
+// The amended order keeps its ID but loses its time priority and is
+// matched again as if it had just been submitted.
+func (ob *OrderBook) AmendOrder(customerID uint, orderID uint64, price model.Price, gtt *time.Time) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
 
-		// No match found, push the opposite order back and exit loop
-		heap.Push(oppositeOrders, oppositeOrder)
-		// Reinsert any skipped orders before exiting the loop
-		ob.reinsertSkippedOrders(oppositeOrders, skippedOrders)
-		break
+	timestamp := ob.clock.Now()
+	req := model.OrderRequest{CustomerID: customerID, Price: price, GTT: gtt}
+
+	// Refuse amends in market phases without order entry
+	if err := ob.checkPhase(ob.Phase.AcceptsOrders()); err != nil {
+		return ob.rejectOrder(req, orderID, err)
 	}
 
-	// No match found, add the order to the list of active target orders
-	heap.Push(targetOrders, order)
-	ob.Orders[order.ID] = order
+	// Validate price
+	if price == 0 {
+		ob.logger.Error("Invalid price", zap.Error(reject.ErrInvalidPrice))
+		return ob.rejectOrder(req, orderID, reject.ErrInvalidPrice)
+	}
+	if err := ob.checkPrice(price); err != nil {
+		return ob.rejectOrder(req, orderID, err)
+	}
 
-	// Add the order to the CustomerOrders map
-	if ob.CustomerOrders[order.CustomerID] == nil {
-		ob.CustomerOrders[order.CustomerID] = make(map[uint64]*model.Order)
+	// Refuse a GTT (Good Til Time) that has already passed
+	if gtt != nil && !gtt.After(timestamp) {
+		ob.logger.Debug("Amended order expired on arrival", zap.Uint64("orderID", orderID))
+		return ob.rejectOrder(req, orderID, reject.ErrExpiredOnArrival)
 	}
-	ob.CustomerOrders[order.CustomerID][order.ID] = order
-}
 
-// removeOrder remove an order from all relevant data structures
-func (ob *OrderBook) removeOrder(order *model.Order) {
-	delete(ob.Orders, order.ID)
-	if customerOrders, ok := ob.CustomerOrders[order.CustomerID]; ok {
-		delete(customerOrders, order.ID)
-		if len(customerOrders) == 0 {
-			delete(ob.CustomerOrders, order.CustomerID)
-		}
+	// Check if the order exists in the order book
+	order, exists := ob.Orders[orderID]
+	if !exists {
+		ob.logger.Debug("Order not found", zap.Uint64("orderID", orderID))
+		return ob.rejectOrder(req, orderID, fmt.Errorf("%w: %d", reject.ErrUnknownOrder, orderID))
 	}
-}
 
-// reinsertSkippedOrders reinsert skipped orders back into the heap
-func (ob *OrderBook) reinsertSkippedOrders(orders *model.OrderHeap, skippedOrders []*model.Order) {
-	for _, skipped := range skippedOrders {
-		heap.Push(orders, skipped)
+	// Only the owner may amend the order
+	if order.CustomerID != customerID {
+		ob.logger.Warn("Amend by non-owner", zap.Uint64("orderID", orderID), zap.Uint("customerID", customerID))
+		return ob.rejectOrder(req, orderID, fmt.Errorf("%w: %d", reject.ErrNotOwner, orderID))
+	}
+
+	// Run pre-trade risk checks as if the order was replaced by a new one
+	req.Quantity = order.Remaining
+	req.OrderType = order.OrderType
+	if _, err := price.Notional(order.Remaining); err != nil {
+		return ob.rejectOrder(req, orderID, fmt.Errorf("%w: %v", reject.ErrNotionalOverflow, err))
+	}
+	if err := ob.checkRisk(req, order); err != nil {
+		return ob.rejectOrder(req, orderID, err)
 	}
+	if err := ob.checkFunds(req, order); err != nil {
+		return ob.rejectOrder(req, orderID, err)
+	}
+
+	// Journal the accepted command before touching the book
+	if err := ob.journalCommand(&model.Command{
+		Timestamp:  timestamp,
+		Action:     constant.AmendCommand,
+		CustomerID: customerID,
+		OrderID:    orderID,
+		Price:      price,
+		GTT:        gtt,
+	}); err != nil {
+		return err
+	}
+
+	// Remove the original order, its heap entry becomes stale
+	ob.removeOrder(order)
+
+	// Create the replacement order, keeping everything but the price, GTT and
+	// time priority. The price of a pegged order is its new limit
+	replacement := *order
+	replacement.Timestamp = timestamp
+	if gtt != nil || !order.HasSessionExpiry() {
+		// Day and good-till-date orders keep their session close unless given a GTT
+		replacement.GTT = gtt
+	}
+	if replacement.IsPegged() {
+		replacement.PegLimit = price
+		bid, ask := ob.limitPrices()
+		replacement.Price = ob.pegPrice(&replacement, bid, ask)
+	} else {
+		replacement.Price = price
+	}
+	amended := &replacement
+	ob.reserve(amended)
+
+	ob.logger.Debug("Order amended", zap.Uint64("orderID", orderID), zap.Uint64("price", uint64(price)))
+	ob.emit(event.OrderAmended{Header: ob.header(timestamp), Order: *amended})
+
+	// Try to match the amended order, then trigger the stops and pegs it moved
+	ob.submitOrder(amended)
+	ob.runTriggers(timestamp)
+	return nil
+}
+
+// SetPhase moves the book to another market phase on behalf of an operator,
+// or "schedule" for the calendar. When matching resumes, the orders collected
+// in the meantime are uncrossed in a call auction.
+func (ob *OrderBook) SetPhase(phase constant.MarketPhase, operator string) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	// Validate operator
+	if operator == "" {
+		return fmt.Errorf("operator is required")
+	}
+
+	// Validate transition
+	previous := ob.Phase
+	if !phase.IsValid() || !previous.CanMoveTo(phase) {
+		err := fmt.Errorf("%w: %s to %q", reject.ErrInvalidPhase, previous, phase)
+		return ob.rejectOrder(model.OrderRequest{}, 0, err)
+	}
+
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: timestamp,
+		Action:    constant.PhaseCommand,
+		Admin:     operator,
+		Phase:     phase,
+	}); err != nil {
+		return err
+	}
+
+	ob.changePhase(phase, operator, "", timestamp)
+	return nil
+}
+
+// Halt stops trading on behalf of an operator. Customers can still cancel
+// their orders, new orders are rejected until the book resumes.
+func (ob *OrderBook) Halt(reason, operator string) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	// Validate operator
+	if operator == "" {
+		return fmt.Errorf("operator is required")
+	}
+
+	// Validate transition
+	if !ob.Phase.CanMoveTo(constant.HaltedPhase) {
+		err := fmt.Errorf("%w: %s to %s", reject.ErrInvalidPhase, ob.Phase, constant.HaltedPhase)
+		return ob.rejectOrder(model.OrderRequest{}, 0, err)
+	}
+
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: timestamp,
+		Action:    constant.HaltCommand,
+		Admin:     operator,
+		Reason:    reason,
+	}); err != nil {
+		return err
+	}
+
+	ob.changePhase(constant.HaltedPhase, operator, reason, timestamp)
+	return nil
+}
+
+// Resume ends a halt on behalf of an operator, or "circuit_breaker" when the
+// breaker's halt is over. The orders left crossed by the halt are uncrossed
+// in a reopening auction before continuous trading resumes.
+func (ob *OrderBook) Resume(operator string) error {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	// Validate operator
+	if operator == "" {
+		return fmt.Errorf("operator is required")
+	}
+
+	// Validate phase
+	if ob.Phase != constant.HaltedPhase {
+		err := fmt.Errorf("%w: %s is not halted", reject.ErrInvalidPhase, ob.Phase)
+		return ob.rejectOrder(model.OrderRequest{}, 0, err)
+	}
+
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: timestamp,
+		Action:    constant.ResumeCommand,
+		Admin:     operator,
+	}); err != nil {
+		return err
+	}
+
+	ob.changePhase(constant.ContinuousPhase, operator, "", timestamp)
+	return nil
+}
+
+// GetHaltedUntil returns when a circuit breaker halt ends, nil if the book
+// is not halted by the breaker.
+func (ob *OrderBook) GetHaltedUntil() *time.Time {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+	return ob.HaltedUntil
+}
+
+// changePhase moves the book to an accepted phase, publishing halts and
+// resumptions. When matching resumes, the orders collected in the meantime
+// are uncrossed in a call auction, and the breaker starts a new window.
+func (ob *OrderBook) changePhase(phase constant.MarketPhase, operator, reason string, timestamp time.Time) {
+	previous := ob.Phase
+	ob.Phase = phase
+	ob.logger.Info("Market phase changed", zap.String("previous", string(previous)), zap.String("phase", string(phase)), zap.String("operator", operator))
+	ob.emit(event.PhaseChanged{Header: ob.header(timestamp), Previous: previous, Phase: phase, Operator: operator})
+
+	switch {
+	case phase == constant.HaltedPhase:
+		ob.emit(event.TradingHalted{Header: ob.header(timestamp), Reason: reason, Operator: operator, Until: ob.HaltedUntil})
+	case previous == constant.HaltedPhase:
+		ob.HaltedUntil = nil
+		ob.emit(event.TradingResumed{Header: ob.header(timestamp), Operator: operator, Phase: phase})
+	}
+
+	if phase.Matches() && !previous.Matches() {
+		ob.runAuction(timestamp)
+		ob.BreakerTrades = nil
+
+		// Constrained orders left behind by the halt are retried too
+		ob.buyGained, ob.sellGained = true, true
+		ob.runTriggers(timestamp)
+	}
+}
+
+// checkBreaker adds a continuous trade to the circuit breaker's window,
+// dropping the trades that left it, and halts the book if the trade trips
+// the breaker.
+func (ob *OrderBook) checkBreaker(trade *model.Trade, timestamp time.Time) {
+	if ob.breaker == nil || !ob.Phase.Matches() {
+		return
+	}
+
+	start := trade.Timestamp.Add(-ob.breaker.Window())
+	kept := ob.BreakerTrades[:0]
+	for _, previous := range ob.BreakerTrades {
+		if previous.Timestamp.After(start) {
+			kept = append(kept, previous)
+		}
+	}
+	ob.BreakerTrades = append(kept, trade)
+	if !ob.breaker.Tripped(ob.BreakerTrades) {
+		return
+	}
+
+	if halt := ob.breaker.HaltDuration(); halt > 0 {
+		until := timestamp.Add(halt)
+		ob.HaltedUntil = &until
+	}
+	reason := fmt.Sprintf("trade price %d moved beyond the circuit breaker limit", ob.LastTradePrice)
+	ob.logger.Warn("Circuit breaker tripped", zap.Uint64("price", uint64(ob.LastTradePrice)))
+	ob.changePhase(constant.HaltedPhase, constant.CircuitBreakerOperator, reason, timestamp)
+}
+
+// Uncross runs a call auction on behalf of an operator, or "schedule" for a
+// book trading in periodic auctions, and returns its outcome. The book must
+// be collecting orders for an auction.
+func (ob *OrderBook) Uncross(operator string) (model.Auction, error) {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	// Validate operator
+	if operator == "" {
+		return model.Auction{}, fmt.Errorf("operator is required")
+	}
+
+	// Validate phase
+	if err := ob.checkPhase(ob.Phase.IsCall()); err != nil {
+		return model.Auction{}, ob.rejectOrder(model.OrderRequest{}, 0, err)
+	}
+
+	// Journal the accepted command before touching the book
+	timestamp := ob.clock.Now()
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: timestamp,
+		Action:    constant.UncrossCommand,
+		Admin:     operator,
+	}); err != nil {
+		return model.Auction{}, err
+	}
+
+	result := ob.runAuction(timestamp)
+	ob.runTriggers(timestamp)
+	return result, nil
+}
+
+// IndicativeAuction returns the price and volume a call auction would execute now.
+func (ob *OrderBook) IndicativeAuction() model.Auction {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+
+	bids, asks := ob.auctionOrders(ob.clock.Now())
+	return auction.ClearingPrice(bids, asks, ob.LastTradePrice)
+}
+
+// GetPhase returns the current market phase.
+func (ob *OrderBook) GetPhase() constant.MarketPhase {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+	return ob.Phase
+}
+
+// GetCurrency returns the currency of the book's prices.
+func (ob *OrderBook) GetCurrency() model.Currency {
+	return ob.currency
+}
+
+// QueryOrders returns all active orders for a given customer ID.
+func (ob *OrderBook) QueryOrders(customerID uint) []*model.Order {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+
+	activeOrders := []*model.Order{}
+	currentTime := ob.clock.Now()
+
+	// Filter and collect only the active orders
+	if customerOrders, ok := ob.CustomerOrders[customerID]; ok {
+		for _, order := range customerOrders {
+			if order.GTT == nil || order.GTT.After(currentTime) {
+				activeOrders = append(activeOrders, order)
+			}
+		}
+	}
+
+	// Return the list of active orders for the customer
+	return activeOrders
+}
+
+// Depth returns the displayed quantity of up to levels prices per side, all
+// prices if levels is 0. Iceberg orders count with their displayed slice only.
+func (ob *OrderBook) Depth(levels int) model.Depth {
+	ob.mtx.RLock()
+	defer ob.mtx.RUnlock()
+
+	currentTime := ob.clock.Now()
+	bids := make(map[model.Price]*model.PriceLevel)
+	asks := make(map[model.Price]*model.PriceLevel)
+	for _, order := range ob.Orders {
+		// Skip orders outside the book and expired orders not swept yet
+		if order.IsPendingStop() || (order.GTT != nil && !order.GTT.After(currentTime)) {
+			continue
+		}
+
+		side := asks
+		if order.OrderType == constant.BuyOrder {
+			side = bids
+		}
+		level, ok := side[order.Price]
+		if !ok {
+			level = &model.PriceLevel{Price: order.Price}
+			side[order.Price] = level
+		}
+		level.Quantity += order.Displayed()
+		level.Orders++
+	}
+
+	return model.Depth{
+		Currency: ob.currency,
+		Bids:     sortLevels(bids, levels, func(a, b model.Price) bool { return a > b }),
+		Asks:     sortLevels(asks, levels, func(a, b model.Price) bool { return a < b }),
+	}
+}
+
+// sortLevels returns up to limit price levels, best first, all if limit is 0.
+func sortLevels(levels map[model.Price]*model.PriceLevel, limit int, better func(a, b model.Price) bool) []model.PriceLevel {
+	sorted := make([]model.PriceLevel, 0, len(levels))
+	for _, level := range levels {
+		sorted = append(sorted, *level)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return better(sorted[i].Price, sorted[j].Price)
+	})
+	if limit > 0 && len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+// RemoveExpiredBuyOrders removes expired buy orders from the order book.
+// It locks the order book to ensure thread safety, checks each buy order
+// for expiration, and removes it if expired. Orders that are not expired
+// are temporarily removed and reinserted after the process.
+func (ob *OrderBook) RemoveExpiredBuyOrders() {
+	currentTime := ob.clock.Now()
+
+	// Lock the order book to ensure thread safety
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	// Nothing to do, keep the journal free of empty sweeps
+	if !ob.hasExpiredOrders(constant.BuyOrder, currentTime) {
+		return
+	}
+
+	// Journal the sweep so recovery expires the same orders
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: currentTime,
+		Action:    constant.ExpireCommand,
+		OrderType: constant.BuyOrder,
+	}); err != nil {
+		return
+	}
+
+	// Create a slice to store orders that are not expired
+	skippedOrders := []*model.Order{}
+
+	// Iterate over buy orders
+	for ob.BuyOrders.Len() > 0 {
+		// Remove the top order from the heap
+		order := heap.Pop(ob.BuyOrders).(*model.Order)
+
+		// Drop stale entries of cancelled or amended orders
+		if !ob.isActive(order) {
+			continue
+		}
+
+		// Check if the order is expired
+		if order.GTT != nil && order.GTT.Before(currentTime) {
+			// Remove expired order
+			ob.expireOrder(order, currentTime)
+			continue
+		}
+
+		// Add non-expired order to the skippedOrders slice
+		skippedOrders = append(skippedOrders, order)
+	}
+
+	// Reinsert any skipped orders before exiting the function
+	ob.reinsertSkippedOrders(ob.BuyOrders, skippedOrders)
+
+	// Expire buy stop orders still waiting for their trigger
+	ob.expireStops(ob.BuyStops, currentTime)
+
+	// Reprice the pegged orders following the orders removed
+	ob.runTriggers(currentTime)
+}
+
+// RemoveExpiredSellOrders removes expired sell orders from the order book.
+// It locks the order book to ensure thread safety, checks each sell order
+// for expiration, and removes it if expired. Orders that are not expired
+// are temporarily removed and reinserted after the process.
+func (ob *OrderBook) RemoveExpiredSellOrders() {
+	currentTime := ob.clock.Now()
+
+	// Lock the order book to ensure thread safety
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+	defer ob.publishEvents()
+
+	// Nothing to do, keep the journal free of empty sweeps
+	if !ob.hasExpiredOrders(constant.SellOrder, currentTime) {
+		return
+	}
+
+	// Journal the sweep so recovery expires the same orders
+	if err := ob.journalCommand(&model.Command{
+		Timestamp: currentTime,
+		Action:    constant.ExpireCommand,
+		OrderType: constant.SellOrder,
+	}); err != nil {
+		return
+	}
+
+	// Create a slice to store orders that are not expired
+	skippedOrders := []*model.Order{}
+
+	// Iterate over sell orders
+	for ob.SellOrders.Len() > 0 {
+		// Remove the top order from the heap
+		order := heap.Pop(ob.SellOrders).(*model.Order)
+
+		// Drop stale entries of cancelled or amended orders
+		if !ob.isActive(order) {
+			continue
+		}
+
+		// Check if the order is expired
+		if order.GTT != nil && order.GTT.Before(currentTime) {
+			// Remove expired order
+			ob.expireOrder(order, currentTime)
+			continue
+		}
+
+		// Add non-expired order to the skippedOrders slice
+		skippedOrders = append(skippedOrders, order)
+	}
+
+	// Reinsert any skipped orders before exiting the function
+	ob.reinsertSkippedOrders(ob.SellOrders, skippedOrders)
+
+	// Expire sell stop orders still waiting for their trigger
+	ob.expireStops(ob.SellStops, currentTime)
+
+	// Reprice the pegged orders following the orders removed
+	ob.runTriggers(currentTime)
+}
+
+// matchOrder attempts to match a new order with existing orders
+// until it is filled, then rests any remaining quantity.
+func (ob *OrderBook) matchOrder(order *model.Order, currentTime time.Time) {
+	oppositeOrders := ob.oppositeOrders(order)
+
+	// Orders only rest outside continuous trading, and an order whose fill
+	// constraint cannot be satisfied now does not trade at all
+	matchable := ob.Phase.Matches() &&
+		(!order.IsConstrained() || ob.fillableQuantity(order, currentTime) >= order.MinFill())
+
+	skippedOrders := []*model.Order{}
+
+	// Attempt to match the order with existing opposite orders one price
+	// level at a time, until the circuit breaker halts the book
+	for matchable && ob.Phase.Matches() && order.Remaining > 0 {
+		level := ob.popLevel(oppositeOrders, order, currentTime, &skippedOrders)
+		if len(level) == 0 {
+			break
+		}
+
+		// Share the order between the level with the book's allocation,
+		// skipping the opposite orders whose fill constraint their share
+		// cannot satisfy, the others can still trade
+		makers, shares, dropped := ob.allocateLevel(order.Remaining, level)
+		skippedOrders = append(skippedOrders, dropped...)
+
+		for i, oppositeOrder := range makers {
+			if shares[i] > 0 && ob.Phase.Matches() {
+				// A match is found, execute the trade
+				ob.logger.Info("MATCHED ORDERS!!!",
+					zap.Uint64("orderID", order.ID),
+					zap.String("orderType", order.OrderType.String()),
+					zap.Uint64("oppositeOrderID", oppositeOrder.ID),
+					zap.Uint64("price", uint64(oppositeOrder.Price)),
+				)
+				ob.fillOrders(order, oppositeOrder, shares[i], currentTime)
+			}
+
+			// Keep a partially filled opposite order in its place. An
+			// iceberg shows its next slice with a fresh time priority instead
+			if oppositeOrder.Remaining > 0 {
+				if oppositeOrder.IsIceberg() && oppositeOrder.Visible == 0 {
+					oppositeOrder.Replenish()
+					oppositeOrder.Timestamp = currentTime
+				}
+				heap.Push(oppositeOrders, oppositeOrder)
+			} else {
+				ob.removeOrder(oppositeOrder)
+			}
+		}
+	}
+
+	// Reinsert any skipped orders
+	ob.reinsertSkippedOrders(oppositeOrders, skippedOrders)
+
+	// Add the remaining quantity to the list of active target orders,
+	// market orders never rest and cancel what they could not fill
+	switch {
+	case order.Remaining > 0 && order.IsMarket():
+		ob.removeOrder(order)
+		ob.emit(event.OrderCancelled{Header: ob.header(currentTime), Order: *order, CancelledBy: "unfilled"})
+	case order.Remaining > 0:
+		// An iceberg rests showing a full slice whatever it traded on arrival
+		if order.IsIceberg() {
+			order.Replenish()
+		}
+		ob.addOrder(order)
+	case ob.isActive(order):
+		// A filled stop order leaves the lookup maps it was parked in
+		ob.removeOrder(order)
+	}
+}
+
+// fillableQuantity returns how much of the order a match would fill now. It
+// runs the match on copies of the crossing opposite orders, making the same
+// choices as matchOrder without touching the book.
+func (ob *OrderBook) fillableQuantity(order *model.Order, currentTime time.Time) uint {
+	opposite := ob.oppositeOrders(order)
+	shadow := &model.OrderHeap{Type: opposite.Type}
+	for _, maker := range opposite.Orders {
+		if !ob.isActive(maker) || maker.CustomerID == order.CustomerID || !crosses(order, maker) ||
+			(maker.GTT != nil && !maker.GTT.After(currentTime)) {
+			continue
+		}
+		copied := *maker
+		shadow.Orders = append(shadow.Orders, &copied)
+	}
+	heap.Init(shadow)
+
+	taker := *order
+	for taker.Remaining > 0 && shadow.Len() > 0 {
+		level := []*model.Order{heap.Pop(shadow).(*model.Order)}
+		for shadow.Len() > 0 && shadow.Orders[0].Price == level[0].Price {
+			level = append(level, heap.Pop(shadow).(*model.Order))
+		}
+
+		makers, shares, _ := ob.allocateLevel(taker.Remaining, level)
+		for i, maker := range makers {
+			taker.Fill(shares[i])
+			maker.Fill(shares[i])
+			if maker.Remaining > 0 {
+				if maker.IsIceberg() && maker.Visible == 0 {
+					maker.Replenish()
+					maker.Timestamp = currentTime
+				}
+				heap.Push(shadow, maker)
+			}
+		}
+	}
+	return order.Remaining - taker.Remaining
+}
+
+// popLevel pops the active orders at the best price of a side if it crosses
+// the taker, oldest first. Expired orders met on the way are expired, and the
+// orders of the taker's customer are set aside in skipped.
+func (ob *OrderBook) popLevel(orders *model.OrderHeap, taker *model.Order, currentTime time.Time, skipped *[]*model.Order) []*model.Order {
+	level := []*model.Order{}
+	for orders.Len() > 0 {
+		top := orders.Orders[0]
+
+		// Drop entries of cancelled, amended or already matched orders
+		if !ob.isActive(top) {
+			heap.Pop(orders)
+			continue
+		}
+		if len(level) > 0 && top.Price != level[0].Price {
+			break
+		}
+		heap.Pop(orders)
+
+		// Skip if the opposite order belongs to the same customer
+		if top.CustomerID == taker.CustomerID {
+			*skipped = append(*skipped, top)
+			continue
+		}
+
+		// Remove expired opposite orders based on their GTT (Good Til Time)
+		if top.GTT != nil && !top.GTT.After(currentTime) {
+			ob.expireOrder(top, currentTime)
+			continue
+		}
+
+		// Check if the order prices can match, market orders match any price
+		if len(level) == 0 && !crosses(taker, top) {
+			heap.Push(orders, top)
+			break
+		}
+		level = append(level, top)
+	}
+	return level
+}
+
+// allocateLevel shares quantity between the orders of a price level with the
+// book's allocation. Orders whose fill constraint their share cannot satisfy
+// are dropped, and the quantity is shared again between the others.
+func (ob *OrderBook) allocateLevel(quantity uint, level []*model.Order) (makers []*model.Order, shares []uint, dropped []*model.Order) {
+	makers = level
+	for {
+		shares = ob.allocator.Allocate(quantity, makers)
+		kept := make([]*model.Order, 0, len(makers))
+		for i, maker := range makers {
+			if shares[i] > 0 && shares[i] < maker.MinFill() {
+				dropped = append(dropped, maker)
+				continue
+			}
+			kept = append(kept, maker)
+		}
+		if len(kept) == len(makers) {
+			return makers, shares, dropped
+		}
+		makers = kept
+	}
+}
+
+// retryConstrained matches the resting orders with a fill constraint that the
+// opposite side can now satisfy, oldest first, and reports whether any traded.
+// They keep their time priority for what they leave resting. Only the orders
+// whose opposite side gained orders since they were last retried are tried.
+func (ob *OrderBook) retryConstrained(timestamp time.Time) bool {
+	if !ob.Phase.Matches() || (!ob.buyGained && !ob.sellGained) {
+		return false
+	}
+	retryBuys, retrySells := ob.sellGained, ob.buyGained
+	ob.buyGained, ob.sellGained = false, false
+
+	constrained := []*model.Order{}
+	for _, order := range ob.constrained {
+		if order.OrderType == constant.BuyOrder && !retryBuys || order.OrderType == constant.SellOrder && !retrySells {
+			continue
+		}
+		if order.GTT == nil || order.GTT.After(timestamp) {
+			constrained = append(constrained, order)
+		}
+	}
+	sort.Slice(constrained, func(i, j int) bool {
+		return constrained[i].ID < constrained[j].ID
+	})
+
+	traded := false
+	for _, order := range constrained {
+		// Skip orders filled by an earlier retried order and orders nothing crosses
+		if !ob.isActive(order) {
+			continue
+		}
+		if best := ob.bestOrder(ob.oppositeOrders(order)); best == nil || !crosses(order, best) {
+			continue
+		}
+		if ob.fillableQuantity(order, timestamp) < order.MinFill() {
+			continue
+		}
+
+		// Match a copy of the order, its heap entry becomes stale
+		ob.removeOrder(order)
+		retried := *order
+		ob.reserve(&retried)
+		ob.matchOrder(&retried, timestamp)
+		traded = true
+	}
+	return traded
+}
+
+// runAuction uncrosses the resting orders at the single price executing the
+// most quantity, with the last trade price as reference. The later order of
+// each trade is its taker.
+func (ob *OrderBook) runAuction(timestamp time.Time) model.Auction {
+	bids, asks := ob.auctionOrders(timestamp)
+	result := auction.ClearingPrice(bids, asks, ob.LastTradePrice)
+	if result.Volume == 0 {
+		return result
+	}
+
+	// Report the volume that executes: fills between orders of the same
+	// customer are skipped and their quantity stays on both sides
+	fills := auction.Allocate(bids, asks, result)
+	var executed uint
+	for _, fill := range fills {
+		executed += fill.Quantity
+	}
+	result.BidSurplus += result.Volume - executed
+	result.AskSurplus += result.Volume - executed
+	result.Volume = executed
+	if executed == 0 {
+		return result
+	}
+
+	ob.logger.Info("Auction uncrossed", zap.Uint64("price", uint64(result.Price)), zap.Uint("volume", result.Volume))
+	ob.emit(event.AuctionUncrossed{Header: ob.header(timestamp), Auction: result})
+	for _, fill := range fills {
+		taker, maker := fill.Bid, fill.Ask
+		if maker.Timestamp.After(taker.Timestamp) || (maker.Timestamp.Equal(taker.Timestamp) && maker.ID > taker.ID) {
+			taker, maker = maker, taker
+		}
+		ob.executeTrade(taker, maker, fill.Quantity, result.Price, timestamp)
+	}
+
+	// Drop the filled orders and show the next slice of the icebergs, then
+	// restore the heap order their new timestamps changed
+	for _, order := range append(bids, asks...) {
+		switch {
+		case order.Remaining == 0:
+			ob.removeOrder(order)
+		case order.IsIceberg() && order.Visible == 0:
+			order.Replenish()
+			order.Timestamp = timestamp
+		}
+	}
+	heap.Init(ob.BuyOrders)
+	heap.Init(ob.SellOrders)
+	return result
+}
+
+// auctionOrders returns the resting bids and asks taking part in an auction.
+// Orders with a fill constraint wait for continuous trading.
+func (ob *OrderBook) auctionOrders(timestamp time.Time) (bids, asks []*model.Order) {
+	for _, order := range ob.Orders {
+		if order.IsPendingStop() || order.IsConstrained() || (order.GTT != nil && !order.GTT.After(timestamp)) {
+			continue
+		}
+		if order.OrderType == constant.BuyOrder {
+			bids = append(bids, order)
+		} else {
+			asks = append(asks, order)
+		}
+	}
+	return bids, asks
+}
+
+// oppositeOrders returns the side an order trades against.
+func (ob *OrderBook) oppositeOrders(order *model.Order) *model.OrderHeap {
+	if order.OrderType == constant.BuyOrder {
+		return ob.SellOrders
+	}
+	return ob.BuyOrders
+}
+
+// crosses reports whether an order can trade at the price of an opposite
+// order. Market orders cross any price.
+func crosses(order, opposite *model.Order) bool {
+	switch {
+	case order.IsMarket():
+		return true
+	case order.OrderType == constant.BuyOrder:
+		return opposite.Price <= order.Price
+	default:
+		return opposite.Price >= order.Price
+	}
+}
+
+// submitOrder matches a new or amended order. A stop order is parked in the
+// trigger book instead, unless the book matches and the last trade price
+// already reached its stop.
+func (ob *OrderBook) submitOrder(order *model.Order) {
+	if !order.IsPendingStop() {
+		ob.matchOrder(order, order.Timestamp)
+		return
+	}
+	if ob.Phase.Matches() && ob.stopReached(order) {
+		ob.triggerStop(order, order.Timestamp)
+		return
+	}
+	ob.addOrder(order)
+}
+
+// triggerStops injects every stop order reached by the last trade price,
+// oldest first, until the trades of the triggered orders trigger no more.
+// Stops reached by the trade that halted the book wait for the resumption.
+func (ob *OrderBook) triggerStops(timestamp time.Time) {
+	// Stops wait for continuous trading: a stop market order triggered in a
+	// call phase could not trade and would be cancelled on arrival
+	if !ob.Phase.Matches() {
+		return
+	}
+	for {
+		var next *model.Order
+		var stops *model.OrderHeap
+		if buy := ob.bestOrder(ob.BuyStops); buy != nil && ob.stopReached(buy) {
+			next, stops = buy, ob.BuyStops
+		}
+		if sell := ob.bestOrder(ob.SellStops); sell != nil && ob.stopReached(sell) && (next == nil || sell.ID < next.ID) {
+			next, stops = sell, ob.SellStops
+		}
+		if next == nil {
+			return
+		}
+
+		heap.Pop(stops)
+		ob.triggerStop(next, timestamp)
+	}
+}
+
+// runTriggers triggers the stops reached by the last trade price, reprices
+// the pegged orders and retries the orders with a fill constraint, until
+// none of them changes the book any more.
+func (ob *OrderBook) runTriggers(timestamp time.Time) {
+	for {
+		ob.triggerStops(timestamp)
+		repriced := ob.repricePegs(timestamp)
+		retried := ob.retryConstrained(timestamp)
+		if !repriced && !retried {
+			return
+		}
+	}
+}
+
+// repricePegs moves every pegged order whose reference price changed, oldest
+// first, and reports whether any moved. A moved order loses its time
+// priority like an amended order and is matched at its new price. Nothing
+// moves while the best limit prices stay where the pegs were last priced.
+func (ob *OrderBook) repricePegs(timestamp time.Time) bool {
+	if len(ob.pegged) == 0 {
+		return false
+	}
+	bid, ask := ob.limitPrices()
+	if bid == ob.pegBid && ask == ob.pegAsk {
+		return false
+	}
+	ob.pegBid, ob.pegAsk = bid, ask
+
+	pegged := make([]*model.Order, 0, len(ob.pegged))
+	for _, order := range ob.pegged {
+		pegged = append(pegged, order)
+	}
+	sort.Slice(pegged, func(i, j int) bool {
+		return pegged[i].ID < pegged[j].ID
+	})
+
+	moved := false
+	for _, order := range pegged {
+		// Skip orders filled by an earlier repriced order and expired orders not swept yet
+		if !ob.isActive(order) || (order.GTT != nil && !order.GTT.After(timestamp)) {
+			continue
+		}
+		price := ob.pegPrice(order, bid, ask)
+		if price == order.Price {
+			continue
+		}
+
+		// Cancel an order its new price takes past the checks it was accepted with
+		if err := ob.checkRepriced(order, price); err != nil {
+			ob.logger.Warn("Repriced order refused", zap.Uint64("orderID", order.ID), zap.Error(err))
+			ob.removeOrder(order)
+			ob.emit(event.OrderCancelled{Header: ob.header(timestamp), Order: *order, CancelledBy: "repriced"})
+			moved = true
+			continue
+		}
+
+		// Replace the order, its heap entry becomes stale
+		ob.removeOrder(order)
+		replacement := *order
+		replacement.Price = price
+		replacement.Timestamp = timestamp
+		repriced := &replacement
+		ob.reserve(repriced)
+
+		ob.logger.Debug("Order repriced", zap.Uint64("orderID", order.ID), zap.Uint64("price", uint64(price)))
+		ob.emit(event.OrderRepriced{Header: ob.header(timestamp), Order: *repriced})
+		ob.matchOrder(repriced, timestamp)
+		moved = true
+
+		// Later pegs follow the limit orders the moved one traded with
+		bid, ask = ob.limitPrices()
+	}
+
+	// Some pegs may have been priced before the last trades, check them all again
+	if moved {
+		ob.pegBid, ob.pegAsk = 0, 0
+	}
+	return moved
+}
+
+// checkRepriced runs the checks of an accepted order against a pegged order
+// moving to a new price: the price must follow the trading rules, its
+// notional must fit in 64 bits and its customer's exposure must stay within
+// the risk limits.
+func (ob *OrderBook) checkRepriced(order *model.Order, price model.Price) error {
+	if err := ob.checkPrice(price); err != nil {
+		return err
+	}
+	if _, err := max(price, order.PegLimit).Notional(order.Remaining); err != nil {
+		return fmt.Errorf("%w: %v", reject.ErrNotionalOverflow, err)
+	}
+	return ob.checkRisk(model.OrderRequest{
+		CustomerID: order.CustomerID,
+		Price:      price,
+		Quantity:   order.Remaining,
+		OrderType:  order.OrderType,
+	}, order)
+}
+
+// pegPrice returns the price a pegged order follows the book to: its
+// reference price moved by the offset, rounded to the tick away from the
+// opposite side and capped at its limit. Without a reference price, e.g.
+// when a side is empty, the order keeps its price.
+func (ob *OrderBook) pegPrice(order *model.Order, bid, ask model.Price) model.Price {
+	price := order.Price
+	if reference := pegReference(order.Peg, order.OrderType, bid, ask); reference != 0 {
+		price = reference.Offset(order.PegOffset)
+		if ob.rules != nil {
+			price = ob.rules.RoundToTick(price, order.OrderType == constant.SellOrder)
+		}
+	}
+	if order.PegLimit == 0 {
+		return price
+	}
+	if order.OrderType == constant.BuyOrder {
+		return min(price, order.PegLimit)
+	}
+	return max(price, order.PegLimit)
+}
+
+// pegReference returns the reference price of a peg given the best limit
+// prices, 0 if there is none.
+func pegReference(peg constant.PegType, orderType constant.OrderType, bid, ask model.Price) model.Price {
+	own, opposite := bid, ask
+	if orderType == constant.SellOrder {
+		own, opposite = ask, bid
+	}
+
+	switch peg {
+	case constant.PrimaryPeg:
+		return own
+	case constant.MarketPeg:
+		return opposite
+	case constant.MidpointPeg:
+		if bid == 0 || ask == 0 {
+			return 0
+		}
+		// Round a half tick away from the opposite side
+		if orderType == constant.BuyOrder {
+			return bid + (ask-bid)/2
+		}
+		return ask - (ask-bid)/2
+	}
+	return 0
+}
+
+// limitPrices returns the best bid and ask the pegged orders follow. Pegged
+// orders never set them, so they cannot chase each other.
+func (ob *OrderBook) limitPrices() (bid, ask model.Price) {
+	return ob.bestLimitPrice(ob.BuyOrders), ob.bestLimitPrice(ob.SellOrders)
+}
+
+// bestLimitPrice returns the best price among the active orders of a side
+// that are not pegged, 0 if there are none. The pegged and expired orders
+// ahead of it are popped and pushed back, stale entries are dropped.
+func (ob *OrderBook) bestLimitPrice(orders *model.OrderHeap) model.Price {
+	currentTime := ob.clock.Now()
+	var best model.Price
+	skipped := []*model.Order{}
+	for orders.Len() > 0 {
+		top := orders.Orders[0]
+		if !ob.isActive(top) {
+			heap.Pop(orders)
+			continue
+		}
+		if top.IsPegged() || (top.GTT != nil && !top.GTT.After(currentTime)) {
+			skipped = append(skipped, heap.Pop(orders).(*model.Order))
+			continue
+		}
+		best = top.Price
+		break
+	}
+	ob.reinsertSkippedOrders(orders, skipped)
+	return best
+}
+
+// sessionExpiry returns the GTT of an order: the close of the current session
+// for a day order, the session close on its expire date for a good-till-date
+// order. A given GTT is kept, so journaled orders replay with the GTT they
+// were accepted with.
+func (ob *OrderBook) sessionExpiry(req model.OrderRequest, timestamp time.Time) (*time.Time, error) {
+	switch req.TimeInForce {
+	case "", constant.GoodTillCancel:
+		return req.GTT, nil
+	case constant.GoodForDay, constant.GoodTillDate:
+	default:
+		return nil, fmt.Errorf("%w: unknown time in force %q", reject.ErrInvalidExpiry, req.TimeInForce)
+	}
+	if req.GTT != nil {
+		return req.GTT, nil
+	}
+	if ob.calendar == nil {
+		return nil, fmt.Errorf("%w: %s orders need a trading calendar", reject.ErrInvalidExpiry, req.TimeInForce)
+	}
+
+	if req.TimeInForce == constant.GoodForDay {
+		gtt := ob.calendar.SessionClose(timestamp)
+		return &gtt, nil
+	}
+	gtt, err := ob.calendar.CloseOn(req.ExpireDate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: expire date %q: %v", reject.ErrInvalidExpiry, req.ExpireDate, err)
+	}
+	return &gtt, nil
+}
+
+// checkPeg validates the peg of a new order and returns its starting price.
+func (ob *OrderBook) checkPeg(req model.OrderRequest) (model.Price, error) {
+	switch req.Peg {
+	case constant.PrimaryPeg, constant.MarketPeg, constant.MidpointPeg:
+	default:
+		return 0, fmt.Errorf("%w: unknown peg %q", reject.ErrInvalidPrice, req.Peg)
+	}
+	if req.StopPrice != 0 {
+		return 0, fmt.Errorf("%w: a pegged order cannot be a stop order", reject.ErrInvalidPrice)
+	}
+	bid, ask := ob.limitPrices()
+	if pegReference(req.Peg, req.OrderType, bid, ask) == 0 {
+		return 0, fmt.Errorf("%w: no reference price for a %s peg", reject.ErrInvalidPrice, req.Peg)
+	}
+	return ob.pegPrice(&model.Order{OrderType: req.OrderType, Peg: req.Peg, PegOffset: req.PegOffset, PegLimit: req.Price}, bid, ask), nil
+}
+
+// stopReached reports whether the last trade price reached the stop price:
+// at or above it for a buy stop, at or below it for a sell stop.
+func (ob *OrderBook) stopReached(order *model.Order) bool {
+	if ob.LastTradePrice == 0 {
+		return false
+	}
+	if order.OrderType == constant.BuyOrder {
+		return ob.LastTradePrice >= order.StopPrice
+	}
+	return ob.LastTradePrice <= order.StopPrice
+}
+
+// triggerStop turns a stop order into a market or limit order with the time
+// priority of the trigger and matches it.
+func (ob *OrderBook) triggerStop(order *model.Order, timestamp time.Time) {
+	order.Triggered = true
+	order.Timestamp = timestamp
+	ob.logger.Debug("Stop triggered", zap.Uint64("orderID", order.ID), zap.Uint64("stopPrice", uint64(order.StopPrice)))
+	ob.emit(event.StopTriggered{Header: ob.header(timestamp), Order: *order})
+	ob.matchOrder(order, timestamp)
+}
+
+// expireStops removes the stop orders of a trigger book whose GTT has passed.
+func (ob *OrderBook) expireStops(stops *model.OrderHeap, currentTime time.Time) {
+	live := stops.Orders[:0]
+	for _, order := range stops.Orders {
+		if !ob.isActive(order) {
+			continue
+		}
+		if order.GTT != nil && order.GTT.Before(currentTime) {
+			ob.expireOrder(order, currentTime)
+			continue
+		}
+		live = append(live, order)
+	}
+	stops.Orders = live
+	heap.Init(stops)
+}
+
+// fillOrders executes a trade between the incoming taker order and a resting
+// maker order for the quantity allocated to the maker, at the maker's price.
+func (ob *OrderBook) fillOrders(taker, maker *model.Order, quantity uint, timestamp time.Time) {
+	trade := ob.executeTrade(taker, maker, quantity, maker.Price, timestamp)
+	ob.checkBreaker(trade, timestamp)
+}
+
+// executeTrade fills the taker and maker orders with a trade at the given
+// price, settles it and publishes it.
+func (ob *OrderBook) executeTrade(taker, maker *model.Order, quantity uint, price model.Price, timestamp time.Time) *model.Trade {
+	taker.Fill(quantity)
+	maker.Fill(quantity)
+	ob.bookChanged = true
+
+	trade := ob.recordTrade(taker, maker, quantity, price, timestamp)
+	if taker.OrderType == constant.BuyOrder {
+		ob.settleTrade(trade, taker, maker)
+	} else {
+		ob.settleTrade(trade, maker, taker)
+	}
+	ob.emit(event.TradeExecuted{Header: ob.header(timestamp), Trade: *trade})
+	ob.emitFill(maker, quantity, timestamp)
+	ob.emitFill(taker, quantity, timestamp)
+	return trade
+}
+
+// emitFill publishes the fill event matching the order's remaining quantity.
+func (ob *OrderBook) emitFill(order *model.Order, quantity uint, timestamp time.Time) {
+	if order.Remaining == 0 {
+		ob.emit(event.OrderFilled{Header: ob.header(timestamp), Order: *order})
+		return
+	}
+	ob.emit(event.OrderPartiallyFilled{Header: ob.header(timestamp), Order: *order, FillQuantity: quantity})
+}
+
+// expireOrder removes an order whose GTT has passed.
+func (ob *OrderBook) expireOrder(order *model.Order, timestamp time.Time) {
+	ob.removeOrder(order)
+	ob.emit(event.OrderExpired{Header: ob.header(timestamp), Order: *order})
+}
+
+// addOrder adds a resting order to its heap, or a stop order to its trigger
+// book, and to the lookup maps.
+func (ob *OrderBook) addOrder(order *model.Order) {
+	switch {
+	case order.IsPendingStop() && order.OrderType == constant.BuyOrder:
+		heap.Push(ob.BuyStops, order)
+	case order.IsPendingStop():
+		heap.Push(ob.SellStops, order)
+	case order.OrderType == constant.BuyOrder:
+		ob.bookChanged = true
+		ob.buyGained = true
+		heap.Push(ob.BuyOrders, order)
+	default:
+		ob.bookChanged = true
+		ob.sellGained = true
+		heap.Push(ob.SellOrders, order)
+	}
+	ob.Orders[order.ID] = order
+	if order.IsPegged() {
+		ob.pegged[order.ID] = order
+	}
+	if order.IsConstrained() && !order.IsPendingStop() {
+		ob.constrained[order.ID] = order
+	}
+
+	// Add the order to the CustomerOrders map
+	if ob.CustomerOrders[order.CustomerID] == nil {
+		ob.CustomerOrders[order.CustomerID] = make(map[uint64]*model.Order)
+	}
+	ob.CustomerOrders[order.CustomerID][order.ID] = order
+}
+
+// hasExpiredOrders reports whether any active order of the given type has expired.
+func (ob *OrderBook) hasExpiredOrders(orderType constant.OrderType, currentTime time.Time) bool {
+	for _, order := range ob.Orders {
+		if order.OrderType == orderType && order.GTT != nil && order.GTT.Before(currentTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// journalCommand durably records cmd before the book is mutated.
+// Without a journal it is a no-op.
+func (ob *OrderBook) journalCommand(cmd *model.Command) error {
+	if ob.journal == nil {
+		return nil
+	}
+	if err := ob.journal.Append(cmd); err != nil {
+		ob.logger.Error("Failed to journal command", zap.String("action", string(cmd.Action)), zap.Error(err))
+		return err
+	}
+	ob.LastSeq = cmd.Seq
+	return nil
+}
+
+// recordTrade appends a trade between the incoming taker order and the resting maker order.
+func (ob *OrderBook) recordTrade(taker, maker *model.Order, quantity uint, price model.Price, timestamp time.Time) *model.Trade {
+	trade := &model.Trade{
+		ID:              ob.NextTradeID,
+		TakerOrderID:    taker.ID,
+		MakerOrderID:    maker.ID,
+		TakerCustomerID: taker.CustomerID,
+		MakerCustomerID: maker.CustomerID,
+		TakerSide:       taker.OrderType,
+		Price:           price,
+		Quantity:        quantity,
+		Timestamp:       timestamp,
+	}
+	if ob.fees != nil {
+		trade.MakerFee, trade.TakerFee = ob.fees.Fees(*trade)
+	}
+	ob.NextTradeID++
+	ob.Trades = append(ob.Trades, trade)
+	if ob.tradeHistory > 0 && len(ob.Trades) >= 2*ob.tradeHistory {
+		// Copy the kept trades, slices handed out by GetTrades stay valid
+		ob.Trades = append([]*model.Trade(nil), ob.Trades[len(ob.Trades)-ob.tradeHistory:]...)
+	}
+	ob.LastTradePrice = trade.Price
+	return trade
+}
+
+// isActive reports whether a heap entry still refers to a live order.
+// Cancelled and amended orders are removed from the maps only, so their
+// heap entries are dropped lazily when they are popped.
+func (ob *OrderBook) isActive(order *model.Order) bool {
+	live, exists := ob.Orders[order.ID]
+	return exists && live == order
+}
+
+// removeOrder remove an order from all relevant data structures
+// and releases the reservation of its remaining quantity.
+func (ob *OrderBook) removeOrder(order *model.Order) {
+	ob.bookChanged = true
+	ob.release(order)
+	delete(ob.Orders, order.ID)
+	delete(ob.pegged, order.ID)
+	delete(ob.constrained, order.ID)
+	if customerOrders, ok := ob.CustomerOrders[order.CustomerID]; ok {
+		delete(customerOrders, order.ID)
+		if len(customerOrders) == 0 {
+			delete(ob.CustomerOrders, order.CustomerID)
+		}
+	}
+}
+
+// reinsertSkippedOrders reinsert skipped orders back into the heap
+func (ob *OrderBook) reinsertSkippedOrders(orders *model.OrderHeap, skippedOrders []*model.Order) {
+	for _, skipped := range skippedOrders {
+		heap.Push(orders, skipped)
+	}
+}
+
+// checkRisk runs the risk checker, if any, against the customer's current
+// exposure. An order being replaced is left out of the exposure.
+func (ob *OrderBook) checkRisk(req model.OrderRequest, replaced *model.Order) error {
+	if ob.risk == nil {
+		return nil
+	}
+
+	exposure := model.RiskExposure{LastTradePrice: ob.LastTradePrice}
+	for _, order := range ob.CustomerOrders[req.CustomerID] {
+		if order == replaced {
+			continue
+		}
+		exposure.OpenOrders++
+		if order.OrderType == constant.BuyOrder {
+			// Saturate, an overflowing exposure breaches any limit anyway
+			bidNotional, err := model.AddNotional(exposure.BidNotional, order.RemainingNotional())
+			if err != nil {
+				bidNotional = math.MaxUint64
+			}
+			exposure.BidNotional = bidNotional
+		}
+	}
+
+	if err := ob.risk.Check(req, exposure); err != nil {
+		ob.logger.Warn("Risk check failed", zap.Uint("customerID", req.CustomerID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// checkPrice validates a price against the trading rules, with the band
+// around the last trade price.
+func (ob *OrderBook) checkPrice(price model.Price) error {
+	if ob.rules == nil {
+		return nil
+	}
+	if err := ob.rules.CheckPrice(price, ob.LastTradePrice); err != nil {
+		ob.logger.Warn("Price refused by trading rules", zap.Uint64("price", uint64(price)), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// checkStopPrice returns an error if the stop price is off the tick or
+// outside the price limits.
+func (ob *OrderBook) checkStopPrice(price model.Price) error {
+	if ob.rules == nil {
+		return nil
+	}
+	if err := ob.rules.CheckStopPrice(price); err != nil {
+		ob.logger.Warn("Stop price refused by trading rules", zap.Uint64("stopPrice", uint64(price)), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// checkPhase returns the rejection of a command the current market phase
+// does not accept.
+func (ob *OrderBook) checkPhase(accepted bool) error {
+	switch {
+	case accepted:
+		return nil
+	case ob.Phase == constant.HaltedPhase:
+		return reject.ErrBookHalted
+	default:
+		return fmt.Errorf("%w: %s", reject.ErrMarketClosed, ob.Phase)
+	}
+}
+
+// rejectOrder publishes the rejection of a request and returns err.
+func (ob *OrderBook) rejectOrder(req model.OrderRequest, orderID uint64, err error) error {
+	ob.emit(event.OrderRejected{
+		Header:  event.Header{Timestamp: ob.clock.Now()},
+		Request: req,
+		OrderID: orderID,
+		Code:    reject.CodeOf(err),
+		Reason:  err.Error(),
+	})
+	return err
+}
+
+// header returns the event header for the command being processed.
+func (ob *OrderBook) header(timestamp time.Time) event.Header {
+	header := event.Header{Timestamp: timestamp}
+	if ob.journal != nil {
+		header.Seq = ob.LastSeq
+	}
+	return header
+}
+
+// emit queues an event until the current command completes.
+func (ob *OrderBook) emit(e event.Event) {
+	if ob.events == nil {
+		return
+	}
+	ob.pendingEvents = append(ob.pendingEvents, e)
+}
+
+// publishEvents publishes the events of the current command. A command that
+// changed the resting orders adds a BookChanged event, followed during a call
+// period by the new indicative auction. It runs while the book is still
+// locked, so events of different commands never interleave.
+func (ob *OrderBook) publishEvents() {
+	changed := ob.bookChanged
+	ob.bookChanged = false
+	if ob.events == nil {
+		return
+	}
+
+	if changed {
+		bookChanged := event.BookChanged{Header: ob.header(ob.clock.Now())}
+		if best := ob.bestOrder(ob.BuyOrders); best != nil {
+			bookChanged.BestBid = best.Price
+			bookChanged.BestBidQuantity = best.Displayed()
+		}
+		if best := ob.bestOrder(ob.SellOrders); best != nil {
+			bookChanged.BestAsk = best.Price
+			bookChanged.BestAskQuantity = best.Displayed()
+		}
+		ob.pendingEvents = append(ob.pendingEvents, bookChanged)
+	}
+	if changed && ob.Phase.IsCall() {
+		bids, asks := ob.auctionOrders(ob.clock.Now())
+		indicative := auction.ClearingPrice(bids, asks, ob.LastTradePrice)
+		ob.pendingEvents = append(ob.pendingEvents, event.AuctionIndicative{Header: ob.header(ob.clock.Now()), Auction: indicative})
+	}
+
+	ob.events.Publish(ob.pendingEvents...)
+	ob.pendingEvents = nil
+}
+
+// bestOrder returns the highest priority active order of a side, dropping
+// stale entries from the top of the heap on the way.
+func (ob *OrderBook) bestOrder(orders *model.OrderHeap) *model.Order {
+	for orders.Len() > 0 {
+		if top := orders.Orders[0]; ob.isActive(top) {
+			return top
+		}
+		heap.Pop(orders)
+	}
+	return nil
 }
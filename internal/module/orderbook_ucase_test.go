@@ -1,28 +1,51 @@
 package module_test
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/trungnt1811/simple-order-book/internal/allocation"
+	"github.com/trungnt1811/simple-order-book/internal/breaker"
+	"github.com/trungnt1811/simple-order-book/internal/calendar"
+	"github.com/trungnt1811/simple-order-book/internal/clock"
 	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/fee"
+	"github.com/trungnt1811/simple-order-book/internal/instrument"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
 	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+	"github.com/trungnt1811/simple-order-book/internal/risk"
 	"github.com/trungnt1811/simple-order-book/internal/util"
 )
 
+// startTime is the initial time of the manual clock used by the tests.
+var startTime = time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+// createGTT returns a GTT the given number of hours after the clock's current time.
+func createGTT(clk *clock.Manual, hours int) *time.Time {
+	gtt := clk.Now().Add(time.Duration(hours) * time.Hour)
+	return &gtt
+}
+
 // TestSubmitOrder tests the SubmitOrder function.
 func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	logger := util.SetupLogger()
 	defer logger.Sync() // Flushes buffer, if any
 	t.Run("Submit Buy Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(18)
 		orderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 1))
 
 		// Check if the order is added to the BuyOrders heap
 		require.Equal(t, 1, orderBook.GetBuyOrders().Len(), "Expected 1 buy order in the heap")
@@ -36,12 +59,13 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Sell Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(11)
 		orderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 90, constant.SellOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 90, constant.SellOrder, createGTT(clk, 1))
 
 		// Check if the order is added to the SellOrders heap
 		require.Equal(t, 1, orderBook.GetSellOrders().Len(), "Expected 1 sell order in the heap")
@@ -55,18 +79,19 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Buy Order with Exact Price and Match Sell Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		// Prepare sell order
 		sellCustomerID := uint(1995)
 		sellOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(sellCustomerID, 90, constant.SellOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(sellCustomerID, 90, constant.SellOrder, createGTT(clk, 1))
 
 		// Prepare buy order should match
 		buyCustomerID := uint(4953)
 		buyOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, createGTT(clk, 1))
 
 		// Check if the buy order is matched and not in the heap
 		require.Equal(t, 0, orderBook.GetBuyOrders().Len(), "Expected 0 buy orders in the heap")
@@ -88,18 +113,19 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Buy Order with Higher Price and Match Sell Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		// Prepare sell order
 		sellCustomerID := uint(1995)
 		sellOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(sellCustomerID, 90, constant.SellOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(sellCustomerID, 90, constant.SellOrder, createGTT(clk, 1))
 
 		// Prepare buy order should match
 		buyCustomerID := uint(4953)
 		buyOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(buyCustomerID, 91, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(buyCustomerID, 91, constant.BuyOrder, createGTT(clk, 1))
 
 		// Check if the buy order is matched and not in the heap
 		require.Equal(t, 0, orderBook.GetBuyOrders().Len(), "Expected 0 buy orders in the heap")
@@ -121,18 +147,19 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Sell Order with Exact Price and Match Buy Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		// Prepare buy order
 		buyCustomerID := uint(4953)
 		buyOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, createGTT(clk, 1))
 
 		// Prepare sell order that should match
 		sellCustomerID := uint(1995)
 		sellOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(sellCustomerID, 90, constant.SellOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(sellCustomerID, 90, constant.SellOrder, createGTT(clk, 1))
 
 		// Check if the sell order is matched and not in the heap
 		require.Equal(t, 0, orderBook.GetSellOrders().Len(), "Expected 0 sell orders in the heap")
@@ -154,18 +181,19 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Sell Order with Lower Price and Match Buy Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		// Prepare buy order
 		buyCustomerID := uint(4953)
 		buyOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, createGTT(clk, 1))
 
 		// Prepare sell order that should match
 		sellCustomerID := uint(1995)
 		sellOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(sellCustomerID, 89, constant.SellOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(sellCustomerID, 89, constant.SellOrder, createGTT(clk, 1))
 
 		// Check if the sell order is matched and not in the heap
 		require.Equal(t, 0, orderBook.GetSellOrders().Len(), "Expected 0 sell orders in the heap")
@@ -187,8 +215,9 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Order with Nil GTT", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		orderID := orderBook.GetNextOrderID()
 		customerID := uint(911)
@@ -206,12 +235,13 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Multiple Orders from Same Customer", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(9947)
-		orderBook.SubmitOrder(customerID, 120, constant.SellOrder, util.CreateGTT(2))
-		orderBook.SubmitOrder(customerID, 130, constant.SellOrder, util.CreateGTT(3))
+		orderBook.SubmitOrder(customerID, 120, constant.SellOrder, createGTT(clk, 2))
+		orderBook.SubmitOrder(customerID, 130, constant.SellOrder, createGTT(clk, 3))
 
 		// Check if the orders are added to the SellOrders heap
 		require.Equal(t, 2, orderBook.GetSellOrders().Len(), "Expected 2 sell orders in the heap")
@@ -221,13 +251,15 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Order with Expired GTT", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
-		expiredGTT := time.Now().Add(-1 * time.Hour)
+		expiredGTT := clk.Now().Add(-1 * time.Hour)
 		orderID := orderBook.GetNextOrderID()
 		customerID := uint(18111995)
-		orderBook.SubmitOrder(customerID, 95, constant.SellOrder, &expiredGTT)
+		err := orderBook.SubmitOrder(customerID, 95, constant.SellOrder, &expiredGTT)
+		require.ErrorIs(t, err, reject.ErrExpiredOnArrival)
 
 		// Check if the order is not added to the SellOrders heap
 		require.Equal(t, 0, orderBook.GetSellOrders().Len(), "Expected 0 sell orders in the heap")
@@ -241,16 +273,18 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Submit Orders from Same Customer with Same Prices but Different Timestamp", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(69)
 		orderID1 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 1))
 
 		// Change the timestamp but same price
+		clk.Advance(time.Second)
 		orderID2 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, util.CreateGTT(2))
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 2))
 
 		// Check if both orders are added to the BuyOrders heap
 		require.Equal(t, 2, orderBook.GetBuyOrders().Len(), "Expected 2 buy orders in the heap")
@@ -266,19 +300,20 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 
 	t.Run("Match Cancelled Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		// Submit and then cancel a buy order
 		buyCustomerID := uint(3456)
 		buyOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(buyCustomerID, 95, constant.BuyOrder, util.CreateGTT(1))
-		orderBook.CancelOrder(buyOrderID)
+		orderBook.SubmitOrder(buyCustomerID, 95, constant.BuyOrder, createGTT(clk, 1))
+		orderBook.CancelOrder(buyCustomerID, buyOrderID)
 
 		// Submit a sell order with matching price
 		sellCustomerID := uint(7890)
 		sellOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(sellCustomerID, 95, constant.SellOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(sellCustomerID, 95, constant.SellOrder, createGTT(clk, 1))
 
 		// Check if the cancelled buy order is not matched
 		require.Equal(t, 0, orderBook.GetBuyOrders().Len(), "Expected 0 buy orders in the heap after cancellation")
@@ -300,18 +335,139 @@ func TestOrderBookUCase_SubmitOrder(t *testing.T) {
 	})
 }
 
+func TestOrderBookUCase_TimePriority(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+	t.Run("Earlier Order at Same Price Matches First", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		// Two sell orders at the same price, one second apart
+		firstOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(401), 100, constant.SellOrder, nil)
+		clk.Advance(time.Second)
+		secondOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(402), 100, constant.SellOrder, nil)
+
+		// The buy order matches the earlier sell order
+		clk.Advance(time.Second)
+		orderBook.SubmitOrder(uint(403), 100, constant.BuyOrder, nil)
+
+		trades := orderBook.GetTrades()
+		require.Equal(t, 1, len(trades), "Expected 1 trade")
+		require.Equal(t, firstOrderID, trades[0].MakerOrderID, "Expected the earlier order to match first")
+		require.Equal(t, clk.Now(), trades[0].Timestamp, "Expected the trade to use the clock time")
+		_, exists := orderBook.GetOrders()[secondOrderID]
+		require.True(t, exists, "Order ID %d should still exist in the Orders map", secondOrderID)
+	})
+
+	t.Run("Amended Order Loses Time Priority", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		firstOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(404), 100, constant.BuyOrder, nil)
+		clk.Advance(time.Second)
+		secondOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(405), 100, constant.BuyOrder, nil)
+
+		// Amending the first order moves it behind the second one
+		clk.Advance(time.Second)
+		orderBook.AmendOrder(uint(404), firstOrderID, 100, nil)
+		orderBook.SubmitOrder(uint(406), 100, constant.SellOrder, nil)
+
+		trades := orderBook.GetTrades()
+		require.Equal(t, 1, len(trades), "Expected 1 trade")
+		require.Equal(t, secondOrderID, trades[0].MakerOrderID, "Expected the unamended order to match first")
+	})
+}
+
+func TestOrderBookUCase_RemoveExpiredOrders(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+	t.Run("Remove Expired Buy Orders", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		customerID := uint(501)
+		expiringOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 1))
+		activeOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(customerID, 101, constant.BuyOrder, createGTT(clk, 3))
+		orderBook.SubmitOrder(customerID, 102, constant.BuyOrder, nil)
+
+		// Move past the first GTT only
+		clk.Advance(2 * time.Hour)
+		require.Equal(t, 2, len(orderBook.QueryOrders(customerID)), "Expected 2 active orders for customer ID %d", customerID)
+
+		orderBook.RemoveExpiredBuyOrders()
+
+		_, exists := orderBook.GetOrders()[expiringOrderID]
+		require.False(t, exists, "Order ID %d should not exist in the Orders map", expiringOrderID)
+		_, exists = orderBook.GetOrders()[activeOrderID]
+		require.True(t, exists, "Order ID %d should exist in the Orders map", activeOrderID)
+		require.Equal(t, 2, orderBook.GetBuyOrders().Len(), "Expected 2 buy orders in the heap")
+		require.Equal(t, 2, len(orderBook.GetCustomerOrders()[customerID]), "Expected 2 orders for customer ID %d", customerID)
+	})
+
+	t.Run("Remove Expired Sell Orders", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		customerID := uint(502)
+		orderBook.SubmitOrder(customerID, 100, constant.SellOrder, createGTT(clk, 1))
+		orderBook.SubmitOrder(customerID, 101, constant.SellOrder, createGTT(clk, 1))
+
+		// Nothing expires before the GTT
+		orderBook.RemoveExpiredSellOrders()
+		require.Equal(t, 2, orderBook.GetSellOrders().Len(), "Expected 2 sell orders in the heap")
+
+		clk.Advance(time.Hour + time.Second)
+		orderBook.RemoveExpiredSellOrders()
+
+		require.Equal(t, 0, orderBook.GetSellOrders().Len(), "Expected 0 sell orders in the heap")
+		require.Equal(t, 0, len(orderBook.GetOrders()), "Expected 0 orders in the Orders map")
+		require.Equal(t, 0, len(orderBook.GetCustomerOrders()[customerID]), "Expected 0 orders for customer ID %d", customerID)
+	})
+
+	t.Run("Expired Resting Order Is Not Matched", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		sellOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(503), 100, constant.SellOrder, createGTT(clk, 1))
+
+		// The sell order expires before the buy order arrives
+		clk.Advance(2 * time.Hour)
+		buyOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(504), 100, constant.BuyOrder, createGTT(clk, 1))
+
+		require.Equal(t, 0, len(orderBook.GetTrades()), "Expected no trade against an expired order")
+		_, exists := orderBook.GetOrders()[sellOrderID]
+		require.False(t, exists, "Order ID %d should not exist in the Orders map", sellOrderID)
+		_, exists = orderBook.GetOrders()[buyOrderID]
+		require.True(t, exists, "Order ID %d should exist in the Orders map", buyOrderID)
+	})
+}
+
 func TestOrderBookUCase_CancelOrder(t *testing.T) {
 	logger := util.SetupLogger()
 	defer logger.Sync() // Flushes buffer, if any
 	t.Run("Cancel existing order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		orderID := orderBook.GetNextOrderID()
 
 		// Submit initial order
 		customerID := uint(123)
-		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 1))
 
 		// Check if the order is added to the Orders map
 		_, exists := orderBook.GetOrders()[orderID]
@@ -321,7 +477,7 @@ func TestOrderBookUCase_CancelOrder(t *testing.T) {
 		require.Equal(t, 1, len(orderBook.GetCustomerOrders()[customerID]), "Unexpected number of orders for customer ID %d", customerID)
 
 		// Perform cancel existing order
-		err := orderBook.CancelOrder(orderID)
+		err := orderBook.CancelOrder(customerID, orderID)
 		require.NoError(t, err, "CancelOrder should not return an error")
 
 		// Check if the order is removed from the Orders map
@@ -332,14 +488,15 @@ func TestOrderBookUCase_CancelOrder(t *testing.T) {
 		require.Equal(t, 0, len(orderBook.GetCustomerOrders()[customerID]), "Unexpected number of orders for customer ID %d", customerID)
 	})
 	t.Run("Cancel non-existent order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		orderID := orderBook.GetNextOrderID()
 
 		// Submit initial order
 		customerID := uint(456)
-		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 1))
 
 		// Check if the order is added to the Orders map
 		_, exists := orderBook.GetOrders()[orderID]
@@ -350,8 +507,10 @@ func TestOrderBookUCase_CancelOrder(t *testing.T) {
 
 		// Try to cancel a non-existent order
 		nonExistentOrderID := orderID + 1
-		err := orderBook.CancelOrder(nonExistentOrderID)
+		err := orderBook.CancelOrder(customerID, nonExistentOrderID)
 		require.Error(t, err, fmt.Sprintf("order not found: %d", nonExistentOrderID))
+		require.ErrorIs(t, err, reject.ErrUnknownOrder)
+		require.Equal(t, reject.CodeUnknownOrder, reject.CodeOf(err))
 
 		// Check if the order is still present in the Orders map
 		_, exists = orderBook.GetOrders()[orderID]
@@ -361,19 +520,61 @@ func TestOrderBookUCase_CancelOrder(t *testing.T) {
 		require.Equal(t, 1, len(orderBook.GetCustomerOrders()[customerID]), "Unexpected number of orders for customer ID %d", customerID)
 	})
 
+	t.Run("Cancel order of another customer", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		orderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(457), 100, constant.BuyOrder, createGTT(clk, 1))
+
+		// Another customer guesses the order ID
+		err := orderBook.CancelOrder(uint(458), orderID)
+		require.ErrorIs(t, err, reject.ErrNotOwner)
+
+		// Check if the order is still present in the Orders map
+		_, exists := orderBook.GetOrders()[orderID]
+		require.True(t, exists, "Order ID %d should still exist in the Orders map", orderID)
+	})
+
+	t.Run("Admin cancel", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		journal := &recordingJournal{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(journal), module.WithEventBus(publisher))
+
+		orderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(459), 100, constant.BuyOrder, createGTT(clk, 1))
+
+		// Support staff may cancel any order
+		require.NoError(t, orderBook.AdminCancelOrder(orderID, "alice"))
+		_, exists := orderBook.GetOrders()[orderID]
+		require.False(t, exists, "Order ID %d should not exist in the Orders map", orderID)
+
+		// The canceller is journaled and published
+		require.Equal(t, "alice", journal.commands[1].Admin)
+		cancelled := publisher.events[len(publisher.events)-2].(event.OrderCancelled)
+		require.Equal(t, "admin:alice", cancelled.CancelledBy)
+
+		require.ErrorIs(t, orderBook.AdminCancelOrder(orderID, "alice"), reject.ErrUnknownOrder)
+		require.Error(t, orderBook.AdminCancelOrder(orderID+1, ""), "Expected the operator to be required")
+	})
+
 	t.Run("Cancel order in multi-order customer", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		// Submit initial orders
 		customerID := uint(789)
 		orderID1 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 150, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 150, constant.BuyOrder, createGTT(clk, 1))
 		orderID2 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 160, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 160, constant.BuyOrder, createGTT(clk, 1))
 
 		// Cancel the first order
-		err := orderBook.CancelOrder(orderID1)
+		err := orderBook.CancelOrder(customerID, orderID1)
 		require.NoError(t, err, "CancelOrder should not return an error")
 
 		// Check if the first order is removed from the Orders map
@@ -393,12 +594,13 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	logger := util.SetupLogger()
 	defer logger.Sync() // Flushes buffer, if any
 	t.Run("Query Orders with Active Orders", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(100)
-		orderBook.SubmitOrder(customerID, 120, constant.BuyOrder, util.CreateGTT(1))
-		orderBook.SubmitOrder(customerID, 130, constant.BuyOrder, util.CreateGTT(2))
+		orderBook.SubmitOrder(customerID, 120, constant.BuyOrder, createGTT(clk, 1))
+		orderBook.SubmitOrder(customerID, 130, constant.BuyOrder, createGTT(clk, 2))
 
 		// Query active orders
 		orders := orderBook.QueryOrders(customerID)
@@ -407,11 +609,12 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	})
 
 	t.Run("Query Orders with Expired Orders", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(101)
-		expiredGTT := time.Now().Add(-1 * time.Hour)
+		expiredGTT := clk.Now().Add(-1 * time.Hour)
 		orderBook.SubmitOrder(customerID, 120, constant.BuyOrder, &expiredGTT)
 		orderBook.SubmitOrder(customerID, 130, constant.BuyOrder, &expiredGTT)
 
@@ -422,8 +625,9 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	})
 
 	t.Run("Query Orders with No Orders", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(102)
 
@@ -434,14 +638,15 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	})
 
 	t.Run("Query Orders with Both Active and Expired Orders", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(103)
-		expiredGTT := time.Now().Add(-1 * time.Hour)
+		expiredGTT := clk.Now().Add(-1 * time.Hour)
 		orderBook.SubmitOrder(customerID, 140, constant.BuyOrder, &expiredGTT)
 		activeOrderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 150, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 150, constant.BuyOrder, createGTT(clk, 1))
 
 		// Query orders
 		orders := orderBook.QueryOrders(customerID)
@@ -451,13 +656,14 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	})
 
 	t.Run("Query Orders After Canceling an Order", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(104)
 		orderID := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 150, constant.BuyOrder, util.CreateGTT(1))
-		orderBook.CancelOrder(orderID)
+		orderBook.SubmitOrder(customerID, 150, constant.BuyOrder, createGTT(clk, 1))
+		orderBook.CancelOrder(customerID, orderID)
 
 		// Query orders
 		orders := orderBook.QueryOrders(customerID)
@@ -466,16 +672,17 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	})
 
 	t.Run("Query Orders After Canceling All Orders", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(105)
 		orderID1 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 160, constant.BuyOrder, util.CreateGTT(1))
+		orderBook.SubmitOrder(customerID, 160, constant.BuyOrder, createGTT(clk, 1))
 		orderID2 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 170, constant.BuyOrder, util.CreateGTT(2))
-		orderBook.CancelOrder(orderID1)
-		orderBook.CancelOrder(orderID2)
+		orderBook.SubmitOrder(customerID, 170, constant.BuyOrder, createGTT(clk, 2))
+		orderBook.CancelOrder(customerID, orderID1)
+		orderBook.CancelOrder(customerID, orderID2)
 
 		// Query orders
 		orders := orderBook.QueryOrders(customerID)
@@ -484,16 +691,16 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 	})
 
 	t.Run("Query Orders with Cancelled Orders but Active Orders Present", func(t *testing.T) {
-		// Create a new order book
-		orderBook := module.NewOrderBookUCase(logger)
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
 
 		customerID := uint(106)
-		expiredGTT := time.Now().Add(-1 * time.Hour)
 		orderID1 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 180, constant.BuyOrder, &expiredGTT)
+		orderBook.SubmitOrder(customerID, 180, constant.BuyOrder, createGTT(clk, 1))
 		orderID2 := orderBook.GetNextOrderID()
-		orderBook.SubmitOrder(customerID, 190, constant.BuyOrder, util.CreateGTT(1))
-		orderBook.CancelOrder(orderID1)
+		orderBook.SubmitOrder(customerID, 190, constant.BuyOrder, createGTT(clk, 1))
+		orderBook.CancelOrder(customerID, orderID1)
 
 		// Query orders
 		orders := orderBook.QueryOrders(customerID)
@@ -502,3 +709,1684 @@ func TestOrderBookUCase_QueryOrders(t *testing.T) {
 		require.Equal(t, orderID2, orders[0].ID, "Expected order with ID %d", orderID2)
 	})
 }
+
+func TestOrderBookUCase_AmendOrder(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+	t.Run("Amend Order Price into a Match", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		sellCustomerID := uint(201)
+		sellOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(sellCustomerID, 100, constant.SellOrder, createGTT(clk, 1))
+
+		buyCustomerID := uint(202)
+		buyOrderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(buyCustomerID, 90, constant.BuyOrder, createGTT(clk, 1))
+
+		// Amend the buy order so it crosses the sell order
+		err := orderBook.AmendOrder(buyCustomerID, buyOrderID, 100, createGTT(clk, 1))
+		require.NoError(t, err, "AmendOrder should not return an error")
+
+		// Check if both orders are removed from the Orders map
+		_, exists := orderBook.GetOrders()[sellOrderID]
+		require.False(t, exists, "Order ID %d should not exist in the Orders map", sellOrderID)
+		_, exists = orderBook.GetOrders()[buyOrderID]
+		require.False(t, exists, "Order ID %d should not exist in the Orders map", buyOrderID)
+
+		// Check if the trade is recorded
+		trades := orderBook.GetTrades()
+		require.Equal(t, 1, len(trades), "Expected 1 trade")
+		require.Equal(t, buyOrderID, trades[0].TakerOrderID, "Expected the amended order to be the taker")
+		require.Equal(t, model.Price(100), trades[0].Price, "Expected trade at the maker price")
+	})
+
+	t.Run("Amend Order Keeps ID and Drops Stale Entry", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		customerID := uint(203)
+		orderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(customerID, 100, constant.BuyOrder, createGTT(clk, 1))
+
+		err := orderBook.AmendOrder(customerID, orderID, 95, createGTT(clk, 2))
+		require.NoError(t, err, "AmendOrder should not return an error")
+
+		// Check if the amended order replaced the original one
+		order, exists := orderBook.GetOrders()[orderID]
+		require.True(t, exists, "Order ID %d should exist in the Orders map", orderID)
+		require.Equal(t, model.Price(95), order.Price, "Expected amended price")
+		require.Equal(t, 1, len(orderBook.GetCustomerOrders()[customerID]), "Expected 1 order for customer ID %d", customerID)
+
+		// The stale heap entry must not match against a sell order at the old price
+		orderBook.SubmitOrder(uint(204), 98, constant.SellOrder, createGTT(clk, 1))
+		require.Equal(t, 0, len(orderBook.GetTrades()), "Expected no trade against the stale entry")
+		_, exists = orderBook.GetOrders()[orderID]
+		require.True(t, exists, "Order ID %d should still exist in the Orders map", orderID)
+	})
+
+	t.Run("Amend Non-existent Order", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		err := orderBook.AmendOrder(1, orderBook.GetNextOrderID(), 100, nil)
+		require.ErrorIs(t, err, reject.ErrUnknownOrder, "AmendOrder should return an error for unknown orders")
+	})
+
+	t.Run("Amend Order by Non-owner", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		journal := &recordingJournal{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(journal))
+
+		orderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(206), 100, constant.BuyOrder, nil)
+
+		err := orderBook.AmendOrder(uint(207), orderID, 101, nil)
+		require.ErrorIs(t, err, reject.ErrNotOwner, "AmendOrder should reject a non-owner")
+
+		// Check if the original order is untouched and the amend was not journaled
+		require.Equal(t, model.Price(100), orderBook.GetOrders()[orderID].Price, "Expected original price")
+		require.Len(t, journal.commands, 1, "Rejected amends should not be journaled")
+	})
+
+	t.Run("Amend Order with Invalid Price", func(t *testing.T) {
+		// Create a new order book driven by a manual clock
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		orderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(205), 100, constant.BuyOrder, nil)
+
+		err := orderBook.AmendOrder(uint(205), orderID, 0, nil)
+		require.ErrorIs(t, err, reject.ErrInvalidPrice, "AmendOrder should reject a zero price")
+
+		// Check if the original order is untouched
+		require.Equal(t, model.Price(100), orderBook.GetOrders()[orderID].Price, "Expected original price")
+	})
+}
+
+// recordingJournal is a journal stub that records commands or fails on demand.
+type recordingJournal struct {
+	commands []model.Command
+	err      error
+}
+
+func (j *recordingJournal) Append(cmd *model.Command) error {
+	if j.err != nil {
+		return j.err
+	}
+	cmd.Seq = uint64(len(j.commands) + 1)
+	j.commands = append(j.commands, *cmd)
+	return nil
+}
+
+func TestOrderBookUCase_Journal(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+	t.Run("Accepted Commands Are Journaled", func(t *testing.T) {
+		journal := &recordingJournal{}
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(journal))
+
+		orderID := orderBook.GetNextOrderID()
+		orderBook.SubmitOrder(uint(301), 100, constant.BuyOrder, nil)
+		orderBook.AmendOrder(uint(301), orderID, 101, nil)
+		orderBook.CancelOrder(301, orderID)
+
+		// Rejected commands are not journaled
+		orderBook.SubmitOrder(uint(301), 0, constant.BuyOrder, nil)
+		orderBook.CancelOrder(301, orderID)
+
+		require.Equal(t, 3, len(journal.commands), "Expected 3 journaled commands")
+		require.Equal(t, constant.SubmitCommand, journal.commands[0].Action)
+		require.Equal(t, constant.AmendCommand, journal.commands[1].Action)
+		require.Equal(t, constant.CancelCommand, journal.commands[2].Action)
+		require.Equal(t, uint64(3), journal.commands[2].Seq)
+	})
+
+	t.Run("Journal Failure Leaves Book Untouched", func(t *testing.T) {
+		journal := &recordingJournal{err: errors.New("disk full")}
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(journal))
+
+		orderID := orderBook.GetNextOrderID()
+		err := orderBook.SubmitOrder(uint(302), 100, constant.BuyOrder, nil)
+		require.Error(t, err, "SubmitOrder should fail when the journal fails")
+
+		// Check if the order is not added to the book
+		require.Equal(t, 0, orderBook.GetBuyOrders().Len(), "Expected 0 buy orders in the heap")
+		_, exists := orderBook.GetOrders()[orderID]
+		require.False(t, exists, "Order ID %d should not exist in the Orders map", orderID)
+		require.Equal(t, orderID, orderBook.GetNextOrderID(), "Expected the order ID not to be consumed")
+	})
+}
+
+// TestOrderBookUCase_PlaceOrder tests matching orders with quantities.
+func TestOrderBookUCase_PlaceOrder(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Partial Fill Across Price Levels", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		sell1, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		clk.Advance(time.Second)
+		sell2, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 101, Quantity: 4, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		clk.Advance(time.Second)
+
+		// Buy 5 takes all of the best level and 2 of the next one
+		buy, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 101, Quantity: 5, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, sell1, trades[0].MakerOrderID)
+		require.Equal(t, uint(3), trades[0].Quantity)
+		require.Equal(t, sell2, trades[1].MakerOrderID)
+		require.Equal(t, uint(2), trades[1].Quantity)
+		require.Equal(t, model.Price(101), trades[1].Price)
+
+		// The buy order is filled and the second sell order rests with 2 left
+		_, exists := orderBook.GetOrders()[buy]
+		require.False(t, exists, "Filled order should not rest in the book")
+		require.Equal(t, uint(2), orderBook.GetOrders()[sell2].Remaining)
+		require.Equal(t, uint(4), orderBook.GetOrders()[sell2].Quantity)
+	})
+
+	t.Run("Remaining Quantity Rests", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 2, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		buy, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 5, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		require.Equal(t, 0, len(orderBook.QueryOrders(1)), "Sell order should be filled")
+		require.Equal(t, uint(3), orderBook.GetOrders()[buy].Remaining)
+	})
+
+	t.Run("Skipped Own Orders Are Kept", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+
+		// The only sell order belongs to the buyer, the heap empties while skipping it
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		require.Equal(t, 1, orderBook.GetSellOrders().Len(), "Skipped sell order should be back in the heap")
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 1)
+	})
+
+	t.Run("Notional Overflow", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: math.MaxUint64 / 2, Quantity: 3, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrNotionalOverflow)
+
+		orderID, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 3, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.ErrorIs(t, orderBook.AmendOrder(1, orderID, math.MaxUint64/2, nil), reject.ErrNotionalOverflow)
+	})
+
+	t.Run("Invalid Quantity", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrInvalidQuantity)
+		require.Equal(t, 0, len(orderBook.GetOrders()))
+	})
+
+	t.Run("Trade History Keeps The Last Trades", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithTradeHistory(3))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 10, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		var earlier []*model.Trade
+		for i := range 10 {
+			require.NoError(t, orderBook.SubmitOrder(2, 100, constant.BuyOrder, nil))
+			if i == 2 {
+				earlier = orderBook.GetTrades()
+			}
+		}
+		trades := orderBook.GetTrades()
+		require.LessOrEqual(t, len(trades), 6)
+		require.GreaterOrEqual(t, len(trades), 3)
+		require.Equal(t, uint64(10), trades[len(trades)-1].ID)
+
+		// Trades handed out earlier are not overwritten
+		require.Len(t, earlier, 3)
+		require.Equal(t, uint64(1), earlier[0].ID)
+	})
+}
+
+// recordingPublisher collects the events published by the order book.
+type recordingPublisher struct {
+	events []event.Event
+}
+
+func (p *recordingPublisher) Publish(events ...event.Event) {
+	p.events = append(p.events, events...)
+}
+
+// types returns the types of the recorded events.
+func (p *recordingPublisher) types() []event.Type {
+	types := []event.Type{}
+	for _, e := range p.events {
+		types = append(types, e.EventType())
+	}
+	return types
+}
+
+// TestOrderBookUCase_Events tests the events published for each command.
+func TestOrderBookUCase_Events(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Fill Events", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Equal(t, []event.Type{event.OrderAcceptedType, event.BookChangedType}, publisher.types())
+
+		publisher.events = nil
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Equal(t, []event.Type{
+			event.OrderAcceptedType,
+			event.TradeExecutedType,
+			event.OrderPartiallyFilledType, // Maker
+			event.OrderFilledType,          // Taker
+			event.BookChangedType,
+		}, publisher.types())
+
+		partial := publisher.events[2].(event.OrderPartiallyFilled)
+		require.Equal(t, uint(1), partial.FillQuantity)
+		require.Equal(t, uint(2), partial.Order.Remaining)
+
+		bookChanged := publisher.events[4].(event.BookChanged)
+		require.Equal(t, model.Price(0), bookChanged.BestBid, "Expected an empty buy side")
+		require.Equal(t, model.Price(100), bookChanged.BestAsk)
+		require.Equal(t, uint(2), bookChanged.BestAskQuantity)
+	})
+
+	t.Run("Cancel, Reject and Expire Events", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher))
+
+		orderID, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 99, Quantity: 1, OrderType: constant.BuyOrder, GTT: createGTT(clk, 1)})
+		require.NoError(t, err)
+
+		publisher.events = nil
+		require.NoError(t, orderBook.CancelOrder(1, orderID))
+		require.Equal(t, []event.Type{event.OrderCancelledType, event.BookChangedType}, publisher.types())
+
+		publisher.events = nil
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 0, Quantity: 1, OrderType: constant.BuyOrder})
+		require.Error(t, err)
+		require.Equal(t, []event.Type{event.OrderRejectedType}, publisher.types())
+		require.Equal(t, reject.CodeInvalidPrice, publisher.events[0].(event.OrderRejected).Code)
+
+		publisher.events = nil
+		clk.Advance(2 * time.Hour)
+		orderBook.RemoveExpiredBuyOrders()
+		require.Equal(t, []event.Type{event.OrderExpiredType, event.BookChangedType}, publisher.types())
+	})
+
+	t.Run("Events Carry The Journal Sequence", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(&recordingJournal{}), module.WithEventBus(publisher))
+
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 100, constant.SellOrder, nil))
+		for _, e := range publisher.events[2:] {
+			require.Equal(t, uint64(2), e.EventHeader().Seq, "Expected events of the second command")
+		}
+	})
+}
+
+// TestOrderBookUCase_MassCancel tests cancelling every order matching a filter.
+func TestOrderBookUCase_MassCancel(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	// newBook returns a book with two customers resting orders on both sides
+	newBook := func(opts ...module.Option) interfaces.OrderBookUCase {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, append(opts, module.WithClock(clk))...)
+		orderBook.SubmitOrder(1, 90, constant.BuyOrder, nil)   // Order 1
+		orderBook.SubmitOrder(1, 110, constant.SellOrder, nil) // Order 2
+		orderBook.SubmitOrder(2, 95, constant.BuyOrder, nil)   // Order 3
+		orderBook.SubmitOrder(2, 120, constant.SellOrder, nil) // Order 4
+		orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil)  // Order 5
+		return orderBook
+	}
+	customerID := uint(1)
+	buySide := constant.BuyOrder
+
+	testCases := []struct {
+		name      string
+		filter    model.MassCancelFilter
+		cancelled []uint64
+	}{
+		{"By Customer", model.MassCancelFilter{CustomerID: &customerID}, []uint64{1, 2, 5}},
+		{"By Side", model.MassCancelFilter{Side: &buySide}, []uint64{1, 3, 5}},
+		{"By Price Range", model.MassCancelFilter{MinPrice: 95, MaxPrice: 110}, []uint64{2, 3, 5}},
+		{"By Customer and Side", model.MassCancelFilter{CustomerID: &customerID, Side: &buySide}, []uint64{1, 5}},
+		{"Whole Book", model.MassCancelFilter{}, []uint64{1, 2, 3, 4, 5}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderBook := newBook()
+
+			cancelled, err := orderBook.MassCancel(tc.filter, "alice")
+			require.NoError(t, err)
+			require.Equal(t, tc.cancelled, cancelled)
+
+			// Check if only the cancelled orders left the book
+			for _, orderID := range cancelled {
+				_, exists := orderBook.GetOrders()[orderID]
+				require.False(t, exists, "Order ID %d should not exist in the Orders map", orderID)
+			}
+			require.Equal(t, 5-len(cancelled), len(orderBook.GetOrders()))
+		})
+	}
+
+	t.Run("Single Journaled Command", func(t *testing.T) {
+		journal := &recordingJournal{}
+		publisher := &recordingPublisher{}
+		orderBook := newBook(module.WithJournal(journal), module.WithEventBus(publisher))
+
+		publisher.events = nil
+		cancelled, err := orderBook.MassCancel(model.MassCancelFilter{CustomerID: &customerID}, "alice")
+		require.NoError(t, err)
+		require.Len(t, journal.commands, 6, "Expected 5 submits and 1 mass cancel")
+		require.Equal(t, constant.MassCancelCommand, journal.commands[5].Action)
+		require.Equal(t, customerID, *journal.commands[5].Filter.CustomerID)
+
+		// One cancelled event per order and a single book update
+		require.Len(t, publisher.events, len(cancelled)+1)
+		require.Equal(t, "admin:alice", publisher.events[0].(event.OrderCancelled).CancelledBy)
+		require.Equal(t, event.BookChangedType, publisher.events[len(cancelled)].EventType())
+
+		// Nothing left to cancel is not journaled
+		cancelled, err = orderBook.MassCancel(model.MassCancelFilter{CustomerID: &customerID}, "alice")
+		require.NoError(t, err)
+		require.Empty(t, cancelled)
+		require.Len(t, journal.commands, 6)
+	})
+
+	t.Run("Invalid Price Range", func(t *testing.T) {
+		orderBook := newBook()
+
+		_, err := orderBook.MassCancel(model.MassCancelFilter{MinPrice: 110, MaxPrice: 100}, "alice")
+		require.ErrorIs(t, err, reject.ErrInvalidPrice)
+		require.Equal(t, 5, len(orderBook.GetOrders()))
+	})
+}
+
+// TestOrderBookUCase_RiskChecks tests pre-trade risk checks against the book.
+func TestOrderBookUCase_RiskChecks(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Exposure Of Resting Orders", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		checker := risk.NewChecker(risk.Limits{MaxOpenOrders: 2, MaxBidNotional: 1000})
+		journal := &recordingJournal{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(journal), module.WithRiskChecker(checker))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 6, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		// 600 resting plus 500 is above the bid notional limit
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrRiskLimit)
+		require.Len(t, journal.commands, 1, "Rejected orders should not be journaled")
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 200, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		// Two open orders is the limit
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 201, Quantity: 1, OrderType: constant.SellOrder})
+		require.ErrorIs(t, err, reject.ErrRiskLimit)
+
+		// Other customers have their own exposure
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 10, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+	})
+
+	t.Run("Amend Is Checked Without The Replaced Order", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		checker := risk.NewChecker(risk.Limits{MaxOpenOrders: 1, MaxBidNotional: 1000})
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithRiskChecker(checker))
+
+		orderID, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		require.NoError(t, orderBook.AmendOrder(1, orderID, 200, nil))
+		require.ErrorIs(t, orderBook.AmendOrder(1, orderID, 201, nil), reject.ErrRiskLimit)
+		require.Equal(t, model.Price(200), orderBook.GetOrders()[orderID].Price)
+	})
+
+	t.Run("Fat Finger Against Last Trade", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		checker := risk.NewChecker(risk.Limits{MaxDeviationBps: 500})
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithRiskChecker(checker))
+
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.SellOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 100, constant.BuyOrder, nil))
+
+		require.NoError(t, orderBook.SubmitOrder(1, 105, constant.SellOrder, nil))
+		require.ErrorIs(t, orderBook.SubmitOrder(1, 106, constant.SellOrder, nil), reject.ErrRiskLimit)
+	})
+}
+
+// TestOrderBookUCase_Fees tests fees attached to trades at match time.
+func TestOrderBookUCase_Fees(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Maker And Taker Fees On Each Fill", func(t *testing.T) {
+		schedule := fee.NewSchedule(fee.Rates{MakerBps: 10, TakerBps: 30, MinFee: 1})
+		schedule.SetTier("vip", fee.Rates{MakerBps: 0, TakerBps: 10})
+		require.NoError(t, schedule.AssignTier(3, "vip"))
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithFeeSchedule(schedule))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 1000, Quantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 1000, Quantity: 2, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 1000, Quantity: 3, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, uint64(2), trades[0].MakerFee)
+		require.Equal(t, uint64(6), trades[0].TakerFee)
+		require.Equal(t, uint64(3), trades[1].MakerFee)
+		require.Equal(t, uint64(3), trades[1].TakerFee, "Expected the taker's tier rate")
+
+		require.Equal(t, map[uint]uint64{1: 5, 2: 6, 3: 3}, fee.Totals(trades))
+	})
+
+	t.Run("No Fees Without A Schedule", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SubmitOrder(1, 1000, constant.SellOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 1000, constant.BuyOrder, nil))
+
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 1)
+		require.Zero(t, trades[0].MakerFee)
+		require.Zero(t, trades[0].TakerFee)
+	})
+}
+
+// TestOrderBookUCase_TradingRules tests tick size and price band validation.
+func TestOrderBookUCase_TradingRules(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Submit And Amend Are Validated", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		journal := &recordingJournal{}
+		rules := instrument.Rules{TickSize: 5, MaxPrice: 10000}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithJournal(journal), module.WithTradingRules(rules))
+
+		require.ErrorIs(t, orderBook.SubmitOrder(1, 102, constant.SellOrder, nil), reject.ErrOffTick)
+		require.ErrorIs(t, orderBook.SubmitOrder(1, 1_000_000, constant.SellOrder, nil), reject.ErrPriceOutOfRange)
+		require.Empty(t, journal.commands, "Rejected orders should not be journaled")
+
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.SellOrder, nil))
+		orderID := orderBook.GetNextOrderID() - 1
+		require.ErrorIs(t, orderBook.AmendOrder(1, orderID, 101, nil), reject.ErrOffTick)
+		require.NoError(t, orderBook.AmendOrder(1, orderID, 105, nil))
+	})
+
+	t.Run("Stop Prices Are Validated", func(t *testing.T) {
+		rules := instrument.Rules{TickSize: 5, MaxPrice: 10000, BandBps: 1000, ReferencePrice: 100}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithTradingRules(rules))
+
+		stop := func(stopPrice model.Price) error {
+			_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 105, StopPrice: stopPrice, Quantity: 1, OrderType: constant.BuyOrder})
+			return err
+		}
+		require.ErrorIs(t, stop(103), reject.ErrOffTick)
+		require.ErrorIs(t, stop(10005), reject.ErrPriceOutOfRange)
+		require.NoError(t, stop(200), "Expected a stop price outside the band to be accepted")
+	})
+
+	t.Run("Band Follows The Last Trade", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		rules := instrument.Rules{BandBps: 1000, ReferencePrice: 100}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithTradingRules(rules))
+
+		require.ErrorIs(t, orderBook.SubmitOrder(1, 120, constant.SellOrder, nil), reject.ErrPriceOutOfBand)
+		require.NoError(t, orderBook.SubmitOrder(1, 110, constant.SellOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 110, constant.BuyOrder, nil))
+
+		// The band is now centred on 110
+		require.NoError(t, orderBook.SubmitOrder(1, 120, constant.SellOrder, nil))
+		require.ErrorIs(t, orderBook.SubmitOrder(2, 98, constant.BuyOrder, nil), reject.ErrPriceOutOfBand)
+	})
+
+	t.Run("Pegs Stay On Tick And In The Band", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		rules := instrument.Rules{TickSize: 5, BandBps: 1000, ReferencePrice: 100}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)),
+			module.WithEventBus(publisher), module.WithTradingRules(rules))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 105, constant.SellOrder, nil))
+		ask := orderBook.GetNextOrderID() - 1
+
+		// The midpoint and odd offsets round to the tick away from the opposite side
+		testCases := []struct {
+			peg       constant.PegType
+			offset    int64
+			orderType constant.OrderType
+			price     model.Price
+		}{
+			{constant.MidpointPeg, 0, constant.BuyOrder, 100},
+			{constant.MidpointPeg, 0, constant.SellOrder, 105},
+			{constant.PrimaryPeg, -3, constant.BuyOrder, 95},
+			{constant.PrimaryPeg, 3, constant.SellOrder, 110},
+		}
+		for _, tc := range testCases {
+			id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Quantity: 1, OrderType: tc.orderType, Peg: tc.peg, PegOffset: tc.offset})
+			require.NoError(t, err)
+			require.Equal(t, tc.price, orderBook.GetOrders()[id].Price, "Price of a %s %s peg", tc.orderType, tc.peg)
+		}
+		pegged := orderBook.GetNextOrderID() - 1
+
+		// A peg starting out of the band is refused
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Quantity: 1, OrderType: constant.SellOrder, Peg: constant.PrimaryPeg, PegOffset: 10})
+		require.ErrorIs(t, err, reject.ErrPriceOutOfBand)
+
+		// A peg repriced out of the band is cancelled
+		require.NoError(t, orderBook.CancelOrder(2, ask))
+		publisher.events = nil
+		require.NoError(t, orderBook.SubmitOrder(2, 110, constant.SellOrder, nil))
+		require.NotContains(t, orderBook.GetOrders(), pegged)
+		require.Contains(t, publisher.types(), event.OrderCancelledType)
+	})
+}
+
+// TestOrderBookUCase_StopOrders tests stop and stop-limit orders.
+func TestOrderBookUCase_StopOrders(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	// trade executes one unit at price between two fresh customers
+	trade := func(t *testing.T, orderBook interfaces.OrderBookUCase, price model.Price) {
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 100, Price: price, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 101, Price: price, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+	}
+
+	t.Run("Stop Waits Outside The Book", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		trade(t, orderBook, 100)
+
+		orderID, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 106, StopPrice: 105, Quantity: 2, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Zero(t, orderBook.GetBuyOrders().Len(), "Expected the stop order outside the book")
+		require.Len(t, orderBook.QueryOrders(1), 1)
+
+		// A sell at the stop price does not trade against the stop order
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 104, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 1)
+
+		require.NoError(t, orderBook.CancelOrder(1, orderID))
+		require.Empty(t, orderBook.QueryOrders(1))
+	})
+
+	t.Run("Stop Limit Triggers Into The Book", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher))
+		trade(t, orderBook, 100)
+
+		orderID, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 106, StopPrice: 105, Quantity: 2, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		// A trade at 105 triggers the stop, which rests at its limit
+		clk.Advance(time.Second)
+		trade(t, orderBook, 105)
+		require.Contains(t, publisher.types(), event.StopTriggeredType)
+		order := orderBook.GetOrders()[orderID]
+		require.True(t, order.Triggered)
+		require.Equal(t, clk.Now(), order.Timestamp, "Expected the time priority of the trigger")
+		require.Equal(t, 1, orderBook.GetBuyOrders().Len())
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 106, Quantity: 2, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Empty(t, orderBook.QueryOrders(1))
+	})
+
+	t.Run("Cascading Stop Market Orders", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		trade(t, orderBook, 100)
+
+		// Bids at 99, 98 and 97
+		for i, price := range []model.Price{99, 98, 97} {
+			_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: uint(10 + i), Price: price, Quantity: 1, OrderType: constant.BuyOrder})
+			require.NoError(t, err)
+		}
+
+		// Sell stops at 99 and 98, each selling into the next bid
+		first, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, StopPrice: 99, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		second, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, StopPrice: 98, Quantity: 2, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		// One sell at 99 sets off both stops in the same command
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 99, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 4)
+		require.Equal(t, first, trades[2].TakerOrderID)
+		require.Equal(t, model.Price(98), trades[2].Price)
+		require.Equal(t, second, trades[3].TakerOrderID)
+		require.Equal(t, model.Price(97), trades[3].Price)
+
+		// The unfilled rest of the market order does not rest
+		require.Empty(t, orderBook.GetOrders())
+	})
+
+	t.Run("Stop Reached On Arrival", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		trade(t, orderBook, 100)
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 101, Quantity: 1, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, StopPrice: 95, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 2)
+		require.Equal(t, model.Price(101), orderBook.GetTrades()[1].Price)
+	})
+
+	t.Run("Stop Expires And Survives Snapshots", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 90, StopPrice: 91, Quantity: 1, OrderType: constant.SellOrder, GTT: createGTT(clk, 1)})
+		require.NoError(t, err)
+
+		restored := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		require.NoError(t, restored.Restore(orderBook.GetState()))
+		require.Len(t, restored.QueryOrders(1), 1)
+		require.Zero(t, restored.GetSellOrders().Len())
+
+		clk.Advance(2 * time.Hour)
+		restored.RemoveExpiredSellOrders()
+		require.Empty(t, restored.GetOrders())
+	})
+
+	t.Run("Market Stops Need A Limit With Accounts", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithAccounts())
+		require.NoError(t, orderBook.Deposit(1, 1000, 0))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, StopPrice: 95, Quantity: 1, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrNoFunds)
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 96, StopPrice: 95, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Equal(t, uint64(96), orderBook.GetAccount(1).CashReserved)
+	})
+}
+
+// TestOrderBookUCase_IcebergOrders tests orders showing part of their quantity.
+func TestOrderBookUCase_IcebergOrders(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Depth Shows The Displayed Slice", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 50, DisplayQuantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 101, Quantity: 4, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 90, Quantity: 2, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		depth := orderBook.Depth(0)
+		require.Equal(t, []model.PriceLevel{{Price: 90, Quantity: 2, Orders: 1}}, depth.Bids)
+		require.Equal(t, []model.PriceLevel{{Price: 100, Quantity: 8, Orders: 2}, {Price: 101, Quantity: 4, Orders: 1}}, depth.Asks)
+		require.Len(t, orderBook.Depth(1).Asks, 1)
+	})
+
+	t.Run("Depth Carries The Currency", func(t *testing.T) {
+		usd := model.Currency{Code: "USD", Scale: 2}
+		orderBook := module.NewOrderBookUCase(logger, module.WithCurrency(usd))
+		require.Equal(t, usd, orderBook.GetCurrency())
+		require.Equal(t, usd, orderBook.Depth(0).Currency)
+	})
+
+	t.Run("Replenished Slice Loses Time Priority", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		iceberg, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 10, DisplayQuantity: 4, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		clk.Advance(time.Second)
+		other, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		// Taking 6 fills the displayed 4, then moves on to the other order
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 100, Quantity: 6, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, iceberg, trades[0].MakerOrderID)
+		require.Equal(t, uint(4), trades[0].Quantity)
+		require.Equal(t, other, trades[1].MakerOrderID)
+		require.Equal(t, uint(2), trades[1].Quantity)
+
+		order := orderBook.GetOrders()[iceberg]
+		require.Equal(t, uint(6), order.Remaining)
+		require.Equal(t, uint(4), order.Visible)
+		require.Equal(t, clk.Now(), order.Timestamp)
+	})
+
+	t.Run("Iceberg Alone Refills Within One Order", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		iceberg, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 10, DisplayQuantity: 4, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 9, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 3, "Expected a trade per displayed slice")
+
+		order := orderBook.GetOrders()[iceberg]
+		require.Equal(t, uint(1), order.Remaining)
+		require.Equal(t, uint(1), order.Visible)
+	})
+
+	t.Run("Display Above Quantity", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 3, DisplayQuantity: 4, OrderType: constant.SellOrder})
+		require.ErrorIs(t, err, reject.ErrInvalidQuantity)
+	})
+}
+
+// TestOrderBookUCase_PeggedOrders tests orders following the top of the book.
+func TestOrderBookUCase_PeggedOrders(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Starting Price Per Peg", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 105, constant.SellOrder, nil))
+
+		testCases := []struct {
+			peg       constant.PegType
+			offset    int64
+			orderType constant.OrderType
+			price     model.Price
+		}{
+			{constant.PrimaryPeg, 0, constant.BuyOrder, 100},
+			{constant.PrimaryPeg, -2, constant.BuyOrder, 98},
+			{constant.MarketPeg, -1, constant.BuyOrder, 104},
+			{constant.PrimaryPeg, 1, constant.SellOrder, 106},
+			{constant.MidpointPeg, 0, constant.BuyOrder, 102},
+			{constant.MidpointPeg, 0, constant.SellOrder, 103},
+		}
+		for _, tc := range testCases {
+			id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Quantity: 1, OrderType: tc.orderType, Peg: tc.peg, PegOffset: tc.offset})
+			require.NoError(t, err)
+			require.Equal(t, tc.price, orderBook.GetOrders()[id].Price, "Price of a %s %s peg", tc.orderType, tc.peg)
+		}
+		require.Empty(t, orderBook.GetTrades())
+	})
+
+	t.Run("Follows The Best Bid", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		pegged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+
+		// A better bid moves the peg up with it
+		clk.Advance(time.Second)
+		publisher.events = nil
+		improving, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 102, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		order := orderBook.GetOrders()[pegged]
+		require.Equal(t, model.Price(102), order.Price)
+		require.Equal(t, clk.Now(), order.Timestamp)
+		require.Contains(t, publisher.types(), event.OrderRepricedType)
+
+		// Cancelling it moves the peg back down
+		require.NoError(t, orderBook.CancelOrder(3, improving))
+		require.Equal(t, model.Price(100), orderBook.GetOrders()[pegged].Price)
+
+		// An empty side leaves the peg where it is
+		require.NoError(t, orderBook.CancelOrder(1, 1))
+		require.Equal(t, model.Price(100), orderBook.GetOrders()[pegged].Price)
+	})
+
+	t.Run("Limit Caps The Price", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		pegged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 101, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+
+		require.NoError(t, orderBook.SubmitOrder(3, 104, constant.BuyOrder, nil))
+		require.Equal(t, model.Price(101), orderBook.GetOrders()[pegged].Price)
+
+		// Amending a pegged order changes its limit
+		require.NoError(t, orderBook.AmendOrder(2, pegged, 103, nil))
+		order := orderBook.GetOrders()[pegged]
+		require.Equal(t, model.Price(103), order.Price)
+		require.Equal(t, model.Price(103), order.PegLimit)
+	})
+
+	t.Run("Market Peg Follows The Offer Up", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SubmitOrder(1, 105, constant.SellOrder, nil))
+
+		// Without an offset the peg takes the offer on arrival and rests at its price
+		pegged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 110, Quantity: 2, OrderType: constant.BuyOrder, Peg: constant.MarketPeg})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 1)
+		require.Equal(t, model.Price(105), orderBook.GetOrders()[pegged].Price)
+
+		// A new offer is followed and taken by the peg
+		require.NoError(t, orderBook.SubmitOrder(3, 107, constant.SellOrder, nil))
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, pegged, trades[1].TakerOrderID)
+		require.Equal(t, model.Price(107), trades[1].Price)
+		require.NotContains(t, orderBook.GetOrders(), pegged)
+	})
+
+	t.Run("Pegs Moved Together Keep Submission Order", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		first, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+		second, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+
+		// Both pegs move to 101 in the command of the new bid and share its timestamp
+		clk.Advance(time.Second)
+		require.NoError(t, orderBook.SubmitOrder(4, 101, constant.BuyOrder, nil))
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 5, Price: 101, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 3)
+		require.Equal(t, first, trades[0].MakerOrderID)
+		require.Equal(t, second, trades[1].MakerOrderID)
+		require.Equal(t, uint64(4), trades[2].MakerOrderID)
+	})
+
+	t.Run("Reservation At The Limit", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithAccounts())
+		require.NoError(t, orderBook.Deposit(1, 1000, 0))
+		require.NoError(t, orderBook.Deposit(2, 0, 10))
+		require.NoError(t, orderBook.Deposit(3, 1000, 0))
+		require.NoError(t, orderBook.SubmitOrder(3, 95, constant.BuyOrder, nil))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Quantity: 2, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.ErrorIs(t, err, reject.ErrNoFunds, "Expected an uncapped pegged buy to be refused")
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 2, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+		require.Equal(t, uint64(200), orderBook.GetAccount(1).CashReserved)
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 95, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Equal(t, uint64(810), orderBook.GetAccount(1).Cash)
+		requireReservations(t, orderBook, 1, 2, 3)
+	})
+
+	t.Run("Restored Pegs Keep Following", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+		pegged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+
+		restored := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, restored.Restore(orderBook.GetState()))
+		require.NoError(t, restored.SubmitOrder(3, 102, constant.BuyOrder, nil))
+		require.Equal(t, model.Price(102), restored.GetOrders()[pegged].Price)
+
+		// The peg is no longer followed once cancelled
+		require.NoError(t, restored.CancelOrder(2, pegged))
+		require.NoError(t, restored.SubmitOrder(3, 103, constant.BuyOrder, nil))
+		require.NotContains(t, restored.GetOrders(), pegged)
+	})
+
+	t.Run("Reprice Past The Acceptance Checks Cancels", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		checker := risk.NewChecker(risk.Limits{MaxBidNotional: 1000})
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)),
+			module.WithEventBus(publisher), module.WithRiskChecker(checker))
+		require.NoError(t, orderBook.SubmitOrder(1, 95, constant.BuyOrder, nil))
+		pegged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 10, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+
+		// 10 at 101 breaches the bid notional limit of customer 2
+		publisher.events = nil
+		require.NoError(t, orderBook.SubmitOrder(3, 101, constant.BuyOrder, nil))
+		require.NotContains(t, orderBook.GetOrders(), pegged)
+		require.Contains(t, publisher.types(), event.OrderCancelledType)
+		require.NotContains(t, publisher.types(), event.OrderRepricedType)
+
+		// An uncapped peg whose notional would overflow is cancelled too
+		orderBook = module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SubmitOrder(1, 1<<20, constant.BuyOrder, nil))
+		overflowing, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 1 << 40, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.NoError(t, err)
+		require.NoError(t, orderBook.SubmitOrder(3, 1<<25, constant.BuyOrder, nil))
+		require.NotContains(t, orderBook.GetOrders(), overflowing)
+	})
+
+	t.Run("Uncapped Peg Is Risk Checked At Its Starting Price", func(t *testing.T) {
+		checker := risk.NewChecker(risk.Limits{MaxOrderPrice: 150})
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithRiskChecker(checker))
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+
+		// 100 + 60 is above the max order price though the request has no price
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg, PegOffset: 60})
+		require.ErrorIs(t, err, reject.ErrRiskLimit)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg, PegOffset: 40})
+		require.NoError(t, err)
+	})
+
+	t.Run("Invalid Peg", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.ErrorIs(t, err, reject.ErrInvalidPrice, "Expected no reference price")
+
+		require.NoError(t, orderBook.SubmitOrder(2, 100, constant.BuyOrder, nil))
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Quantity: 1, OrderType: constant.BuyOrder, Peg: "last"})
+		require.ErrorIs(t, err, reject.ErrInvalidPrice)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, StopPrice: 90, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg})
+		require.ErrorIs(t, err, reject.ErrInvalidPrice)
+	})
+}
+
+// TestOrderBookUCase_FillConstraints tests all-or-none and minimum quantity orders.
+func TestOrderBookUCase_FillConstraints(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("All Or None Taker Waits For Enough Quantity", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 20, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		// 30 copies cannot be filled from 20, nothing trades and the bid rests
+		clk.Advance(time.Second)
+		bid, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 30, AllOrNone: true, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Empty(t, orderBook.GetTrades())
+		require.Equal(t, uint(30), orderBook.GetOrders()[bid].Remaining)
+
+		// Once another 10 arrive the whole order fills in one go
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 100, Quantity: 10, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, uint(20), trades[0].Quantity)
+		require.Equal(t, uint(10), trades[1].Quantity)
+		require.NotContains(t, orderBook.GetOrders(), bid)
+		require.Empty(t, orderBook.GetSellOrders().Orders)
+	})
+
+	t.Run("All Or None Maker Is Skipped Without Blocking", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		aon, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 30, AllOrNone: true, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		clk.Advance(time.Second)
+		other, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 101, Quantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+
+		// A bid for 5 passes over the all-or-none ask to the next one
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 101, Quantity: 5, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 1)
+		require.Equal(t, other, trades[0].MakerOrderID)
+
+		// The all-or-none ask kept its place and fills against a large enough bid
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 100, Quantity: 30, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		trades = orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, aon, trades[1].MakerOrderID)
+		require.Equal(t, uint(30), trades[1].Quantity)
+	})
+
+	t.Run("Minimum Quantity Applies To Every Match", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		bid, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 30, MinQuantity: 10, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 4, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Empty(t, orderBook.GetTrades(), "Expected 4 to be too small")
+
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 100, Quantity: 12, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 1)
+		require.Equal(t, uint(12), trades[0].Quantity)
+		require.Equal(t, uint(18), orderBook.GetOrders()[bid].Remaining)
+
+		// The retried bid now takes the 4 together with 6 more
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 100, Quantity: 6, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 3)
+		order := orderBook.GetOrders()[bid]
+		require.Equal(t, uint(8), order.Remaining)
+		require.Equal(t, startTime, order.Timestamp, "Expected the retried bid to keep its time priority")
+	})
+
+	t.Run("All Or None Market Order Is Cancelled", func(t *testing.T) {
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithEventBus(publisher))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, StopPrice: 100, Quantity: 10, AllOrNone: true, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		// The stop market order triggers with 4 left, too few to fill it
+		require.NoError(t, orderBook.SubmitOrder(2, 100, constant.BuyOrder, nil))
+		require.Len(t, orderBook.GetTrades(), 1)
+		require.Contains(t, publisher.types(), event.OrderCancelledType)
+		require.Len(t, orderBook.GetOrders(), 1)
+	})
+
+	t.Run("Restored Constrained Order Is Retried", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		bid, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 8, AllOrNone: true, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		restored := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, restored.Restore(orderBook.GetState()))
+
+		// Another bid does not help, three more copies on offer do
+		require.NoError(t, restored.SubmitOrder(3, 99, constant.BuyOrder, nil))
+		require.Empty(t, restored.GetTrades())
+		_, err = restored.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 100, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		require.Len(t, restored.GetTrades(), 2)
+		require.NotContains(t, restored.GetOrders(), bid)
+	})
+
+	t.Run("Invalid Constraints", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, MinQuantity: 6, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrInvalidQuantity)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, DisplayQuantity: 1, AllOrNone: true, OrderType: constant.BuyOrder})
+		require.ErrorIs(t, err, reject.ErrInvalidQuantity)
+	})
+}
+
+// TestOrderBookUCase_SessionExpiry tests day and good-till-date orders.
+func TestOrderBookUCase_SessionExpiry(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	cal, err := calendar.New(calendar.Config{Timezone: "America/New_York", Open: "09:30", Close: "16:00"})
+	require.NoError(t, err)
+
+	// The manual clock starts at 06:00 in New York, before the session
+	todayClose := time.Date(2024, 6, 1, 20, 0, 0, 0, time.UTC)
+
+	t.Run("Day Order Expires At Session Close", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithCalendar(cal), module.WithEventBus(publisher))
+		id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: constant.GoodForDay})
+		require.NoError(t, err)
+		require.True(t, todayClose.Equal(*orderBook.GetOrders()[id].GTT))
+
+		// Still resting at the close, gone right after it
+		clk.Set(todayClose)
+		orderBook.RemoveExpiredBuyOrders()
+		require.Contains(t, orderBook.GetOrders(), id)
+		clk.Advance(time.Second)
+		orderBook.RemoveExpiredBuyOrders()
+		require.NotContains(t, orderBook.GetOrders(), id)
+		require.Contains(t, publisher.types(), event.OrderExpiredType)
+	})
+
+	t.Run("Day Order At The Close Is Good For The Next Session", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(todayClose)), module.WithCalendar(cal))
+		id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: constant.GoodForDay})
+		require.NoError(t, err)
+		require.True(t, todayClose.AddDate(0, 0, 1).Equal(*orderBook.GetOrders()[id].GTT))
+	})
+
+	t.Run("Good Till Date On A Day Without A Session", func(t *testing.T) {
+		weekdays := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday"}
+		exchange, err := calendar.New(calendar.Config{Timezone: "America/New_York", Open: "09:30", Close: "16:00", Weekdays: weekdays, Holidays: []string{"2024-12-25"}})
+		require.NoError(t, err)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithCalendar(exchange))
+
+		for _, date := range []string{"2024-12-25", "2024-12-21"} {
+			_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.SellOrder,
+				TimeInForce: constant.GoodTillDate, ExpireDate: date})
+			require.ErrorIs(t, err, reject.ErrInvalidExpiry, "Expected %s to be refused", date)
+		}
+	})
+
+	t.Run("Day Order After Close Is Good For The Next Session", func(t *testing.T) {
+		clk := clock.NewManual(todayClose.Add(time.Hour))
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithCalendar(cal))
+		id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: constant.GoodForDay})
+		require.NoError(t, err)
+		require.True(t, todayClose.AddDate(0, 0, 1).Equal(*orderBook.GetOrders()[id].GTT))
+	})
+
+	t.Run("Good Till Date In The Calendar Timezone", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithCalendar(cal))
+		id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.SellOrder,
+			TimeInForce: constant.GoodTillDate, ExpireDate: "2024-12-20"})
+		require.NoError(t, err)
+		require.True(t, time.Date(2024, 12, 20, 21, 0, 0, 0, time.UTC).Equal(*orderBook.GetOrders()[id].GTT), "Expected 16:00 EST")
+
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.SellOrder,
+			TimeInForce: constant.GoodTillDate, ExpireDate: "2024-05-31"})
+		require.ErrorIs(t, err, reject.ErrExpiredOnArrival)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.SellOrder,
+			TimeInForce: constant.GoodTillDate})
+		require.ErrorIs(t, err, reject.ErrInvalidExpiry)
+	})
+
+	t.Run("Amend Keeps The Session Close", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)), module.WithCalendar(cal))
+		id, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: constant.GoodForDay})
+		require.NoError(t, err)
+		require.NoError(t, orderBook.AmendOrder(1, id, 101, nil))
+		require.True(t, todayClose.Equal(*orderBook.GetOrders()[id].GTT))
+	})
+
+	t.Run("Invalid Time In Force", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: constant.GoodForDay})
+		require.ErrorIs(t, err, reject.ErrInvalidExpiry, "Expected day orders to need a calendar")
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: "ioc"})
+		require.ErrorIs(t, err, reject.ErrInvalidExpiry)
+	})
+}
+
+// TestOrderBookUCase_MarketPhases tests the commands accepted in each market phase.
+func TestOrderBookUCase_MarketPhases(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Commands Accepted Per Phase", func(t *testing.T) {
+		testCases := []struct {
+			phases []constant.MarketPhase // Path from continuous
+			submit error
+			cancel error
+		}{
+			{[]constant.MarketPhase{constant.ClosedPhase}, reject.ErrMarketClosed, reject.ErrMarketClosed},
+			{[]constant.MarketPhase{constant.ClosedPhase, constant.PreOpenPhase}, nil, nil},
+			{[]constant.MarketPhase{constant.ClosedPhase, constant.OpeningAuctionPhase}, reject.ErrMarketClosed, reject.ErrMarketClosed},
+			{[]constant.MarketPhase{}, nil, nil},
+			{[]constant.MarketPhase{constant.HaltedPhase}, reject.ErrBookHalted, nil},
+			{[]constant.MarketPhase{constant.ClosingPhase}, reject.ErrMarketClosed, nil},
+		}
+		for _, tc := range testCases {
+			orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+			require.NoError(t, orderBook.SubmitOrder(1, 100, constant.BuyOrder, nil))
+			for _, phase := range tc.phases {
+				require.NoError(t, orderBook.SetPhase(phase, "ops"))
+			}
+			phase := orderBook.GetPhase()
+
+			err := orderBook.SubmitOrder(2, 90, constant.BuyOrder, nil)
+			if tc.submit == nil {
+				require.NoError(t, err, "Submit in %s", phase)
+			} else {
+				require.ErrorIs(t, err, tc.submit, "Submit in %s", phase)
+				require.ErrorIs(t, orderBook.AmendOrder(1, 1, 99, nil), tc.submit, "Amend in %s", phase)
+			}
+
+			err = orderBook.CancelOrder(1, 1)
+			if tc.cancel == nil {
+				require.NoError(t, err, "Cancel in %s", phase)
+			} else {
+				require.ErrorIs(t, err, tc.cancel, "Cancel in %s", phase)
+				require.NoError(t, orderBook.AdminCancelOrder(1, "ops"), "Admin cancel in %s", phase)
+			}
+		}
+	})
+
+	t.Run("Pre Open Orders Match When Trading Starts", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher))
+		require.NoError(t, orderBook.SetPhase(constant.ClosedPhase, "ops"))
+		require.NoError(t, orderBook.SetPhase(constant.PreOpenPhase, "ops"))
+
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 5, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 102, Quantity: 3, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Empty(t, orderBook.GetTrades(), "Expected no matching before the open")
+
+		clk.Advance(time.Second)
+		require.NoError(t, orderBook.SetPhase(constant.ContinuousPhase, "ops"))
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 1)
+		require.Equal(t, uint(3), trades[0].Quantity)
+		require.Contains(t, publisher.types(), event.PhaseChangedType)
+	})
+
+	t.Run("Invalid Transition", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.ErrorIs(t, orderBook.SetPhase(constant.PreOpenPhase, "ops"), reject.ErrInvalidPhase)
+		require.ErrorIs(t, orderBook.SetPhase("lunch", "ops"), reject.ErrInvalidPhase)
+		require.Error(t, orderBook.SetPhase(constant.HaltedPhase, ""))
+		require.Equal(t, constant.ContinuousPhase, orderBook.GetPhase())
+	})
+
+	t.Run("Phase Survives A Snapshot", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, orderBook.SetPhase(constant.HaltedPhase, "ops"))
+
+		restored := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.NoError(t, restored.Restore(orderBook.GetState()))
+		require.Equal(t, constant.HaltedPhase, restored.GetPhase())
+	})
+}
+
+// TestOrderBookUCase_CallAuction tests uncrossing the orders collected during a call period.
+func TestOrderBookUCase_CallAuction(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	// callBook returns a book collecting orders in the pre-open phase.
+	callBook := func(clk *clock.Manual, opts ...module.Option) interfaces.OrderBookUCase {
+		orderBook := module.NewOrderBookUCase(logger, append(opts, module.WithClock(clk))...)
+		require.NoError(t, orderBook.SetPhase(constant.ClosedPhase, "ops"))
+		require.NoError(t, orderBook.SetPhase(constant.PreOpenPhase, "ops"))
+		return orderBook
+	}
+	place := func(clk *clock.Manual, orderBook interfaces.OrderBookUCase, customerID uint, orderType constant.OrderType, price model.Price, quantity uint) {
+		clk.Advance(time.Second)
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerID, Price: price, Quantity: quantity, OrderType: orderType})
+		require.NoError(t, err)
+	}
+
+	t.Run("Orders Execute At One Clearing Price", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := callBook(clk, module.WithEventBus(publisher))
+		place(clk, orderBook, 1, constant.SellOrder, 100, 5)
+		place(clk, orderBook, 2, constant.SellOrder, 101, 5)
+		place(clk, orderBook, 3, constant.BuyOrder, 102, 4)
+		place(clk, orderBook, 4, constant.BuyOrder, 101, 4)
+		require.Empty(t, orderBook.GetTrades())
+
+		clk.Advance(time.Second)
+		require.NoError(t, orderBook.SetPhase(constant.ContinuousPhase, "ops"))
+		var volume uint
+		for _, trade := range orderBook.GetTrades() {
+			require.Equal(t, model.Price(101), trade.Price)
+			volume += trade.Quantity
+		}
+		require.Equal(t, uint(8), volume)
+		require.Contains(t, publisher.types(), event.AuctionUncrossedType)
+
+		orders := orderBook.GetOrders()
+		require.Len(t, orders, 1)
+		require.Equal(t, uint(2), orders[2].Remaining)
+	})
+
+	t.Run("Indicative Price During The Call", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := callBook(clk, module.WithEventBus(publisher))
+		lastIndicative := func() model.Auction {
+			for i := len(publisher.events) - 1; i >= 0; i-- {
+				if indicative, ok := publisher.events[i].(event.AuctionIndicative); ok {
+					return indicative.Auction
+				}
+			}
+			t.Fatal("Expected an indicative auction event")
+			return model.Auction{}
+		}
+
+		place(clk, orderBook, 1, constant.SellOrder, 100, 5)
+		place(clk, orderBook, 2, constant.SellOrder, 101, 5)
+		require.Equal(t, model.Auction{}, lastIndicative())
+
+		place(clk, orderBook, 3, constant.BuyOrder, 102, 4)
+		expected := model.Auction{Price: 100, Volume: 4, AskSurplus: 1}
+		require.Equal(t, expected, lastIndicative())
+		require.Equal(t, expected, orderBook.IndicativeAuction())
+
+		require.NoError(t, orderBook.SetPhase(constant.ContinuousPhase, "ops"))
+		published := len(publisher.events)
+		place(clk, orderBook, 4, constant.BuyOrder, 90, 1)
+		for _, e := range publisher.events[published:] {
+			require.NotEqual(t, event.AuctionIndicativeType, e.EventType(), "Expected no indicative price in continuous trading")
+		}
+	})
+
+	t.Run("Periodic Uncross Stays In The Call", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := callBook(clk)
+		place(clk, orderBook, 1, constant.SellOrder, 100, 5)
+		place(clk, orderBook, 2, constant.BuyOrder, 100, 3)
+
+		result, err := orderBook.Uncross("ops")
+		require.NoError(t, err)
+		require.Equal(t, model.Auction{Price: 100, Volume: 3, AskSurplus: 2}, result)
+		require.Equal(t, constant.PreOpenPhase, orderBook.GetPhase())
+
+		place(clk, orderBook, 3, constant.BuyOrder, 101, 1)
+		require.Len(t, orderBook.GetTrades(), 1, "Expected orders to keep resting after the uncross")
+
+		_, err = orderBook.Uncross("")
+		require.Error(t, err)
+		require.NoError(t, orderBook.SetPhase(constant.ContinuousPhase, "ops"))
+		_, err = orderBook.Uncross("ops")
+		require.ErrorIs(t, err, reject.ErrMarketClosed)
+	})
+
+	t.Run("Buyers Pay The Clearing Price", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := callBook(clk, module.WithAccounts())
+		require.NoError(t, orderBook.Deposit(1, 0, 5))
+		require.NoError(t, orderBook.Deposit(2, 1000, 0))
+		place(clk, orderBook, 1, constant.SellOrder, 100, 5)
+		place(clk, orderBook, 2, constant.BuyOrder, 102, 5)
+
+		_, err := orderBook.Uncross("ops")
+		require.NoError(t, err)
+		require.Equal(t, uint64(500), orderBook.GetAccount(2).Cash)
+		require.Equal(t, uint64(500), orderBook.GetAccount(1).Cash)
+		requireReservations(t, orderBook, 1, 2)
+	})
+
+	t.Run("Self Trades Leave The Executed Volume", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := callBook(clk, module.WithEventBus(publisher))
+		place(clk, orderBook, 1, constant.SellOrder, 100, 10)
+		place(clk, orderBook, 2, constant.SellOrder, 100, 5)
+		place(clk, orderBook, 1, constant.BuyOrder, 100, 10)
+
+		// Only the other customer's 5 trade with the buyer
+		expected := model.Auction{Price: 100, Volume: 5, BidSurplus: 5, AskSurplus: 10}
+		result, err := orderBook.Uncross("ops")
+		require.NoError(t, err)
+		require.Equal(t, expected, result)
+		require.Len(t, orderBook.GetTrades(), 1)
+		for _, e := range publisher.events {
+			if uncrossed, ok := e.(event.AuctionUncrossed); ok {
+				require.Equal(t, expected, uncrossed.Auction)
+			}
+		}
+	})
+
+	t.Run("Stops Wait For Continuous Trading", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		orderBook := callBook(clk)
+		place(clk, orderBook, 1, constant.SellOrder, 100, 5)
+		place(clk, orderBook, 2, constant.BuyOrder, 100, 5)
+		place(clk, orderBook, 3, constant.SellOrder, 101, 1)
+		stop, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, StopPrice: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		// The uncross reaches the stop price, but a market order cannot
+		// trade in the call
+		_, err = orderBook.Uncross("ops")
+		require.NoError(t, err)
+		require.True(t, orderBook.GetOrders()[stop].IsPendingStop())
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 5, StopPrice: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Len(t, orderBook.GetTrades(), 1)
+
+		// Both trigger and trade once matching resumes
+		require.NoError(t, orderBook.SetPhase(constant.ContinuousPhase, "ops"))
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 2)
+		require.Equal(t, model.Price(101), trades[1].Price)
+		require.Equal(t, stop, trades[1].TakerOrderID)
+		require.Len(t, orderBook.GetOrders(), 0, "Expected the second stop to be cancelled unfilled in continuous trading")
+	})
+}
+
+// TestOrderBookUCase_TradingHalts tests manual halts, the circuit breaker and the reopening auction.
+func TestOrderBookUCase_TradingHalts(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	place := func(clk *clock.Manual, orderBook interfaces.OrderBookUCase, customerID uint, orderType constant.OrderType, price model.Price, quantity uint) error {
+		clk.Advance(time.Second)
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerID, Price: price, Quantity: quantity, OrderType: orderType})
+		return err
+	}
+
+	t.Run("Manual Halt Rejects New Orders", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher))
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 100, 5))
+
+		require.NoError(t, orderBook.Halt("pending news", "ops"))
+		require.Equal(t, constant.HaltedPhase, orderBook.GetPhase())
+		require.Nil(t, orderBook.GetHaltedUntil())
+		require.ErrorIs(t, place(clk, orderBook, 2, constant.BuyOrder, 100, 1), reject.ErrBookHalted)
+		require.NoError(t, orderBook.CancelOrder(1, 1))
+
+		require.NoError(t, orderBook.Resume("ops"))
+		require.Equal(t, constant.ContinuousPhase, orderBook.GetPhase())
+		require.Contains(t, publisher.types(), event.TradingHaltedType)
+		require.Contains(t, publisher.types(), event.TradingResumedType)
+	})
+
+	t.Run("Invalid Halt And Resume", func(t *testing.T) {
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(startTime)))
+		require.ErrorIs(t, orderBook.Resume("ops"), reject.ErrInvalidPhase)
+		require.Error(t, orderBook.Halt("news", ""))
+		require.NoError(t, orderBook.SetPhase(constant.ClosedPhase, "ops"))
+		require.ErrorIs(t, orderBook.Halt("news", "ops"), reject.ErrInvalidPhase)
+	})
+
+	t.Run("Circuit Breaker Halts Mid Match", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		publisher := &recordingPublisher{}
+		cb, err := breaker.New(breaker.Config{MoveBps: 1000, Window: "1m", Halt: "5m"})
+		require.NoError(t, err)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithEventBus(publisher), module.WithCircuitBreaker(cb))
+
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 100, 1))
+		require.NoError(t, place(clk, orderBook, 2, constant.SellOrder, 105, 1))
+		require.NoError(t, place(clk, orderBook, 3, constant.SellOrder, 120, 1))
+		require.NoError(t, place(clk, orderBook, 6, constant.SellOrder, 121, 2))
+		require.NoError(t, place(clk, orderBook, 4, constant.BuyOrder, 100, 1))
+
+		// The trade at 120 is 20% above the trade at 100, the rest of the buy rests crossed
+		require.NoError(t, place(clk, orderBook, 5, constant.BuyOrder, 125, 4))
+		require.Equal(t, constant.HaltedPhase, orderBook.GetPhase())
+		require.Len(t, orderBook.GetTrades(), 3)
+		require.Equal(t, model.Price(120), orderBook.GetTrades()[2].Price)
+		until := orderBook.GetHaltedUntil()
+		require.NotNil(t, until)
+		require.True(t, clk.Now().Add(5*time.Minute).Equal(*until))
+
+		var halted event.TradingHalted
+		for _, e := range publisher.events {
+			if e, ok := e.(event.TradingHalted); ok {
+				halted = e
+			}
+		}
+		require.Equal(t, constant.CircuitBreakerOperator, halted.Operator)
+		require.ErrorIs(t, place(clk, orderBook, 7, constant.BuyOrder, 125, 1), reject.ErrBookHalted)
+
+		// The reopening auction uncrosses at the price closest to the last trade
+		clk.Advance(5 * time.Minute)
+		require.NoError(t, orderBook.Resume(constant.CircuitBreakerOperator))
+		require.Nil(t, orderBook.GetHaltedUntil())
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 4)
+		require.Equal(t, model.Price(121), trades[3].Price)
+		require.Equal(t, uint(2), trades[3].Quantity)
+
+		// The reopening price starts a new window
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 122, 1))
+		require.NoError(t, place(clk, orderBook, 4, constant.BuyOrder, 122, 1))
+		require.Equal(t, constant.ContinuousPhase, orderBook.GetPhase())
+	})
+
+	t.Run("Stops Wait For The Resumption", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		cb, err := breaker.New(breaker.Config{MoveBps: 1000, Window: "1m"})
+		require.NoError(t, err)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithCircuitBreaker(cb))
+
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 100, 1))
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 120, 1))
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 130, 1))
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, StopPrice: 110, Price: 130, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.NoError(t, place(clk, orderBook, 3, constant.BuyOrder, 100, 1))
+		require.NoError(t, place(clk, orderBook, 3, constant.BuyOrder, 120, 1))
+		require.Equal(t, constant.HaltedPhase, orderBook.GetPhase())
+		require.Len(t, orderBook.GetTrades(), 2, "Expected the stop to wait during the halt")
+
+		require.NoError(t, orderBook.Resume("ops"))
+		trades := orderBook.GetTrades()
+		require.Len(t, trades, 3)
+		require.Equal(t, model.Price(130), trades[2].Price)
+	})
+
+	t.Run("Halt Survives A Snapshot", func(t *testing.T) {
+		clk := clock.NewManual(startTime)
+		cb, err := breaker.New(breaker.Config{MoveBps: 1000, Window: "1m", Halt: "5m"})
+		require.NoError(t, err)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithCircuitBreaker(cb))
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 100, 1))
+		require.NoError(t, place(clk, orderBook, 1, constant.SellOrder, 120, 1))
+		require.NoError(t, place(clk, orderBook, 2, constant.BuyOrder, 120, 2))
+
+		restored := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		require.NoError(t, restored.Restore(orderBook.GetState()))
+		require.Equal(t, constant.HaltedPhase, restored.GetPhase())
+		require.Equal(t, orderBook.GetHaltedUntil(), restored.GetHaltedUntil())
+	})
+}
+
+// TestOrderBookUCase_Allocation tests sharing an incoming order between a price level with each algorithm.
+func TestOrderBookUCase_Allocation(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	// allocationBook returns a book with three sells of 10, 30 and 60 at 100
+	// with the given constraint on the second one.
+	allocationBook := func(algorithm constant.Allocation, allOrNone bool) (interfaces.OrderBookUCase, *clock.Manual) {
+		allocator, err := allocation.New(algorithm)
+		require.NoError(t, err)
+		clk := clock.NewManual(startTime)
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk), module.WithAllocator(allocator))
+		for i, quantity := range []uint{10, 30, 60} {
+			clk.Advance(time.Second)
+			_, err := orderBook.PlaceOrder(model.OrderRequest{
+				CustomerID: uint(i + 1), Price: 100, Quantity: quantity, OrderType: constant.SellOrder, AllOrNone: allOrNone && i == 1,
+			})
+			require.NoError(t, err)
+		}
+		return orderBook, clk
+	}
+	// filled returns the quantity filled of each of the three sells
+	filled := func(orderBook interfaces.OrderBookUCase) []uint {
+		fills := make([]uint, orderBook.GetNextOrderID()-1)
+		for _, trade := range orderBook.GetTrades() {
+			fills[trade.MakerOrderID-1] += trade.Quantity
+		}
+		return fills[:3]
+	}
+
+	testCases := []struct {
+		algorithm constant.Allocation
+		expected  []uint
+	}{
+		{constant.FIFOAllocation, []uint{10, 30, 10}},
+		{constant.ProRataAllocation, []uint{5, 15, 30}},
+		{constant.ProRataTopAllocation, []uint{10, 14, 26}},
+		{constant.SizeTimeAllocation, []uint{0, 0, 50}},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.algorithm), func(t *testing.T) {
+			orderBook, clk := allocationBook(tc.algorithm, false)
+			clk.Advance(time.Second)
+			_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 100, Quantity: 50, OrderType: constant.BuyOrder})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, filled(orderBook))
+			for _, trade := range orderBook.GetTrades() {
+				require.Equal(t, model.Price(100), trade.Price)
+			}
+		})
+	}
+
+	t.Run("Constrained Order Left Out Of The Shares", func(t *testing.T) {
+		orderBook, clk := allocationBook(constant.ProRataAllocation, true)
+		clk.Advance(time.Second)
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 100, Quantity: 50, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Equal(t, []uint{8, 0, 42}, filled(orderBook))
+		require.Equal(t, uint(30), orderBook.GetOrders()[2].Remaining)
+	})
+
+	t.Run("Order Spanning Several Levels", func(t *testing.T) {
+		orderBook, clk := allocationBook(constant.ProRataAllocation, false)
+		clk.Advance(time.Second)
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 5, Price: 101, Quantity: 4, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		clk.Advance(time.Second)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 4, Price: 101, Quantity: 102, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		require.Equal(t, []uint{10, 30, 60}, filled(orderBook))
+		trades := orderBook.GetTrades()
+		require.Equal(t, model.Price(101), trades[len(trades)-1].Price)
+		require.Equal(t, uint(2), trades[len(trades)-1].Quantity)
+	})
+}
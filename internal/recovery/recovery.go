@@ -0,0 +1,163 @@
+package recovery
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/clock"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/journal"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/replay"
+	"github.com/trungnt1811/simple-order-book/internal/snapshot"
+)
+
+// ErrDiverged is returned when replaying the journal does not reproduce the
+// commands that were originally journaled.
+var ErrDiverged = errors.New("journal replay diverged")
+
+// Config configures where the durable state lives.
+type Config struct {
+	Journal     journal.Config
+	SnapshotDir string
+	Clock       interfaces.Clock        // Clock used once recovery is complete, defaults to the wall clock
+	Risk        interfaces.RiskChecker  // Risk checks of live commands, skipped for journaled ones but not their repriced pegs
+	Rules       interfaces.TradingRules // Price rules of live commands, skipped for journaled ones but not their repriced pegs
+}
+
+// Recover rebuilds the order book from the latest snapshot and the journal
+// entries after it, then returns the book attached to the opened journal so
+// new commands continue the same sequence. It refuses to recover from a
+// snapshot or journal that fails its checksums. Extra options are applied to
+// the recovered book; an event bus passed this way also receives the events
+// of the replayed commands.
+func Recover(cfg Config, logger *zap.Logger, opts ...module.Option) (interfaces.OrderBookUCase, *journal.Journal, error) {
+	state, err := snapshot.LoadLatest(cfg.SnapshotDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	j, err := journal.Open(cfg.Journal, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	replayClock := clock.NewReplay(cfg.Clock)
+	rj := &replayJournal{journal: j, replaying: true}
+	opts = append(opts, module.WithClock(replayClock), module.WithJournal(rj))
+	if cfg.Risk != nil {
+		// Journaled commands passed the limits in force when they were accepted
+		opts = append(opts, module.WithRiskChecker(&replayRiskChecker{risk: cfg.Risk, journal: rj}))
+	}
+	if cfg.Rules != nil {
+		opts = append(opts, module.WithTradingRules(&replayTradingRules{rules: cfg.Rules, journal: rj}))
+	}
+	orderBook := module.NewOrderBookUCase(logger, opts...)
+
+	var afterSeq uint64
+	if state != nil {
+		if err := orderBook.Restore(*state); err != nil {
+			j.Close()
+			return nil, nil, err
+		}
+		afterSeq = state.Seq
+	}
+
+	// Replay the journal on top of the snapshot
+	replayed := 0
+	err = j.Read(afterSeq, func(cmd model.Command) error {
+		replayClock.Set(cmd.Timestamp)
+		rj.expected = &cmd
+		if err := replay.Apply(orderBook, cmd); err != nil {
+			return fmt.Errorf("%w: seq %d: %v", ErrDiverged, cmd.Seq, err)
+		}
+		if rj.expected != nil {
+			return fmt.Errorf("%w: seq %d: %s command was not reissued", ErrDiverged, cmd.Seq, cmd.Action)
+		}
+		replayed++
+		return nil
+	})
+	if err != nil {
+		j.Close()
+		return nil, nil, err
+	}
+
+	// Switch to live operation
+	replayClock.GoLive()
+	rj.replaying = false
+
+	logger.Info("Order book recovered",
+		zap.Uint64("snapshotSeq", afterSeq),
+		zap.Int("replayed", replayed),
+		zap.Uint64("lastSeq", j.LastSeq()),
+	)
+	return orderBook, j, nil
+}
+
+// replayJournal sits between the order book and the journal. While replaying
+// it hands back the sequence number of the journaled command instead of
+// writing it again, and checks the book reissued the same command.
+type replayJournal struct {
+	journal   *journal.Journal
+	expected  *model.Command
+	replaying bool
+}
+
+func (r *replayJournal) Append(cmd *model.Command) error {
+	if !r.replaying {
+		return r.journal.Append(cmd)
+	}
+
+	if r.expected == nil {
+		return fmt.Errorf("%w: unexpected %s command", ErrDiverged, cmd.Action)
+	}
+	if cmd.Action != r.expected.Action || !cmd.Timestamp.Equal(r.expected.Timestamp) {
+		return fmt.Errorf("%w: seq %d: expected %s command, got %s", ErrDiverged, r.expected.Seq, r.expected.Action, cmd.Action)
+	}
+	cmd.Seq = r.expected.Seq
+	r.expected = nil
+	return nil
+}
+
+// replayRiskChecker skips risk checks while the journal is replayed, except
+// for the checks of pegs repriced once a command is journaled.
+type replayRiskChecker struct {
+	risk    interfaces.RiskChecker
+	journal *replayJournal
+}
+
+func (r *replayRiskChecker) Check(req model.OrderRequest, exposure model.RiskExposure) error {
+	if r.journal.replaying && r.journal.expected != nil {
+		return nil
+	}
+	return r.risk.Check(req, exposure)
+}
+
+// replayTradingRules skips price rules while the journal is replayed, except
+// for the prices the book derives once a command is journaled, such as pegs
+// repriced by it.
+type replayTradingRules struct {
+	rules   interfaces.TradingRules
+	journal *replayJournal
+}
+
+func (r *replayTradingRules) CheckPrice(price, lastTradePrice model.Price) error {
+	if r.journal.replaying && r.journal.expected != nil {
+		return nil
+	}
+	return r.rules.CheckPrice(price, lastTradePrice)
+}
+
+func (r *replayTradingRules) CheckStopPrice(price model.Price) error {
+	if r.journal.replaying && r.journal.expected != nil {
+		return nil
+	}
+	return r.rules.CheckStopPrice(price)
+}
+
+func (r *replayTradingRules) RoundToTick(price model.Price, up bool) model.Price {
+	return r.rules.RoundToTick(price, up)
+}
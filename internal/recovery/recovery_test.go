@@ -0,0 +1,323 @@
+package recovery_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/breaker"
+	"github.com/trungnt1811/simple-order-book/internal/calendar"
+	"github.com/trungnt1811/simple-order-book/internal/clock"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/instrument"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/journal"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/recovery"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+	"github.com/trungnt1811/simple-order-book/internal/risk"
+	"github.com/trungnt1811/simple-order-book/internal/snapshot"
+	"github.com/trungnt1811/simple-order-book/internal/util"
+)
+
+// config returns a recovery config rooted in dir, running on clk once recovered.
+func config(dir string, clk *clock.Manual) recovery.Config {
+	return recovery.Config{
+		Journal:     journal.Config{Dir: filepath.Join(dir, "journal"), SegmentSize: 512},
+		SnapshotDir: filepath.Join(dir, "snapshots"),
+		Clock:       clk,
+	}
+}
+
+// gttIn returns a GTT d after the clock's current time.
+func gttIn(clk *clock.Manual, d time.Duration) *time.Time {
+	gtt := clk.Now().Add(d)
+	return &gtt
+}
+
+// stateJSON encodes the book state for comparison.
+func stateJSON(t *testing.T, orderBook interfaces.OrderBookUCase) string {
+	data, err := json.Marshal(orderBook.GetState())
+	require.NoError(t, err)
+	return string(data)
+}
+
+// submitCommands drives a mix of commands through the book.
+func submitCommands(t *testing.T, orderBook interfaces.OrderBookUCase, clk *clock.Manual, customerOffset uint) {
+	first := orderBook.GetNextOrderID()
+	require.NoError(t, orderBook.SubmitOrder(customerOffset+1, 100, constant.SellOrder, gttIn(clk, time.Hour)))
+	require.NoError(t, orderBook.SubmitOrder(customerOffset+2, 100, constant.SellOrder, nil))
+	require.NoError(t, orderBook.SubmitOrder(customerOffset+3, 95, constant.BuyOrder, nil))
+	require.NoError(t, orderBook.SubmitOrder(customerOffset+4, 100, constant.BuyOrder, gttIn(clk, 2*time.Hour)))
+	require.NoError(t, orderBook.AmendOrder(customerOffset+3, first+2, 97, nil))
+	require.NoError(t, orderBook.SubmitOrder(customerOffset+5, 90, constant.BuyOrder, nil))
+	require.NoError(t, orderBook.CancelOrder(customerOffset+5, first+4))
+	_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 6, StopPrice: 90, Quantity: 2, OrderType: constant.SellOrder})
+	require.NoError(t, err)
+	_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 7, Price: 120, Quantity: 10, DisplayQuantity: 2, OrderType: constant.SellOrder})
+	require.NoError(t, err)
+	_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 8, Quantity: 1, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg, PegOffset: -1})
+	require.NoError(t, err)
+	_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 9, Price: 121, Quantity: 3, AllOrNone: true, OrderType: constant.BuyOrder})
+	require.NoError(t, err)
+
+	// Orders entered before the open are uncrossed in call auctions
+	require.NoError(t, orderBook.SetPhase(constant.ClosedPhase, "ops"))
+	require.NoError(t, orderBook.SetPhase(constant.PreOpenPhase, "ops"))
+	_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 10, Price: 110, Quantity: 2, OrderType: constant.SellOrder})
+	require.NoError(t, err)
+	_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 11, Price: 115, Quantity: 1, OrderType: constant.BuyOrder})
+	require.NoError(t, err)
+	_, err = orderBook.Uncross("ops")
+	require.NoError(t, err)
+	_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerOffset + 12, Price: 112, Quantity: 1, OrderType: constant.BuyOrder})
+	require.NoError(t, err)
+	require.NoError(t, orderBook.SetPhase(constant.ContinuousPhase, "ops"))
+
+	// A halted book reopens with an auction
+	require.NoError(t, orderBook.Halt("pending news", "ops"))
+	require.NoError(t, orderBook.Resume("ops"))
+}
+
+// TestRecover tests restoring the order book after a restart.
+func TestRecover(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Replay journal only", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		orderBook, j, err := recovery.Recover(config(dir, clk), logger)
+		require.NoError(t, err)
+		submitCommands(t, orderBook, clk, 0)
+
+		// Expire an order so the sweep is journaled too
+		require.NoError(t, orderBook.SubmitOrder(50, 80, constant.BuyOrder, gttIn(clk, time.Minute)))
+		clk.Advance(2 * time.Minute)
+		orderBook.RemoveExpiredBuyOrders()
+
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		recovered, j, err := recovery.Recover(config(dir, clk), logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+
+		// New commands continue the sequence
+		require.NoError(t, recovered.SubmitOrder(60, 120, constant.SellOrder, nil))
+		require.Equal(t, recovered.GetState().Seq, j.LastSeq())
+	})
+
+	t.Run("Snapshot and journal tail", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		submitCommands(t, orderBook, clk, 0)
+
+		// Snapshot, then compact the covered journal segments
+		state := orderBook.GetState()
+		_, err = snapshot.Save(cfg.SnapshotDir, state)
+		require.NoError(t, err)
+		require.NoError(t, j.Compact(state.Seq))
+
+		submitCommands(t, orderBook, clk, 10)
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		recovered, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+	})
+
+	t.Run("Time priority survives recovery", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.SellOrder, nil))
+		clk.Advance(time.Second)
+		require.NoError(t, orderBook.SubmitOrder(2, 100, constant.SellOrder, nil))
+		_, err = snapshot.Save(cfg.SnapshotDir, orderBook.GetState())
+		require.NoError(t, err)
+		require.NoError(t, j.Close())
+
+		recovered, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+
+		// The earlier order at the same price is matched first
+		require.NoError(t, recovered.SubmitOrder(3, 100, constant.BuyOrder, nil))
+		trades := recovered.GetTrades()
+		require.Len(t, trades, 1)
+		require.Equal(t, uint(1), trades[0].MakerCustomerID)
+	})
+
+	t.Run("Tightened risk limits do not block replay", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		submitCommands(t, orderBook, clk, 0)
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		// Limits that would refuse every journaled order. Pegs repriced by the
+		// replayed commands are checked, so the pegged order's customer is spared
+		checker := risk.NewChecker(risk.Limits{MaxOrderPrice: 1})
+		checker.SetLimits(8, risk.Limits{})
+		cfg.Risk = checker
+		recovered, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+
+		// Live orders are checked
+		err = recovered.SubmitOrder(60, 120, constant.SellOrder, nil)
+		require.ErrorIs(t, err, reject.ErrRiskLimit)
+	})
+
+	t.Run("Tightened trading rules do not block replay", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		submitCommands(t, orderBook, clk, 0)
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		// A tick size that would refuse the journaled prices. Pegs repriced by
+		// the replayed commands follow the rules, this one stays on its ticks
+		cfg.Rules = instrument.Rules{TickSize: 3}
+		recovered, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+
+		// Live orders are checked
+		err = recovered.SubmitOrder(60, 125, constant.SellOrder, nil)
+		require.ErrorIs(t, err, reject.ErrOffTick)
+	})
+
+	t.Run("Pegs cancelled when repriced replay the same", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		cfg.Risk = risk.NewChecker(risk.Limits{MaxBidNotional: 1000})
+		cfg.Rules = instrument.Rules{TickSize: 5}
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		require.NoError(t, orderBook.SubmitOrder(1, 95, constant.BuyOrder, nil))
+		pegged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Quantity: 11, OrderType: constant.BuyOrder, Peg: constant.PrimaryPeg, PegOffset: -3})
+		require.NoError(t, err)
+		require.NoError(t, orderBook.SubmitOrder(3, 105, constant.BuyOrder, nil))
+		require.NotContains(t, orderBook.GetOrders(), pegged)
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		recovered, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+	})
+
+	t.Run("Day orders replay without the calendar", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cal, err := calendar.New(calendar.Config{Timezone: "Europe/London", Open: "08:00", Close: "16:30"})
+		require.NoError(t, err)
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger, module.WithCalendar(cal))
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 1, OrderType: constant.BuyOrder, TimeInForce: constant.GoodForDay})
+		require.NoError(t, err)
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		// The GTT resolved when the order was accepted is journaled
+		recovered, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+	})
+
+	t.Run("Snapshot inside a circuit breaker window", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		cb, err := breaker.New(breaker.Config{MoveBps: 1000, Window: "1m", Halt: "5m"})
+		require.NoError(t, err)
+		orderBook, j, err := recovery.Recover(cfg, logger, module.WithCircuitBreaker(cb))
+		require.NoError(t, err)
+		require.NoError(t, orderBook.SubmitOrder(1, 100, constant.SellOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 100, constant.BuyOrder, nil))
+		_, err = snapshot.Save(cfg.SnapshotDir, orderBook.GetState())
+		require.NoError(t, err)
+
+		// The trade at 120 trips the breaker against the trade before the snapshot
+		clk.Advance(10 * time.Second)
+		require.NoError(t, orderBook.SubmitOrder(1, 120, constant.SellOrder, nil))
+		require.NoError(t, orderBook.SubmitOrder(2, 120, constant.BuyOrder, nil))
+		require.Equal(t, constant.HaltedPhase, orderBook.GetPhase())
+		clk.Advance(5 * time.Minute)
+		require.NoError(t, orderBook.Resume(constant.CircuitBreakerOperator))
+		expected := stateJSON(t, orderBook)
+		require.NoError(t, j.Close())
+
+		recovered, j, err := recovery.Recover(cfg, logger, module.WithCircuitBreaker(cb))
+		require.NoError(t, err)
+		defer j.Close()
+		require.Equal(t, expected, stateJSON(t, recovered))
+	})
+
+	t.Run("Corrupted snapshot refuses to start", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		submitCommands(t, orderBook, clk, 0)
+		path, err := snapshot.Save(cfg.SnapshotDir, orderBook.GetState())
+		require.NoError(t, err)
+		require.NoError(t, j.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[len(data)-2] ^= 0x01
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		_, _, err = recovery.Recover(cfg, logger)
+		require.ErrorIs(t, err, snapshot.ErrCorrupted)
+	})
+
+	t.Run("Missing journal entries refuse to start", func(t *testing.T) {
+		dir := t.TempDir()
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		cfg := config(dir, clk)
+		orderBook, j, err := recovery.Recover(cfg, logger)
+		require.NoError(t, err)
+		submitCommands(t, orderBook, clk, 0)
+		submitCommands(t, orderBook, clk, 10)
+		require.NoError(t, j.Close())
+
+		// Drop the oldest segment without a snapshot covering it
+		segments, err := filepath.Glob(filepath.Join(cfg.Journal.Dir, "*.wal"))
+		require.NoError(t, err)
+		require.Greater(t, len(segments), 1)
+		require.NoError(t, os.Remove(segments[0]))
+
+		_, _, err = recovery.Recover(cfg, logger)
+		require.ErrorIs(t, err, journal.ErrCorrupted)
+	})
+}
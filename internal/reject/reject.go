@@ -0,0 +1,82 @@
+package reject
+
+import (
+	"errors"
+)
+
+// Code is a stable machine-readable reason for refusing a command.
+// Codes never change once published, so API clients can switch on them.
+type Code string
+
+const (
+	CodeInvalidSide      Code = "INVALID_SIDE"
+	CodeInvalidPrice     Code = "INVALID_PRICE"
+	CodeOffTick          Code = "OFF_TICK"
+	CodePriceOutOfRange  Code = "PRICE_OUT_OF_RANGE"
+	CodePriceOutOfBand   Code = "PRICE_OUT_OF_BAND"
+	CodeInvalidQuantity  Code = "INVALID_QUANTITY"
+	CodeNotionalOverflow Code = "NOTIONAL_OVERFLOW"
+	CodeInvalidExpiry    Code = "INVALID_EXPIRY"
+	CodeExpiredOnArrival Code = "EXPIRED_ON_ARRIVAL"
+	CodeUnknownOrder     Code = "UNKNOWN_ORDER"
+	CodeNotOwner         Code = "NOT_OWNER"
+	CodeRiskLimit        Code = "RISK_LIMIT_BREACHED"
+	CodeNoFunds          Code = "INSUFFICIENT_FUNDS"
+	CodeNoInventory      Code = "INSUFFICIENT_INVENTORY"
+	CodeBookHalted       Code = "BOOK_HALTED"
+	CodeMarketClosed     Code = "MARKET_CLOSED"
+	CodeInvalidPhase     Code = "INVALID_PHASE_TRANSITION"
+	CodeInternal         Code = "INTERNAL"
+)
+
+// Error is a rejection with its stable code.
+// Use the sentinels below with errors.Is, or errors.As to read the code.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a rejection with the same code.
+func (e *Error) Is(target error) bool {
+	var t *Error
+	return errors.As(target, &t) && t.Code == e.Code
+}
+
+// Rejections returned by the order book. Details such as the order ID are
+// added by wrapping, e.g. fmt.Errorf("%w: %d", reject.ErrUnknownOrder, orderID).
+var (
+	ErrInvalidSide      = &Error{Code: CodeInvalidSide, Message: "invalid order type"}
+	ErrInvalidPrice     = &Error{Code: CodeInvalidPrice, Message: "invalid price"}
+	ErrOffTick          = &Error{Code: CodeOffTick, Message: "price not on a tick"}
+	ErrPriceOutOfRange  = &Error{Code: CodePriceOutOfRange, Message: "price outside the instrument limits"}
+	ErrPriceOutOfBand   = &Error{Code: CodePriceOutOfBand, Message: "price outside the price band"}
+	ErrInvalidQuantity  = &Error{Code: CodeInvalidQuantity, Message: "invalid quantity"}
+	ErrNotionalOverflow = &Error{Code: CodeNotionalOverflow, Message: "price times quantity overflows"}
+	ErrInvalidExpiry    = &Error{Code: CodeInvalidExpiry, Message: "invalid time in force"}
+	ErrExpiredOnArrival = &Error{Code: CodeExpiredOnArrival, Message: "order expired on arrival"}
+	ErrUnknownOrder     = &Error{Code: CodeUnknownOrder, Message: "order not found"}
+	ErrNotOwner         = &Error{Code: CodeNotOwner, Message: "order belongs to another customer"}
+	ErrRiskLimit        = &Error{Code: CodeRiskLimit, Message: "risk limit breached"}
+	ErrNoFunds          = &Error{Code: CodeNoFunds, Message: "insufficient funds"}
+	ErrNoInventory      = &Error{Code: CodeNoInventory, Message: "insufficient inventory"}
+	ErrBookHalted       = &Error{Code: CodeBookHalted, Message: "book halted"}
+	ErrMarketClosed     = &Error{Code: CodeMarketClosed, Message: "market phase does not accept the command"}
+	ErrInvalidPhase     = &Error{Code: CodeInvalidPhase, Message: "invalid market phase transition"}
+)
+
+// CodeOf returns the code of the rejection wrapped in err, CodeInternal for
+// any other error and an empty code for nil.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
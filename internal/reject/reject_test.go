@@ -0,0 +1,36 @@
+package reject_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// TestReject tests matching and reading rejection codes.
+func TestReject(t *testing.T) {
+	t.Run("Wrapped Rejection", func(t *testing.T) {
+		err := fmt.Errorf("%w: %d", reject.ErrUnknownOrder, 42)
+		require.ErrorIs(t, err, reject.ErrUnknownOrder)
+		require.NotErrorIs(t, err, reject.ErrNotOwner)
+		require.Equal(t, reject.CodeUnknownOrder, reject.CodeOf(err))
+		require.Equal(t, "order not found: 42", err.Error())
+
+		var rejection *reject.Error
+		require.True(t, errors.As(err, &rejection))
+		require.Equal(t, reject.CodeUnknownOrder, rejection.Code)
+	})
+
+	t.Run("Same Code Matches", func(t *testing.T) {
+		err := &reject.Error{Code: reject.CodeRiskLimit, Message: "max open orders reached"}
+		require.ErrorIs(t, err, reject.ErrRiskLimit)
+	})
+
+	t.Run("Other Errors", func(t *testing.T) {
+		require.Equal(t, reject.CodeInternal, reject.CodeOf(errors.New("disk full")))
+		require.Equal(t, reject.Code(""), reject.CodeOf(nil))
+	})
+}
@@ -0,0 +1,368 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/clock"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// Output record types written by Run.
+const (
+	RecordTrade  = "trade"
+	RecordReject = "reject"
+	RecordOrder  = "order"
+	RecordStop   = "stop"
+	RecordBook   = "book"
+)
+
+// Record is a single line of replay output.
+type Record struct {
+	Type        string       `json:"type"`
+	Seq         uint64       `json:"seq,omitempty"`
+	Code        reject.Code  `json:"code,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	Trade       *model.Trade `json:"trade,omitempty"`
+	Order       *model.Order `json:"order,omitempty"`
+	NextOrderID uint64       `json:"next_order_id,omitempty"`
+}
+
+// commandInput is a command as read from the input, with its prices, peg
+// offset and cash written as decimal amounts of the book's currency.
+type commandInput struct {
+	model.Command
+	Price     json.Number  `json:"price"`
+	StopPrice json.Number  `json:"stop_price"`
+	PegOffset json.Number  `json:"peg_offset"`
+	Cash      json.Number  `json:"cash"`
+	Filter    *filterInput `json:"filter"`
+}
+
+// filterInput is a mass cancel filter with its price range written as
+// decimal amounts.
+type filterInput struct {
+	model.MassCancelFilter
+	MinPrice json.Number `json:"min_price"`
+	MaxPrice json.Number `json:"max_price"`
+}
+
+// tradeOutput is a trade with its price and fees written as decimal amounts.
+type tradeOutput struct {
+	*model.Trade
+	Price    json.Number `json:"price"`
+	MakerFee json.Number `json:"maker_fee,omitempty"`
+	TakerFee json.Number `json:"taker_fee,omitempty"`
+}
+
+// orderOutput is an order with its prices and peg offset written as decimal
+// amounts.
+type orderOutput struct {
+	*model.Order
+	Price     json.Number `json:"price"`
+	StopPrice json.Number `json:"stop_price,omitempty"`
+	PegOffset json.Number `json:"peg_offset,omitempty"`
+	PegLimit  json.Number `json:"peg_limit,omitempty"`
+}
+
+// recordOutput is a record as written to the output.
+type recordOutput struct {
+	Record
+	Trade *tradeOutput `json:"trade,omitempty"`
+	Order *orderOutput `json:"order,omitempty"`
+}
+
+// tradeCollector keeps the trades published by the order book.
+type tradeCollector struct {
+	trades []*model.Trade
+}
+
+func (c *tradeCollector) Publish(events ...event.Event) {
+	for _, e := range events {
+		if executed, ok := e.(event.TradeExecuted); ok {
+			trade := executed.Trade
+			c.trades = append(c.trades, &trade)
+		}
+	}
+}
+
+// Run reads commands from r, applies them in order to a fresh order book
+// configured with opts and writes the resulting trades, rejected commands and
+// final book state to w. The book's clock is driven by the command
+// timestamps, so the same input always produces the same output. Prices,
+// peg offsets, cash and fees are read and written as decimal amounts of the
+// book's currency.
+func Run(r io.Reader, w io.Writer, logger *zap.Logger, opts ...module.Option) error {
+	replayClock := clock.NewReplay(nil)
+	collector := &tradeCollector{}
+	opts = append(opts, module.WithClock(replayClock), module.WithEventBus(collector))
+	orderBook := module.NewOrderBookUCase(logger, opts...)
+	currency := orderBook.GetCurrency()
+
+	encoder := &recordEncoder{encoder: json.NewEncoder(w), currency: currency}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		cmd, err := parseCommand(scanner.Bytes(), currency)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if cmd.Seq == 0 {
+			cmd.Seq = uint64(line)
+		}
+
+		// Commands without a timestamp inherit the previous one
+		replayClock.Set(cmd.Timestamp)
+
+		collector.trades = nil
+		if err := Apply(orderBook, cmd); err != nil {
+			if err := encoder.Encode(Record{Type: RecordReject, Seq: cmd.Seq, Code: reject.CodeOf(err), Error: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Emit the trades produced by this command
+		for _, trade := range collector.trades {
+			if err := encoder.Encode(Record{Type: RecordTrade, Seq: cmd.Seq, Trade: trade}); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return writeBook(encoder, orderBook)
+}
+
+// parseCommand decodes a command, reading its amounts in the currency.
+func parseCommand(line []byte, currency model.Currency) (model.Command, error) {
+	var in commandInput
+	if err := json.Unmarshal(line, &in); err != nil {
+		return model.Command{}, err
+	}
+
+	cmd := in.Command
+	var err error
+	if cmd.Price, err = parseAmount(currency, in.Price); err != nil {
+		return model.Command{}, fmt.Errorf("price: %w", err)
+	}
+	if cmd.StopPrice, err = parseAmount(currency, in.StopPrice); err != nil {
+		return model.Command{}, fmt.Errorf("stop_price: %w", err)
+	}
+	if cmd.PegOffset, err = parseOffset(currency, in.PegOffset); err != nil {
+		return model.Command{}, fmt.Errorf("peg_offset: %w", err)
+	}
+	cash, err := parseAmount(currency, in.Cash)
+	if err != nil {
+		return model.Command{}, fmt.Errorf("cash: %w", err)
+	}
+	cmd.Cash = uint64(cash)
+
+	cmd.Filter = nil
+	if in.Filter != nil {
+		filter := in.Filter.MassCancelFilter
+		if filter.MinPrice, err = parseAmount(currency, in.Filter.MinPrice); err != nil {
+			return model.Command{}, fmt.Errorf("filter min_price: %w", err)
+		}
+		if filter.MaxPrice, err = parseAmount(currency, in.Filter.MaxPrice); err != nil {
+			return model.Command{}, fmt.Errorf("filter max_price: %w", err)
+		}
+		cmd.Filter = &filter
+	}
+	return cmd, nil
+}
+
+// parseAmount reads a decimal amount, 0 if it is missing.
+func parseAmount(currency model.Currency, amount json.Number) (model.Price, error) {
+	if amount == "" {
+		return 0, nil
+	}
+	return currency.Parse(amount.String())
+}
+
+// parseOffset reads a signed decimal amount, 0 if it is missing.
+func parseOffset(currency model.Currency, amount json.Number) (int64, error) {
+	unsigned, negative := strings.CutPrefix(amount.String(), "-")
+	value, err := parseAmount(currency, json.Number(unsigned))
+	if err != nil {
+		return 0, err
+	}
+	if value > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: %q", model.ErrOverflow, amount)
+	}
+	if negative {
+		return -int64(value), nil
+	}
+	return int64(value), nil
+}
+
+// recordEncoder writes records with their amounts formatted in the currency.
+type recordEncoder struct {
+	encoder  *json.Encoder
+	currency model.Currency
+}
+
+func (e *recordEncoder) Encode(record Record) error {
+	out := recordOutput{Record: record}
+	if trade := record.Trade; trade != nil {
+		out.Trade = &tradeOutput{
+			Trade:    trade,
+			Price:    e.amount(trade.Price),
+			MakerFee: e.amount(model.Price(trade.MakerFee)),
+			TakerFee: e.amount(model.Price(trade.TakerFee)),
+		}
+	}
+	if order := record.Order; order != nil {
+		out.Order = &orderOutput{
+			Order:     order,
+			Price:     e.amount(order.Price),
+			StopPrice: e.amount(order.StopPrice),
+			PegOffset: e.offset(order.PegOffset),
+			PegLimit:  e.amount(order.PegLimit),
+		}
+	}
+	return e.encoder.Encode(out)
+}
+
+// amount formats a price or fee, empty for 0 so omitempty drops it. An empty
+// number without omitempty is written as 0.
+func (e *recordEncoder) amount(p model.Price) json.Number {
+	if p == 0 {
+		return ""
+	}
+	return json.Number(e.currency.Format(p))
+}
+
+// offset formats a signed peg offset, empty for 0.
+func (e *recordEncoder) offset(offset int64) json.Number {
+	if offset < 0 {
+		return "-" + e.amount(model.Price(-offset))
+	}
+	return e.amount(model.Price(offset))
+}
+
+// Apply dispatches a single command to the order book.
+func Apply(orderBook interfaces.OrderBookUCase, cmd model.Command) error {
+	switch cmd.Action {
+	case constant.SubmitCommand:
+		_, err := orderBook.PlaceOrder(model.OrderRequest{
+			CustomerID:       cmd.CustomerID,
+			Price:            cmd.Price,
+			StopPrice:        cmd.StopPrice,
+			Quantity:         cmd.Quantity,
+			OrderType:        cmd.OrderType,
+			GTT:              cmd.GTT,
+			KeepOnDisconnect: cmd.KeepOnDisconnect,
+			DisplayQuantity:  cmd.DisplayQuantity,
+			Peg:              cmd.Peg,
+			PegOffset:        cmd.PegOffset,
+			AllOrNone:        cmd.AllOrNone,
+			MinQuantity:      cmd.MinQuantity,
+			TimeInForce:      cmd.TimeInForce,
+			ExpireDate:       cmd.ExpireDate,
+		})
+		return err
+	case constant.CancelCommand:
+		if cmd.Admin != "" {
+			return orderBook.AdminCancelOrder(cmd.OrderID, cmd.Admin)
+		}
+		return orderBook.CancelOrder(cmd.CustomerID, cmd.OrderID)
+	case constant.MassCancelCommand:
+		var filter model.MassCancelFilter
+		if cmd.Filter != nil {
+			filter = *cmd.Filter
+		}
+		_, err := orderBook.MassCancel(filter, cmd.Admin)
+		return err
+	case constant.DisconnectCommand:
+		_, err := orderBook.CancelOnDisconnect(cmd.CustomerID)
+		return err
+	case constant.DepositCommand:
+		return orderBook.Deposit(cmd.CustomerID, cmd.Cash, cmd.Quantity)
+	case constant.WithdrawCommand:
+		return orderBook.Withdraw(cmd.CustomerID, cmd.Cash, cmd.Quantity)
+	case constant.AmendCommand:
+		return orderBook.AmendOrder(cmd.CustomerID, cmd.OrderID, cmd.Price, cmd.GTT)
+	case constant.PhaseCommand:
+		return orderBook.SetPhase(cmd.Phase, cmd.Admin)
+	case constant.HaltCommand:
+		return orderBook.Halt(cmd.Reason, cmd.Admin)
+	case constant.ResumeCommand:
+		return orderBook.Resume(cmd.Admin)
+	case constant.UncrossCommand:
+		_, err := orderBook.Uncross(cmd.Admin)
+		return err
+	case constant.ExpireCommand:
+		if cmd.OrderType == constant.BuyOrder {
+			orderBook.RemoveExpiredBuyOrders()
+		} else {
+			orderBook.RemoveExpiredSellOrders()
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown action: %q", cmd.Action)
+	}
+}
+
+// writeBook writes the resting orders, best buy first then best sell first,
+// then the stop orders waiting for their trigger in trigger order, followed
+// by a summary record.
+func writeBook(encoder *recordEncoder, orderBook interfaces.OrderBookUCase) error {
+	orders := []*model.Order{}
+	stops := []*model.Order{}
+	for _, order := range orderBook.GetOrders() {
+		if order.IsPendingStop() {
+			stops = append(stops, order)
+		} else {
+			orders = append(orders, order)
+		}
+	}
+	sortOrders(orders, false)
+	sortOrders(stops, true)
+
+	for _, order := range orders {
+		if err := encoder.Encode(Record{Type: RecordOrder, Order: order}); err != nil {
+			return err
+		}
+	}
+	for _, order := range stops {
+		if err := encoder.Encode(Record{Type: RecordStop, Order: order}); err != nil {
+			return err
+		}
+	}
+	return encoder.Encode(Record{Type: RecordBook, NextOrderID: orderBook.GetNextOrderID()})
+}
+
+// sortOrders sorts orders buys first, each side in matching priority, or in
+// trigger priority for stop orders.
+func sortOrders(orders []*model.Order, byStopPrice bool) {
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].OrderType != orders[j].OrderType {
+			return orders[i].OrderType == constant.BuyOrder
+		}
+		// Reuse the heap ordering so the output follows matching priority
+		pair := model.OrderHeap{Type: orders[i].OrderType, ByStopPrice: byStopPrice, Orders: []*model.Order{orders[i], orders[j]}}
+		return pair.Less(0, 1)
+	})
+}
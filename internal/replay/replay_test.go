@@ -0,0 +1,162 @@
+package replay_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/instrument"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+	"github.com/trungnt1811/simple-order-book/internal/replay"
+	"github.com/trungnt1811/simple-order-book/internal/util"
+)
+
+// decodeRecords splits replay output into records.
+func decodeRecords(t *testing.T, out *bytes.Buffer) []replay.Record {
+	records := []replay.Record{}
+	decoder := json.NewDecoder(out)
+	for decoder.More() {
+		var record replay.Record
+		require.NoError(t, decoder.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestRun tests replaying a command stream through the order book.
+func TestRun(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	t.Run("Trades, rejects and final book", func(t *testing.T) {
+		input := strings.Join([]string{
+			`{"ts":"2024-06-01T10:00:00Z","action":"submit","customer_id":1,"price":100,"quantity":1,"side":"sell","gtt":"2024-06-01T11:00:00Z"}`,
+			`{"ts":"2024-06-01T10:00:01Z","action":"submit","customer_id":2,"price":95,"quantity":1,"side":"buy"}`,
+			`{"ts":"2024-06-01T10:00:02Z","action":"amend","customer_id":2,"order_id":2,"price":100}`,
+			`{"ts":"2024-06-01T10:00:03Z","action":"cancel","customer_id":2,"order_id":2}`,
+			`{"ts":"2024-06-01T10:00:04Z","action":"submit","customer_id":3,"price":90,"quantity":1,"side":"buy"}`,
+			`{"ts":"2024-06-01T10:00:04Z","action":"submit","customer_id":4,"price":90,"quantity":1,"side":"buy"}`,
+		}, "\n")
+
+		var out bytes.Buffer
+		require.NoError(t, replay.Run(strings.NewReader(input), &out, logger))
+		records := decodeRecords(t, &out)
+
+		require.Len(t, records, 5, "Expected 1 trade, 1 reject, 2 orders and a book record")
+
+		// The amended buy order crosses the resting sell order
+		require.Equal(t, replay.RecordTrade, records[0].Type)
+		require.Equal(t, uint64(3), records[0].Seq)
+		require.Equal(t, uint64(2), records[0].Trade.TakerOrderID)
+		require.Equal(t, uint64(1), records[0].Trade.MakerOrderID)
+		require.Equal(t, model.Price(100), records[0].Trade.Price)
+
+		// The matched order can no longer be cancelled
+		require.Equal(t, replay.RecordReject, records[1].Type)
+		require.Equal(t, uint64(4), records[1].Seq)
+		require.Equal(t, reject.CodeUnknownOrder, records[1].Code)
+
+		// Orders with identical timestamps keep submission order
+		require.Equal(t, replay.RecordOrder, records[2].Type)
+		require.Equal(t, uint64(3), records[2].Order.ID)
+		require.Equal(t, uint64(4), records[3].Order.ID)
+
+		require.Equal(t, replay.RecordBook, records[4].Type)
+		require.Equal(t, uint64(5), records[4].NextOrderID)
+	})
+
+	t.Run("Same input produces same output", func(t *testing.T) {
+		input := `{"ts":"2024-06-01T10:00:00Z","action":"submit","customer_id":1,"price":100,"quantity":1,"side":"sell"}
+{"ts":"2024-06-01T10:00:01Z","action":"submit","customer_id":2,"price":101,"quantity":1,"side":"buy"}
+{"ts":"2024-06-01T10:00:02Z","action":"submit","customer_id":2,"price":99,"quantity":1,"side":"buy"}`
+
+		var first, second bytes.Buffer
+		require.NoError(t, replay.Run(strings.NewReader(input), &first, logger))
+		require.NoError(t, replay.Run(strings.NewReader(input), &second, logger))
+		require.Equal(t, first.String(), second.String())
+	})
+
+	t.Run("Expired GTT against replay clock", func(t *testing.T) {
+		input := `{"ts":"2024-06-01T10:00:00Z","action":"submit","customer_id":1,"price":100,"quantity":1,"side":"sell","gtt":"2024-06-01T10:30:00Z"}
+{"ts":"2024-06-01T11:00:00Z","action":"submit","customer_id":2,"price":100,"quantity":1,"side":"buy"}`
+
+		var out bytes.Buffer
+		require.NoError(t, replay.Run(strings.NewReader(input), &out, logger))
+		records := decodeRecords(t, &out)
+
+		// The sell order expired before the buy order arrived, so the buy order rests
+		require.Len(t, records, 2)
+		require.Equal(t, replay.RecordOrder, records[0].Type)
+		require.Equal(t, uint64(2), records[0].Order.ID)
+	})
+
+	t.Run("Missing quantity", func(t *testing.T) {
+		input := `{"ts":"2024-06-01T10:00:00Z","action":"submit","customer_id":1,"price":100,"side":"sell"}`
+
+		var out bytes.Buffer
+		require.NoError(t, replay.Run(strings.NewReader(input), &out, logger))
+		records := decodeRecords(t, &out)
+		require.Equal(t, replay.RecordReject, records[0].Type)
+		require.Equal(t, reject.CodeInvalidQuantity, records[0].Code)
+	})
+
+	t.Run("Book options and pending stops", func(t *testing.T) {
+		input := strings.Join([]string{
+			`{"ts":"2024-06-01T10:00:00Z","action":"submit","customer_id":1,"price":102,"quantity":1,"side":"sell"}`,
+			`{"ts":"2024-06-01T10:00:01Z","action":"submit","customer_id":2,"price":100,"quantity":1,"side":"sell"}`,
+			`{"ts":"2024-06-01T10:00:02Z","action":"submit","customer_id":3,"price":110,"stop_price":105,"quantity":1,"side":"buy"}`,
+		}, "\n")
+
+		var out bytes.Buffer
+		rules := instrument.Rules{TickSize: 5}
+		require.NoError(t, replay.Run(strings.NewReader(input), &out, logger, module.WithTradingRules(rules)))
+		records := decodeRecords(t, &out)
+		require.Len(t, records, 4)
+
+		// The off-tick order is refused by the trading rules
+		require.Equal(t, replay.RecordReject, records[0].Type)
+		require.Equal(t, reject.CodeOffTick, records[0].Code)
+
+		// The stop waiting for its trigger is not part of the book
+		require.Equal(t, replay.RecordOrder, records[1].Type)
+		require.Equal(t, uint64(1), records[1].Order.ID)
+		require.Equal(t, replay.RecordStop, records[2].Type)
+		require.Equal(t, uint64(2), records[2].Order.ID)
+		require.Equal(t, replay.RecordBook, records[3].Type)
+	})
+
+	t.Run("Amounts in the book's currency", func(t *testing.T) {
+		input := strings.Join([]string{
+			`{"ts":"2024-06-01T10:00:00Z","action":"submit","customer_id":1,"price":12.49,"quantity":2,"side":"sell"}`,
+			`{"ts":"2024-06-01T10:00:01Z","action":"submit","customer_id":2,"price":"12.5","quantity":1,"side":"buy"}`,
+		}, "\n")
+
+		var out bytes.Buffer
+		usd := model.Currency{Code: "USD", Scale: 2}
+		require.NoError(t, replay.Run(strings.NewReader(input), &out, logger, module.WithCurrency(usd)))
+
+		// Prices are written back as decimal amounts
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 3)
+		require.Contains(t, lines[0], `"type":"trade"`)
+		require.Contains(t, lines[0], `"price":12.49`)
+		require.Contains(t, lines[1], `"type":"order"`)
+		require.Contains(t, lines[1], `"price":12.49`)
+		require.Contains(t, lines[1], `"remaining":1`)
+
+		// Digits beyond the scale are refused rather than rounded
+		err := replay.Run(strings.NewReader(`{"action":"submit","customer_id":1,"price":12.495,"quantity":1,"side":"sell"}`), &out, logger, module.WithCurrency(usd))
+		require.ErrorIs(t, err, model.ErrPriceSyntax)
+	})
+
+	t.Run("Malformed line", func(t *testing.T) {
+		var out bytes.Buffer
+		err := replay.Run(strings.NewReader(`{"action":`), &out, logger)
+		require.Error(t, err)
+	})
+}
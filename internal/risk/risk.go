@@ -0,0 +1,121 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+)
+
+// Limits are the pre-trade limits of a customer. A zero limit is not enforced.
+type Limits struct {
+	MaxOrderPrice   model.Price `json:"max_order_price"`
+	MaxOpenOrders   int         `json:"max_open_orders"`
+	MaxBidNotional  uint64      `json:"max_bid_notional"`  // Resting buy notional including the new order
+	MaxDeviationBps uint        `json:"max_deviation_bps"` // Fat-finger band around the last trade price, in basis points
+}
+
+// Config holds the default limits and per-customer overrides.
+type Config struct {
+	Default   Limits            `json:"default"`
+	Customers map[string]Limits `json:"customers"` // Keyed by customer ID
+}
+
+// checker enforces the limits of every customer.
+type checker struct {
+	defaults  Limits
+	customers map[uint]Limits
+	mtx       sync.RWMutex
+}
+
+// NewChecker creates a new risk checker applying defaults to every customer
+// without limits of their own.
+func NewChecker(defaults Limits) *checker {
+	return &checker{
+		defaults:  defaults,
+		customers: make(map[uint]Limits),
+	}
+}
+
+// LoadChecker creates a risk checker from a JSON config file.
+func LoadChecker(path string) (*checker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("risk config %s: %w", path, err)
+	}
+
+	c := NewChecker(cfg.Default)
+	for key, limits := range cfg.Customers {
+		customerID, err := strconv.ParseUint(key, 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("risk config %s: invalid customer ID %q", path, key)
+		}
+		c.SetLimits(uint(customerID), limits)
+	}
+	return c, nil
+}
+
+// SetLimits replaces the limits of a customer.
+func (c *checker) SetLimits(customerID uint, limits Limits) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.customers[customerID] = limits
+}
+
+// Limits returns the limits applied to a customer.
+func (c *checker) Limits(customerID uint) Limits {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if limits, ok := c.customers[customerID]; ok {
+		return limits
+	}
+	return c.defaults
+}
+
+// Check returns a reject.ErrRiskLimit error describing the first limit the
+// order would breach, or nil.
+func (c *checker) Check(req model.OrderRequest, exposure model.RiskExposure) error {
+	limits := c.Limits(req.CustomerID)
+
+	// Check max order price
+	if limits.MaxOrderPrice != 0 && req.Price > limits.MaxOrderPrice {
+		return fmt.Errorf("%w: price %d above max order price %d", reject.ErrRiskLimit, req.Price, limits.MaxOrderPrice)
+	}
+
+	// Check max open orders
+	if limits.MaxOpenOrders != 0 && exposure.OpenOrders >= limits.MaxOpenOrders {
+		return fmt.Errorf("%w: %d open orders, max %d", reject.ErrRiskLimit, exposure.OpenOrders, limits.MaxOpenOrders)
+	}
+
+	// Check max bid notional
+	if limits.MaxBidNotional != 0 && req.OrderType == constant.BuyOrder {
+		notional, err := req.Price.Notional(req.Quantity)
+		if err == nil {
+			notional, err = model.AddNotional(exposure.BidNotional, notional)
+		}
+		if err != nil || notional > limits.MaxBidNotional {
+			return fmt.Errorf("%w: bid notional %d above max %d", reject.ErrRiskLimit, notional, limits.MaxBidNotional)
+		}
+	}
+
+	// Check fat-finger deviation from the last trade
+	if limits.MaxDeviationBps != 0 && exposure.LastTradePrice != 0 && req.Price != 0 {
+		if !req.Price.WithinBand(exposure.LastTradePrice, limits.MaxDeviationBps) {
+			return fmt.Errorf("%w: price %d deviates more than %d bps from last trade %d",
+				reject.ErrRiskLimit, req.Price, limits.MaxDeviationBps, exposure.LastTradePrice)
+		}
+	}
+	return nil
+}
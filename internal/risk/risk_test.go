@@ -0,0 +1,83 @@
+package risk_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/reject"
+	"github.com/trungnt1811/simple-order-book/internal/risk"
+)
+
+// buy returns a buy request of customer 1 for tests.
+func buy(price model.Price, quantity uint) model.OrderRequest {
+	return model.OrderRequest{CustomerID: 1, Price: price, Quantity: quantity, OrderType: constant.BuyOrder}
+}
+
+// TestChecker tests each pre-trade limit.
+func TestChecker(t *testing.T) {
+	testCases := []struct {
+		name     string
+		limits   risk.Limits
+		req      model.OrderRequest
+		exposure model.RiskExposure
+		breached bool
+	}{
+		{"No Limits", risk.Limits{}, buy(1_000_000, 1000), model.RiskExposure{OpenOrders: 1000}, false},
+		{"Max Order Price", risk.Limits{MaxOrderPrice: 100}, buy(101, 1), model.RiskExposure{}, true},
+		{"At Max Order Price", risk.Limits{MaxOrderPrice: 100}, buy(100, 1), model.RiskExposure{}, false},
+		{"Max Open Orders", risk.Limits{MaxOpenOrders: 2}, buy(100, 1), model.RiskExposure{OpenOrders: 2}, true},
+		{"Below Max Open Orders", risk.Limits{MaxOpenOrders: 2}, buy(100, 1), model.RiskExposure{OpenOrders: 1}, false},
+		{"Max Bid Notional", risk.Limits{MaxBidNotional: 1000}, buy(100, 3), model.RiskExposure{BidNotional: 800}, true},
+		{"At Max Bid Notional", risk.Limits{MaxBidNotional: 1000}, buy(100, 2), model.RiskExposure{BidNotional: 800}, false},
+		{
+			"Bid Notional Ignores Sells", risk.Limits{MaxBidNotional: 1000},
+			model.OrderRequest{CustomerID: 1, Price: 100, Quantity: 50, OrderType: constant.SellOrder},
+			model.RiskExposure{BidNotional: 1000}, false,
+		},
+		{"Fat Finger Above", risk.Limits{MaxDeviationBps: 1000}, buy(111, 1), model.RiskExposure{LastTradePrice: 100}, true},
+		{"Fat Finger Below", risk.Limits{MaxDeviationBps: 1000}, buy(89, 1), model.RiskExposure{LastTradePrice: 100}, true},
+		{"Within Fat Finger Band", risk.Limits{MaxDeviationBps: 1000}, buy(110, 1), model.RiskExposure{LastTradePrice: 100}, false},
+		{"Fat Finger Without Trades", risk.Limits{MaxDeviationBps: 1000}, buy(500, 1), model.RiskExposure{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := risk.NewChecker(tc.limits).Check(tc.req, tc.exposure)
+			if tc.breached {
+				require.ErrorIs(t, err, reject.ErrRiskLimit)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("Per Customer Limits", func(t *testing.T) {
+		checker := risk.NewChecker(risk.Limits{MaxOrderPrice: 100})
+		checker.SetLimits(2, risk.Limits{MaxOrderPrice: 1000})
+
+		require.Error(t, checker.Check(buy(500, 1), model.RiskExposure{}))
+		req := buy(500, 1)
+		req.CustomerID = 2
+		require.NoError(t, checker.Check(req, model.RiskExposure{}))
+	})
+
+	t.Run("Load Config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "risk.json")
+		config := `{"default":{"max_order_price":100},"customers":{"2":{"max_open_orders":5}}}`
+		require.NoError(t, os.WriteFile(path, []byte(config), 0o644))
+
+		checker, err := risk.LoadChecker(path)
+		require.NoError(t, err)
+		require.Equal(t, risk.Limits{MaxOrderPrice: 100}, checker.Limits(1))
+		require.Equal(t, risk.Limits{MaxOpenOrders: 5}, checker.Limits(2))
+
+		require.NoError(t, os.WriteFile(path, []byte(`{"customers":{"alice":{}}}`), 0o644))
+		_, err = risk.LoadChecker(path)
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// ErrNoSession is returned for a heartbeat or disconnect of a customer
+// without an open session.
+var ErrNoSession = errors.New("no session")
+
+// manager tracks one session per customer. A session stays alive while the
+// client sends heartbeats; when it disconnects or misses heartbeats for
+// longer than the timeout, the customer's orders flagged cancel-on-disconnect
+// are cancelled.
+type manager struct {
+	OrderBook interfaces.OrderBookUCase
+	Timeout   time.Duration
+	sessions  map[uint]time.Time // Last heartbeat by customer ID
+	clock     interfaces.Clock
+	logger    *zap.Logger
+	mtx       sync.Mutex
+}
+
+// NewManager creates a new session manager for the order book.
+func NewManager(orderBook interfaces.OrderBookUCase, timeout time.Duration, clock interfaces.Clock, logger *zap.Logger) interfaces.SessionManager {
+	return &manager{
+		OrderBook: orderBook,
+		Timeout:   timeout,
+		sessions:  make(map[uint]time.Time),
+		clock:     clock,
+		logger:    logger,
+	}
+}
+
+// Connect opens the session of a customer, or refreshes it if already open.
+func (m *manager) Connect(customerID uint) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.sessions[customerID] = m.clock.Now()
+	m.logger.Debug("Session connected", zap.Uint("customerID", customerID))
+}
+
+// Heartbeat keeps the session of a customer alive.
+func (m *manager) Heartbeat(customerID uint) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.sessions[customerID]; !ok {
+		return ErrNoSession
+	}
+	m.sessions[customerID] = m.clock.Now()
+	return nil
+}
+
+// Disconnect closes the session of a customer and cancels their flagged orders.
+func (m *manager) Disconnect(customerID uint) ([]uint64, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.sessions[customerID]; !ok {
+		return nil, ErrNoSession
+	}
+	delete(m.sessions, customerID)
+
+	m.logger.Info("Session disconnected", zap.Uint("customerID", customerID))
+	return m.OrderBook.CancelOnDisconnect(customerID)
+}
+
+// ExpireSessions closes every session without a heartbeat within the timeout
+// and cancels the flagged orders of their customers.
+func (m *manager) ExpireSessions() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	now := m.clock.Now()
+	for customerID, lastHeartbeat := range m.sessions {
+		if now.Sub(lastHeartbeat) <= m.Timeout {
+			continue
+		}
+		delete(m.sessions, customerID)
+
+		m.logger.Info("Session timed out", zap.Uint("customerID", customerID), zap.Time("lastHeartbeat", lastHeartbeat))
+		if _, err := m.OrderBook.CancelOnDisconnect(customerID); err != nil {
+			m.logger.Error("Failed to cancel orders on disconnect", zap.Uint("customerID", customerID), zap.Error(err))
+		}
+	}
+}
+
+// Active reports whether the customer has an open session.
+func (m *manager) Active(customerID uint) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	_, ok := m.sessions[customerID]
+	return ok
+}
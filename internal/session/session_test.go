@@ -0,0 +1,83 @@
+package session_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/clock"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/session"
+	"github.com/trungnt1811/simple-order-book/internal/util"
+)
+
+// TestManager tests cancelling orders when sessions drop.
+func TestManager(t *testing.T) {
+	logger := util.SetupLogger()
+	defer logger.Sync() // Flushes buffer, if any
+
+	timeout := 30 * time.Second
+
+	// placeOrders rests a flagged and an opted-out order for the customer
+	placeOrders := func(t *testing.T, orderBook interfaces.OrderBookUCase, customerID uint) (flagged, kept uint64) {
+		flagged, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerID, Price: 100, Quantity: 1, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+		kept, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: customerID, Price: 99, Quantity: 1, OrderType: constant.BuyOrder, KeepOnDisconnect: true})
+		require.NoError(t, err)
+		return flagged, kept
+	}
+
+	t.Run("Timed Out Session Cancels Flagged Orders", func(t *testing.T) {
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		sessions := session.NewManager(orderBook, timeout, clk, logger)
+
+		sessions.Connect(1)
+		sessions.Connect(2)
+		flagged, kept := placeOrders(t, orderBook, 1)
+		otherFlagged, _ := placeOrders(t, orderBook, 2)
+
+		// Heartbeats keep customer 2 alive
+		clk.Advance(20 * time.Second)
+		require.NoError(t, sessions.Heartbeat(2))
+		clk.Advance(20 * time.Second)
+		sessions.ExpireSessions()
+
+		require.False(t, sessions.Active(1), "Expected the session of customer 1 to time out")
+		require.True(t, sessions.Active(2))
+		_, exists := orderBook.GetOrders()[flagged]
+		require.False(t, exists, "Flagged order %d should be cancelled", flagged)
+		_, exists = orderBook.GetOrders()[kept]
+		require.True(t, exists, "Opted-out order %d should be kept", kept)
+		_, exists = orderBook.GetOrders()[otherFlagged]
+		require.True(t, exists, "Orders of live sessions should be kept")
+
+		// The timed out customer must reconnect
+		require.ErrorIs(t, sessions.Heartbeat(1), session.ErrNoSession)
+	})
+
+	t.Run("Disconnect", func(t *testing.T) {
+		clk := clock.NewManual(time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC))
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clk))
+		sessions := session.NewManager(orderBook, timeout, clk, logger)
+
+		sessions.Connect(1)
+		flagged, kept := placeOrders(t, orderBook, 1)
+
+		// Amending keeps the flag
+		require.NoError(t, orderBook.AmendOrder(1, flagged, 101, nil))
+
+		cancelled, err := sessions.Disconnect(1)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{flagged}, cancelled)
+		require.Equal(t, 1, len(orderBook.QueryOrders(1)))
+		require.Equal(t, kept, orderBook.QueryOrders(1)[0].ID)
+
+		_, err = sessions.Disconnect(1)
+		require.ErrorIs(t, err, session.ErrNoSession)
+	})
+}
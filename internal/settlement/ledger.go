@@ -0,0 +1,302 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+var (
+	// ErrUnbalanced is returned for an entry whose debits and credits differ.
+	ErrUnbalanced = errors.New("unbalanced entry")
+
+	// ErrInvariant is returned when the ledger as a whole does not balance.
+	ErrInvariant = errors.New("ledger invariant violated")
+)
+
+// Asset is what a ledger account holds.
+type Asset string
+
+const (
+	Cash      Asset = "cash"
+	Inventory Asset = "inventory"
+)
+
+// FeeOwner owns the accounts collecting trading fees.
+const FeeOwner = "fees"
+
+// Account identifies a ledger account by owner and asset.
+type Account struct {
+	Owner string `json:"owner"` // "customer:<id>" or FeeOwner
+	Asset Asset  `json:"asset"`
+}
+
+// CustomerAccount returns the account of a customer for an asset.
+func CustomerAccount(customerID uint, asset Asset) Account {
+	return Account{Owner: fmt.Sprintf("customer:%d", customerID), Asset: asset}
+}
+
+// Posting moves an amount out of (debit) or into (credit) an account.
+type Posting struct {
+	Account Account `json:"account"`
+	Debit   uint64  `json:"debit,omitempty"`
+	Credit  uint64  `json:"credit,omitempty"`
+}
+
+// Entry is a balanced set of postings: for every asset the debits equal the credits.
+type Entry struct {
+	ID        uint64    `json:"id"`
+	TradeID   uint64    `json:"trade_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Postings  []Posting `json:"postings"`
+}
+
+// StatementLine is a posting to a customer account with the running balance.
+type StatementLine struct {
+	EntryID   uint64    `json:"entry_id"`
+	TradeID   uint64    `json:"trade_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Asset     Asset     `json:"asset"`
+	Debit     uint64    `json:"debit,omitempty"`
+	Credit    uint64    `json:"credit,omitempty"`
+	Balance   int64     `json:"balance"`
+}
+
+// Ledger records the settlement obligations created by trades as
+// double-entry postings. A negative customer balance is owed by the
+// customer, a positive one is owed to them.
+type Ledger struct {
+	entries  []Entry
+	balances map[Account]int64
+	trades   map[uint64]int // Entry index by trade ID
+	nextID   uint64
+	mtx      sync.RWMutex
+}
+
+// NewLedger creates a new empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		balances: make(map[Account]int64),
+		trades:   make(map[uint64]int),
+		nextID:   1,
+	}
+}
+
+// TradeEntry returns the postings settling a trade: the buyer pays the
+// seller the trade price times quantity in cash, the seller delivers the
+// quantity of inventory to the buyer, and the maker and taker pay their fees
+// into the fee account.
+func TradeEntry(trade model.Trade) Entry {
+	buyer, seller := trade.BuyerCustomerID(), trade.SellerCustomerID()
+	cost := trade.Notional()
+	quantity := uint64(trade.Quantity)
+
+	entry := Entry{
+		TradeID:   trade.ID,
+		Timestamp: trade.Timestamp,
+		Postings: []Posting{
+			{Account: CustomerAccount(buyer, Cash), Debit: cost},
+			{Account: CustomerAccount(seller, Cash), Credit: cost},
+			{Account: CustomerAccount(seller, Inventory), Debit: quantity},
+			{Account: CustomerAccount(buyer, Inventory), Credit: quantity},
+		},
+	}
+
+	feeAccount := Account{Owner: FeeOwner, Asset: Cash}
+	if trade.MakerFee > 0 {
+		entry.Postings = append(entry.Postings,
+			Posting{Account: CustomerAccount(trade.MakerCustomerID, Cash), Debit: trade.MakerFee},
+			Posting{Account: feeAccount, Credit: trade.MakerFee},
+		)
+	}
+	if trade.TakerFee > 0 {
+		entry.Postings = append(entry.Postings,
+			Posting{Account: CustomerAccount(trade.TakerCustomerID, Cash), Debit: trade.TakerFee},
+			Posting{Account: feeAccount, Credit: trade.TakerFee},
+		)
+	}
+	return entry
+}
+
+// PostTrade posts the settlement entry of a trade. Trades already posted are ignored.
+func (l *Ledger) PostTrade(trade model.Trade) error {
+	return l.Post(TradeEntry(trade))
+}
+
+// Post records a balanced entry. Entries of a trade already posted are ignored.
+func (l *Ledger) Post(entry Entry) error {
+	if err := checkBalanced(entry); err != nil {
+		return err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if _, ok := l.trades[entry.TradeID]; ok {
+		return nil
+	}
+
+	entry.ID = l.nextID
+	l.nextID++
+	for _, posting := range entry.Postings {
+		l.balances[posting.Account] += int64(posting.Credit) - int64(posting.Debit)
+	}
+	l.trades[entry.TradeID] = len(l.entries)
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+// Balance returns the balance of an account.
+func (l *Ledger) Balance(account Account) int64 {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return l.balances[account]
+}
+
+// Entries returns the posted entries, oldest first.
+func (l *Ledger) Entries() []Entry {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// CheckInvariant verifies that the balances of every asset sum to zero,
+// i.e. nothing was created or lost by settlement.
+func (l *Ledger) CheckInvariant() error {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	totals := make(map[Asset]int64)
+	for account, balance := range l.balances {
+		totals[account.Asset] += balance
+	}
+	for asset, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("%w: %s balances sum to %d", ErrInvariant, asset, total)
+		}
+	}
+	return nil
+}
+
+// Statement returns the postings to the accounts of a customer with running
+// balances, oldest first.
+func (l *Ledger) Statement(customerID uint) []StatementLine {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+
+	owner := CustomerAccount(customerID, Cash).Owner
+	balances := make(map[Asset]int64)
+	lines := []StatementLine{}
+	for _, entry := range l.entries {
+		for _, posting := range entry.Postings {
+			if posting.Account.Owner != owner {
+				continue
+			}
+			balances[posting.Account.Asset] += int64(posting.Credit) - int64(posting.Debit)
+			lines = append(lines, StatementLine{
+				EntryID:   entry.ID,
+				TradeID:   entry.TradeID,
+				Timestamp: entry.Timestamp,
+				Asset:     posting.Account.Asset,
+				Debit:     posting.Debit,
+				Credit:    posting.Credit,
+				Balance:   balances[posting.Account.Asset],
+			})
+		}
+	}
+	return lines
+}
+
+// Report is the result of reconciling the ledger against a trade log.
+type Report struct {
+	Trades     int      `json:"trades"`
+	Entries    int      `json:"entries"`
+	Missing    []uint64 `json:"missing,omitempty"`    // Trades without a ledger entry
+	Unexpected []uint64 `json:"unexpected,omitempty"` // Entries for trades not in the log
+	Mismatched []uint64 `json:"mismatched,omitempty"` // Entries that do not settle their trade
+	Invariant  string   `json:"invariant,omitempty"`  // Invariant violation, if any
+}
+
+// OK reports whether the ledger and the trade log agree.
+func (r Report) OK() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0 && len(r.Mismatched) == 0 && r.Invariant == ""
+}
+
+// Reconcile compares the ledger with the trade log: every trade must have
+// exactly the entry that settles it, and every entry must belong to a trade.
+// Postings added to a trade entry, such as adjustments, are allowed. Entries
+// of trades older than the log, which may only hold the latest trades, are
+// not reported.
+func (l *Ledger) Reconcile(trades []*model.Trade) Report {
+	report := Report{Trades: len(trades)}
+	if err := l.CheckInvariant(); err != nil {
+		report.Invariant = err.Error()
+	}
+
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	report.Entries = len(l.entries)
+
+	var first uint64
+	if len(trades) > 0 {
+		first = trades[0].ID
+	}
+	seen := make(map[uint64]bool, len(trades))
+	for _, trade := range trades {
+		seen[trade.ID] = true
+		index, ok := l.trades[trade.ID]
+		if !ok {
+			report.Missing = append(report.Missing, trade.ID)
+			continue
+		}
+		if !settles(l.entries[index], TradeEntry(*trade)) {
+			report.Mismatched = append(report.Mismatched, trade.ID)
+		}
+	}
+	for tradeID := range l.trades {
+		if !seen[tradeID] && tradeID >= first {
+			report.Unexpected = append(report.Unexpected, tradeID)
+		}
+	}
+	sort.Slice(report.Unexpected, func(i, j int) bool {
+		return report.Unexpected[i] < report.Unexpected[j]
+	})
+	return report
+}
+
+// checkBalanced returns ErrUnbalanced unless the debits of every asset equal its credits.
+func checkBalanced(entry Entry) error {
+	totals := make(map[Asset]int64)
+	for _, posting := range entry.Postings {
+		totals[posting.Account.Asset] += int64(posting.Credit) - int64(posting.Debit)
+	}
+	for asset, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("%w: trade %d: %s off by %d", ErrUnbalanced, entry.TradeID, asset, total)
+		}
+	}
+	return nil
+}
+
+// settles reports whether entry contains every posting of expected.
+func settles(entry, expected Entry) bool {
+	remaining := append([]Posting(nil), entry.Postings...)
+	for _, want := range expected.Postings {
+		found := false
+		for i, posting := range remaining {
+			if posting == want {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
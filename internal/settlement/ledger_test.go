@@ -0,0 +1,130 @@
+package settlement_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/clock"
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/module"
+	"github.com/trungnt1811/simple-order-book/internal/settlement"
+	"github.com/trungnt1811/simple-order-book/internal/util"
+	"github.com/trungnt1811/simple-order-book/worker"
+)
+
+var timestamp = time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+// trade returns a trade where customer 1 buys from customer 2.
+func trade(id uint64, price model.Price, quantity uint) *model.Trade {
+	return &model.Trade{
+		ID: id, TakerOrderID: id * 10, MakerOrderID: id*10 + 1, TakerCustomerID: 1, MakerCustomerID: 2,
+		TakerSide: constant.BuyOrder, Price: price, Quantity: quantity, Timestamp: timestamp,
+	}
+}
+
+// TestLedger tests posting trades and reporting on the ledger.
+func TestLedger(t *testing.T) {
+	t.Run("Post Trades", func(t *testing.T) {
+		ledger := settlement.NewLedger()
+		require.NoError(t, ledger.PostTrade(*trade(1, 100, 3)))
+		require.NoError(t, ledger.PostTrade(*trade(2, 110, 1)))
+		require.NoError(t, ledger.PostTrade(*trade(2, 110, 1)), "Posting a trade twice should be ignored")
+
+		require.Equal(t, int64(-410), ledger.Balance(settlement.CustomerAccount(1, settlement.Cash)))
+		require.Equal(t, int64(410), ledger.Balance(settlement.CustomerAccount(2, settlement.Cash)))
+		require.Equal(t, int64(4), ledger.Balance(settlement.CustomerAccount(1, settlement.Inventory)))
+		require.Equal(t, int64(-4), ledger.Balance(settlement.CustomerAccount(2, settlement.Inventory)))
+		require.Len(t, ledger.Entries(), 2)
+		require.NoError(t, ledger.CheckInvariant())
+	})
+
+	t.Run("Post Trade with Fees", func(t *testing.T) {
+		ledger := settlement.NewLedger()
+		withFees := trade(1, 100, 3)
+		withFees.MakerFee, withFees.TakerFee = 2, 5
+		require.NoError(t, ledger.PostTrade(*withFees))
+
+		require.Equal(t, int64(-305), ledger.Balance(settlement.CustomerAccount(1, settlement.Cash)))
+		require.Equal(t, int64(298), ledger.Balance(settlement.CustomerAccount(2, settlement.Cash)))
+		require.Equal(t, int64(7), ledger.Balance(settlement.Account{Owner: settlement.FeeOwner, Asset: settlement.Cash}))
+		require.NoError(t, ledger.CheckInvariant())
+		require.True(t, ledger.Reconcile([]*model.Trade{withFees}).OK())
+	})
+
+	t.Run("Unbalanced Entry", func(t *testing.T) {
+		ledger := settlement.NewLedger()
+		err := ledger.Post(settlement.Entry{TradeID: 1, Postings: []settlement.Posting{
+			{Account: settlement.CustomerAccount(1, settlement.Cash), Debit: 100},
+			{Account: settlement.CustomerAccount(2, settlement.Cash), Credit: 99},
+		}})
+		require.ErrorIs(t, err, settlement.ErrUnbalanced)
+		require.Empty(t, ledger.Entries())
+	})
+
+	t.Run("Statement", func(t *testing.T) {
+		ledger := settlement.NewLedger()
+		require.NoError(t, ledger.PostTrade(*trade(1, 100, 3)))
+		require.NoError(t, ledger.PostTrade(*trade(2, 110, 1)))
+
+		lines := ledger.Statement(1)
+		require.Len(t, lines, 4, "Expected a cash and an inventory line per trade")
+		require.Equal(t, settlement.Cash, lines[0].Asset)
+		require.Equal(t, uint64(300), lines[0].Debit)
+		require.Equal(t, int64(-300), lines[0].Balance)
+		require.Equal(t, settlement.Inventory, lines[1].Asset)
+		require.Equal(t, int64(3), lines[1].Balance)
+		require.Equal(t, int64(-410), lines[2].Balance)
+		require.Equal(t, uint64(2), lines[3].TradeID)
+
+		require.Empty(t, ledger.Statement(3))
+	})
+
+	t.Run("Reconcile", func(t *testing.T) {
+		ledger := settlement.NewLedger()
+		require.NoError(t, ledger.PostTrade(*trade(1, 100, 3)))
+		require.NoError(t, ledger.PostTrade(*trade(2, 110, 1)))
+		require.NoError(t, ledger.PostTrade(*trade(4, 90, 1)))
+
+		// Trade 2 was amended in the log, trade 3 never posted, trade 4 is unknown
+		report := ledger.Reconcile([]*model.Trade{trade(1, 100, 3), trade(2, 111, 1), trade(3, 100, 1)})
+		require.False(t, report.OK())
+		require.Equal(t, 3, report.Trades)
+		require.Equal(t, 3, report.Entries)
+		require.Equal(t, []uint64{3}, report.Missing)
+		require.Equal(t, []uint64{2}, report.Mismatched)
+		require.Equal(t, []uint64{4}, report.Unexpected)
+
+		// Entries older than a trimmed log are not unexpected
+		report = ledger.Reconcile([]*model.Trade{trade(2, 110, 1)})
+		require.Equal(t, []uint64{4}, report.Unexpected)
+	})
+
+	t.Run("Settle Trades From The Event Bus", func(t *testing.T) {
+		logger := util.SetupLogger()
+		defer logger.Sync() // Flushes buffer, if any
+
+		bus := event.NewBus()
+		defer bus.Close()
+		ledger := settlement.NewLedger()
+		settler := worker.NewSettler(bus, ledger, logger)
+		defer settler.Run()()
+
+		orderBook := module.NewOrderBookUCase(logger, module.WithClock(clock.NewManual(timestamp)), module.WithEventBus(bus))
+		_, err := orderBook.PlaceOrder(model.OrderRequest{CustomerID: 2, Price: 100, Quantity: 2, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 3, Price: 101, Quantity: 3, OrderType: constant.SellOrder})
+		require.NoError(t, err)
+		_, err = orderBook.PlaceOrder(model.OrderRequest{CustomerID: 1, Price: 101, Quantity: 4, OrderType: constant.BuyOrder})
+		require.NoError(t, err)
+
+		bus.Flush()
+		report := ledger.Reconcile(orderBook.GetTrades())
+		require.True(t, report.OK(), "Unexpected report %+v", report)
+		require.Equal(t, 2, report.Entries)
+		require.Equal(t, int64(-402), ledger.Balance(settlement.CustomerAccount(1, settlement.Cash)))
+	})
+}
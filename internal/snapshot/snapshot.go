@@ -0,0 +1,165 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+const (
+	filePrefix = "snapshot-"
+	fileExt    = ".json"
+
+	// keep is the number of snapshots retained after a save
+	keep = 2
+)
+
+// ErrCorrupted is returned when a snapshot does not match its checksum.
+var ErrCorrupted = errors.New("snapshot corrupted")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Save atomically writes state to dir and prunes older snapshots.
+// The file starts with a CRC32C checksum line followed by the JSON state.
+func Save(dir string, state model.BookState) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, fileName(state.Seq))
+	tmp, err := os.CreateTemp(dir, filePrefix+"*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := fmt.Fprintf(tmp, "%08x\n", crc32.Checksum(data, crcTable)); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	if err := syncDir(dir); err != nil {
+		return "", err
+	}
+
+	return path, prune(dir)
+}
+
+// LoadLatest reads the newest snapshot in dir. It returns nil if there is none
+// and ErrCorrupted if the newest snapshot fails its checksum.
+func LoadLatest(dir string) (*model.BookState, error) {
+	paths, err := list(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return Load(paths[len(paths)-1])
+}
+
+// Load reads and verifies a single snapshot file.
+func Load(path string) (*model.BookState, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, data, found := bytes.Cut(raw, []byte("\n"))
+	if !found {
+		return nil, fmt.Errorf("%w: %s: missing checksum", ErrCorrupted, filepath.Base(path))
+	}
+
+	var checksum uint32
+	if _, err := fmt.Sscanf(string(header), "%08x", &checksum); err != nil {
+		return nil, fmt.Errorf("%w: %s: invalid checksum", ErrCorrupted, filepath.Base(path))
+	}
+	if crc32.Checksum(data, crcTable) != checksum {
+		return nil, fmt.Errorf("%w: %s: checksum mismatch", ErrCorrupted, filepath.Base(path))
+	}
+
+	var state model.BookState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrCorrupted, filepath.Base(path), err)
+	}
+	return &state, nil
+}
+
+// fileName returns the snapshot file name for a sequence number.
+func fileName(seq uint64) string {
+	return fmt.Sprintf("%s%020d%s", filePrefix, seq, fileExt)
+}
+
+// list returns the snapshot paths in dir, oldest first.
+func list(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, filePrefix) || !strings.HasSuffix(name, fileExt) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	// Zero-padded names sort in sequence order
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// prune removes all but the newest snapshots.
+func prune(dir string) error {
+	paths, err := list(dir)
+	if err != nil {
+		return err
+	}
+	for len(paths) > keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// syncDir flushes directory metadata so the renamed snapshot survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
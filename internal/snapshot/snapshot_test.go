@@ -0,0 +1,79 @@
+package snapshot_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/snapshot"
+)
+
+// bookState returns a small book state for tests.
+func bookState(seq uint64) model.BookState {
+	timestamp := time.Date(2024, 6, 1, 10, 0, 0, 123456789, time.UTC)
+	gtt := timestamp.Add(time.Hour)
+	return model.BookState{
+		Seq:         seq,
+		NextOrderID: 3,
+		NextTradeID: 1,
+		Orders: []*model.Order{
+			{ID: 1, CustomerID: 7, Price: 100, Timestamp: timestamp, OrderType: constant.BuyOrder, GTT: &gtt},
+			{ID: 2, CustomerID: 8, Price: 110, Timestamp: timestamp, OrderType: constant.SellOrder},
+		},
+	}
+}
+
+// TestSnapshot tests saving and loading snapshots.
+func TestSnapshot(t *testing.T) {
+	t.Run("Save and load latest", func(t *testing.T) {
+		dir := t.TempDir()
+		_, err := snapshot.Save(dir, bookState(5))
+		require.NoError(t, err)
+		_, err = snapshot.Save(dir, bookState(9))
+		require.NoError(t, err)
+
+		state, err := snapshot.LoadLatest(dir)
+		require.NoError(t, err)
+		require.Equal(t, uint64(9), state.Seq)
+		require.Equal(t, uint64(3), state.NextOrderID)
+		require.Len(t, state.Orders, 2)
+		require.True(t, state.Orders[0].Timestamp.Equal(bookState(9).Orders[0].Timestamp), "Expected timestamps to survive with nanosecond precision")
+		require.Equal(t, constant.SellOrder, state.Orders[1].OrderType)
+	})
+
+	t.Run("No snapshot", func(t *testing.T) {
+		state, err := snapshot.LoadLatest(filepath.Join(t.TempDir(), "missing"))
+		require.NoError(t, err)
+		require.Nil(t, state)
+	})
+
+	t.Run("Older snapshots are pruned", func(t *testing.T) {
+		dir := t.TempDir()
+		for seq := uint64(1); seq <= 4; seq++ {
+			_, err := snapshot.Save(dir, bookState(seq))
+			require.NoError(t, err)
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, "snapshot-*.json"))
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		path, err := snapshot.Save(dir, bookState(5))
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		data[len(data)-3] ^= 0x01
+		require.NoError(t, os.WriteFile(path, data, 0o644))
+
+		_, err = snapshot.LoadLatest(dir)
+		require.ErrorIs(t, err, snapshot.ErrCorrupted)
+	})
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/storage"
+)
+
+// memoryStorage keeps orders, trades and customers in maps.
+// It is meant for tests and for running without a database.
+type memoryStorage struct {
+	orders    map[uint64]*model.OrderRecord
+	trades    map[uint64]*model.Trade
+	customers map[uint]*model.Customer
+	mtx       sync.RWMutex
+}
+
+// NewStorage creates a new in-memory storage.
+func NewStorage() interfaces.Storage {
+	return &memoryStorage{
+		orders:    make(map[uint64]*model.OrderRecord),
+		trades:    make(map[uint64]*model.Trade),
+		customers: make(map[uint]*model.Customer),
+	}
+}
+
+// SaveOrder inserts or replaces an order record.
+func (s *memoryStorage) SaveOrder(record *model.OrderRecord) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	copied := *record
+	s.orders[record.ID] = &copied
+	return nil
+}
+
+// GetOrder returns the order record with the given ID.
+func (s *memoryStorage) GetOrder(orderID uint64) (*model.OrderRecord, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	record, ok := s.orders[orderID]
+	if !ok {
+		return nil, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// ListCustomerOrders returns the order records of a customer sorted by ID.
+func (s *memoryStorage) ListCustomerOrders(customerID uint) ([]*model.OrderRecord, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	records := []*model.OrderRecord{}
+	for _, record := range s.orders {
+		if record.CustomerID == customerID {
+			copied := *record
+			records = append(records, &copied)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return records, nil
+}
+
+// SaveTrade inserts a trade, ignoring trades that are already stored.
+func (s *memoryStorage) SaveTrade(trade *model.Trade) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.trades[trade.ID]; !ok {
+		copied := *trade
+		s.trades[trade.ID] = &copied
+	}
+	return nil
+}
+
+// ListCustomerTrades returns the trades a customer took part in sorted by ID.
+func (s *memoryStorage) ListCustomerTrades(customerID uint) ([]*model.Trade, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	trades := []*model.Trade{}
+	for _, trade := range s.trades {
+		if trade.TakerCustomerID == customerID || trade.MakerCustomerID == customerID {
+			copied := *trade
+			trades = append(trades, &copied)
+		}
+	}
+	sort.Slice(trades, func(i, j int) bool {
+		return trades[i].ID < trades[j].ID
+	})
+	return trades, nil
+}
+
+// SaveCustomer inserts or replaces a customer.
+func (s *memoryStorage) SaveCustomer(customer *model.Customer) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	copied := *customer
+	s.customers[customer.ID] = &copied
+	return nil
+}
+
+// GetCustomer returns the customer with the given ID.
+func (s *memoryStorage) GetCustomer(customerID uint) (*model.Customer, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	customer, ok := s.customers[customerID]
+	if !ok {
+		return nil, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	copied := *customer
+	return &copied, nil
+}
+
+// Close is a no-op for the in-memory storage.
+func (s *memoryStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,361 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite" // Pure Go SQLite driver
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/storage"
+)
+
+// timeFormat is a fixed-width RFC 3339 layout, so stored UTC timestamps
+// sort and compare correctly as text in SQL.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// schema creates the reporting tables.
+const schema = `
+CREATE TABLE IF NOT EXISTS orders (
+	id           INTEGER PRIMARY KEY,
+	customer_id  INTEGER NOT NULL,
+	price        INTEGER NOT NULL,
+	stop_price   INTEGER NOT NULL DEFAULT 0,
+	quantity     INTEGER NOT NULL,
+	remaining    INTEGER NOT NULL,
+	side         TEXT    NOT NULL,
+	timestamp    TEXT    NOT NULL,
+	gtt          TEXT,
+	status       TEXT    NOT NULL,
+	cancelled_by TEXT    NOT NULL DEFAULT '',
+	updated_at   TEXT    NOT NULL,
+
+	cancel_on_disconnect INTEGER NOT NULL DEFAULT 0,
+	triggered            INTEGER NOT NULL DEFAULT 0,
+	display_quantity     INTEGER NOT NULL DEFAULT 0,
+	visible              INTEGER NOT NULL DEFAULT 0,
+	peg                  TEXT    NOT NULL DEFAULT '',
+	peg_offset           INTEGER NOT NULL DEFAULT 0,
+	peg_limit            INTEGER NOT NULL DEFAULT 0,
+	all_or_none          INTEGER NOT NULL DEFAULT 0,
+	min_quantity         INTEGER NOT NULL DEFAULT 0,
+	time_in_force        TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS orders_customer_id ON orders (customer_id);
+
+CREATE TABLE IF NOT EXISTS trades (
+	id                INTEGER PRIMARY KEY,
+	taker_order_id    INTEGER NOT NULL,
+	maker_order_id    INTEGER NOT NULL,
+	taker_customer_id INTEGER NOT NULL,
+	maker_customer_id INTEGER NOT NULL,
+	taker_side        TEXT    NOT NULL,
+	price             INTEGER NOT NULL,
+	quantity          INTEGER NOT NULL,
+	maker_fee         INTEGER NOT NULL DEFAULT 0,
+	taker_fee         INTEGER NOT NULL DEFAULT 0,
+	timestamp         TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS trades_taker_customer_id ON trades (taker_customer_id);
+CREATE INDEX IF NOT EXISTS trades_maker_customer_id ON trades (maker_customer_id);
+
+CREATE TABLE IF NOT EXISTS customers (
+	id         INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	email      TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+`
+
+// addedOrderColumns are the order columns missing from databases created
+// before they were added to the schema, with their definitions.
+var addedOrderColumns = [][2]string{
+	{"cancel_on_disconnect", "INTEGER NOT NULL DEFAULT 0"},
+	{"triggered", "INTEGER NOT NULL DEFAULT 0"},
+	{"display_quantity", "INTEGER NOT NULL DEFAULT 0"},
+	{"visible", "INTEGER NOT NULL DEFAULT 0"},
+	{"peg", "TEXT NOT NULL DEFAULT ''"},
+	{"peg_offset", "INTEGER NOT NULL DEFAULT 0"},
+	{"peg_limit", "INTEGER NOT NULL DEFAULT 0"},
+	{"all_or_none", "INTEGER NOT NULL DEFAULT 0"},
+	{"min_quantity", "INTEGER NOT NULL DEFAULT 0"},
+	{"time_in_force", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// orderColumns lists the order columns in the order scanOrder reads them.
+const orderColumns = `id, customer_id, price, stop_price, quantity, remaining, side, timestamp, gtt, status, cancelled_by, updated_at,
+	cancel_on_disconnect, triggered, display_quantity, visible, peg, peg_offset, peg_limit, all_or_none, min_quantity, time_in_force`
+
+// sqliteStorage persists orders, trades and customers in an embedded SQLite database.
+type sqliteStorage struct {
+	db *sql.DB
+}
+
+// NewStorage opens (or creates) the SQLite database at path and applies the schema.
+func NewStorage(path string) (interfaces.Storage, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA busy_timeout=5000"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := addColumns(db, "orders", addedOrderColumns); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &sqliteStorage{db: db}, nil
+}
+
+// addColumns adds the columns a table created by an older schema lacks.
+func addColumns(db *sql.DB, table string, columns [][2]string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if existing[column[0]] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column[0], column[1])); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, column[0], err)
+		}
+	}
+	return nil
+}
+
+// SaveOrder inserts or replaces an order record.
+func (s *sqliteStorage) SaveOrder(record *model.OrderRecord) error {
+	_, err := s.db.Exec(`
+		INSERT INTO orders (`+orderColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			customer_id          = excluded.customer_id,
+			price                = excluded.price,
+			stop_price           = excluded.stop_price,
+			quantity             = excluded.quantity,
+			remaining            = excluded.remaining,
+			side                 = excluded.side,
+			timestamp            = excluded.timestamp,
+			gtt                  = excluded.gtt,
+			status               = excluded.status,
+			cancelled_by         = excluded.cancelled_by,
+			updated_at           = excluded.updated_at,
+			cancel_on_disconnect = excluded.cancel_on_disconnect,
+			triggered            = excluded.triggered,
+			display_quantity     = excluded.display_quantity,
+			visible              = excluded.visible,
+			peg                  = excluded.peg,
+			peg_offset           = excluded.peg_offset,
+			peg_limit            = excluded.peg_limit,
+			all_or_none          = excluded.all_or_none,
+			min_quantity         = excluded.min_quantity,
+			time_in_force        = excluded.time_in_force`,
+		record.ID, record.CustomerID, record.Price, record.StopPrice, record.Quantity, record.Remaining, sideText(record.OrderType),
+		formatTime(record.Timestamp), formatOptionalTime(record.GTT), string(record.Status), record.CancelledBy, formatTime(record.UpdatedAt),
+		record.CancelOnDisconnect, record.Triggered, record.DisplayQuantity, record.Visible, string(record.Peg), record.PegOffset,
+		record.PegLimit, record.AllOrNone, record.MinQuantity, string(record.TimeInForce),
+	)
+	return err
+}
+
+// GetOrder returns the order record with the given ID.
+func (s *sqliteStorage) GetOrder(orderID uint64) (*model.OrderRecord, error) {
+	row := s.db.QueryRow(`
+		SELECT `+orderColumns+`
+		FROM orders WHERE id = ?`, orderID)
+	record, err := scanOrder(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("order %d: %w", orderID, storage.ErrNotFound)
+	}
+	return record, err
+}
+
+// ListCustomerOrders returns the order records of a customer sorted by ID.
+func (s *sqliteStorage) ListCustomerOrders(customerID uint) ([]*model.OrderRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT `+orderColumns+`
+		FROM orders WHERE customer_id = ? ORDER BY id`, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*model.OrderRecord{}
+	for rows.Next() {
+		record, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// SaveTrade inserts a trade, ignoring trades that are already stored.
+func (s *sqliteStorage) SaveTrade(trade *model.Trade) error {
+	_, err := s.db.Exec(`
+		INSERT INTO trades (id, taker_order_id, maker_order_id, taker_customer_id, maker_customer_id, taker_side, price, quantity, maker_fee, taker_fee, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING`,
+		trade.ID, trade.TakerOrderID, trade.MakerOrderID, trade.TakerCustomerID, trade.MakerCustomerID,
+		sideText(trade.TakerSide), trade.Price, trade.Quantity, trade.MakerFee, trade.TakerFee, formatTime(trade.Timestamp),
+	)
+	return err
+}
+
+// ListCustomerTrades returns the trades a customer took part in sorted by ID.
+func (s *sqliteStorage) ListCustomerTrades(customerID uint) ([]*model.Trade, error) {
+	rows, err := s.db.Query(`
+		SELECT id, taker_order_id, maker_order_id, taker_customer_id, maker_customer_id, taker_side, price, quantity, maker_fee, taker_fee, timestamp
+		FROM trades WHERE taker_customer_id = ? OR maker_customer_id = ? ORDER BY id`, customerID, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []*model.Trade{}
+	for rows.Next() {
+		var trade model.Trade
+		var side, timestamp string
+		if err := rows.Scan(&trade.ID, &trade.TakerOrderID, &trade.MakerOrderID, &trade.TakerCustomerID,
+			&trade.MakerCustomerID, &side, &trade.Price, &trade.Quantity, &trade.MakerFee, &trade.TakerFee, &timestamp); err != nil {
+			return nil, err
+		}
+		if err := trade.TakerSide.UnmarshalText([]byte(side)); err != nil {
+			return nil, err
+		}
+		if trade.Timestamp, err = parseTime(timestamp); err != nil {
+			return nil, err
+		}
+		trades = append(trades, &trade)
+	}
+	return trades, rows.Err()
+}
+
+// SaveCustomer inserts or replaces a customer.
+func (s *sqliteStorage) SaveCustomer(customer *model.Customer) error {
+	_, err := s.db.Exec(`
+		INSERT INTO customers (id, name, email, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			name       = excluded.name,
+			email      = excluded.email,
+			created_at = excluded.created_at`,
+		customer.ID, customer.Name, customer.Email, formatTime(customer.CreatedAt),
+	)
+	return err
+}
+
+// GetCustomer returns the customer with the given ID.
+func (s *sqliteStorage) GetCustomer(customerID uint) (*model.Customer, error) {
+	var customer model.Customer
+	var createdAt string
+	err := s.db.QueryRow(`SELECT id, name, email, created_at FROM customers WHERE id = ?`, customerID).
+		Scan(&customer.ID, &customer.Name, &customer.Email, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("customer %d: %w", customerID, storage.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if customer.CreatedAt, err = parseTime(createdAt); err != nil {
+		return nil, err
+	}
+	return &customer, nil
+}
+
+// Close closes the database.
+func (s *sqliteStorage) Close() error {
+	return s.db.Close()
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanOrder reads an order record from a row.
+func scanOrder(row scanner) (*model.OrderRecord, error) {
+	var record model.OrderRecord
+	var side, timestamp, status, updatedAt, peg, timeInForce string
+	var gtt sql.NullString
+	if err := row.Scan(&record.ID, &record.CustomerID, &record.Price, &record.StopPrice, &record.Quantity, &record.Remaining,
+		&side, &timestamp, &gtt, &status, &record.CancelledBy, &updatedAt,
+		&record.CancelOnDisconnect, &record.Triggered, &record.DisplayQuantity, &record.Visible, &peg, &record.PegOffset,
+		&record.PegLimit, &record.AllOrNone, &record.MinQuantity, &timeInForce); err != nil {
+		return nil, err
+	}
+
+	if err := record.OrderType.UnmarshalText([]byte(side)); err != nil {
+		return nil, err
+	}
+	record.Status = constant.OrderStatus(status)
+	record.Peg = constant.PegType(peg)
+	record.TimeInForce = constant.TimeInForce(timeInForce)
+
+	var err error
+	if record.Timestamp, err = parseTime(timestamp); err != nil {
+		return nil, err
+	}
+	if record.UpdatedAt, err = parseTime(updatedAt); err != nil {
+		return nil, err
+	}
+	if gtt.Valid {
+		parsed, err := parseTime(gtt.String)
+		if err != nil {
+			return nil, err
+		}
+		record.GTT = &parsed
+	}
+	return &record, nil
+}
+
+// sideText returns the stored representation of an order side.
+func sideText(orderType constant.OrderType) string {
+	text, _ := orderType.MarshalText()
+	return string(text)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+func formatOptionalTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(timeFormat, s)
+}
@@ -0,0 +1,203 @@
+package storage_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+	"github.com/trungnt1811/simple-order-book/internal/storage"
+	"github.com/trungnt1811/simple-order-book/internal/storage/memory"
+	"github.com/trungnt1811/simple-order-book/internal/storage/sqlite"
+)
+
+// implementations returns a fresh instance of every storage implementation.
+func implementations(t *testing.T) map[string]interfaces.Storage {
+	sqliteStorage, err := sqlite.NewStorage(filepath.Join(t.TempDir(), "orderbook.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { sqliteStorage.Close() })
+
+	return map[string]interfaces.Storage{
+		"memory": memory.NewStorage(),
+		"sqlite": sqliteStorage,
+	}
+}
+
+// fullOrder returns an order record using every order field.
+func fullOrder(timestamp time.Time) *model.OrderRecord {
+	gtt := timestamp.Add(time.Hour)
+	return &model.OrderRecord{
+		Order: model.Order{
+			ID: 1, CustomerID: 7, Price: 100, StopPrice: 95, Quantity: 50, Remaining: 30, Timestamp: timestamp,
+			OrderType: constant.BuyOrder, GTT: &gtt, CancelOnDisconnect: true, Triggered: true,
+			DisplayQuantity: 10, Visible: 4, Peg: constant.PrimaryPeg, PegOffset: -2, PegLimit: 105,
+			AllOrNone: true, MinQuantity: 5, TimeInForce: constant.GoodTillDate,
+		},
+		Status:      constant.OrderCancelled,
+		CancelledBy: "repriced",
+		UpdatedAt:   timestamp,
+	}
+}
+
+// requireSameOrder checks that a stored record matches the saved one.
+func requireSameOrder(t *testing.T, expected, stored *model.OrderRecord) {
+	require.True(t, stored.Timestamp.Equal(expected.Timestamp), "Expected timestamp to round-trip")
+	require.True(t, stored.GTT.Equal(*expected.GTT), "Expected GTT to round-trip")
+	require.True(t, stored.UpdatedAt.Equal(expected.UpdatedAt), "Expected update time to round-trip")
+
+	// Compare the remaining fields with the times taken from the saved record
+	normalized := *stored
+	normalized.Timestamp, normalized.GTT, normalized.UpdatedAt = expected.Timestamp, expected.GTT, expected.UpdatedAt
+	require.Equal(t, *expected, normalized)
+}
+
+// TestStorage runs the same checks against every storage implementation.
+func TestStorage(t *testing.T) {
+	timestamp := time.Date(2024, 6, 1, 10, 0, 0, 500, time.UTC)
+	gtt := timestamp.Add(time.Hour)
+
+	for name, store := range implementations(t) {
+		t.Run(name+"/Orders", func(t *testing.T) {
+			record := &model.OrderRecord{
+				Order:     model.Order{ID: 1, CustomerID: 7, Price: 100, Quantity: 5, Remaining: 3, Timestamp: timestamp, OrderType: constant.BuyOrder, GTT: &gtt},
+				Status:    constant.OrderOpen,
+				UpdatedAt: timestamp,
+			}
+			require.NoError(t, store.SaveOrder(record))
+			require.NoError(t, store.SaveOrder(&model.OrderRecord{
+				Order:     model.Order{ID: 2, CustomerID: 7, Price: 110, Quantity: 1, Remaining: 1, Timestamp: timestamp, OrderType: constant.SellOrder},
+				Status:    constant.OrderOpen,
+				UpdatedAt: timestamp,
+			}))
+
+			// Saving again updates the status
+			record.Status = constant.OrderCancelled
+			record.CancelledBy = "admin:alice"
+			require.NoError(t, store.SaveOrder(record))
+
+			stored, err := store.GetOrder(1)
+			require.NoError(t, err)
+			require.Equal(t, constant.OrderCancelled, stored.Status)
+			require.Equal(t, "admin:alice", stored.CancelledBy)
+			require.Equal(t, constant.BuyOrder, stored.OrderType)
+			require.Equal(t, uint(5), stored.Quantity)
+			require.Equal(t, uint(3), stored.Remaining)
+			require.True(t, stored.Timestamp.Equal(timestamp), "Expected timestamp to round-trip")
+			require.True(t, stored.GTT.Equal(gtt), "Expected GTT to round-trip")
+
+			records, err := store.ListCustomerOrders(7)
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			require.Equal(t, uint64(2), records[1].ID)
+			require.Nil(t, records[1].GTT)
+
+			_, err = store.GetOrder(3)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		})
+
+		t.Run(name+"/Every Order Field", func(t *testing.T) {
+			record := fullOrder(timestamp)
+			record.ID = 10
+			require.NoError(t, store.SaveOrder(record))
+
+			stored, err := store.GetOrder(10)
+			require.NoError(t, err)
+			requireSameOrder(t, record, stored)
+		})
+
+		t.Run(name+"/Trades", func(t *testing.T) {
+			trade := &model.Trade{
+				ID: 1, TakerOrderID: 4, MakerOrderID: 3, TakerCustomerID: 8, MakerCustomerID: 9,
+				TakerSide: constant.SellOrder, Price: 100, Quantity: 2, MakerFee: 1, TakerFee: 3, Timestamp: timestamp,
+			}
+			require.NoError(t, store.SaveTrade(trade))
+			require.NoError(t, store.SaveTrade(trade), "Saving a trade twice should be ignored")
+
+			for _, customerID := range []uint{8, 9} {
+				trades, err := store.ListCustomerTrades(customerID)
+				require.NoError(t, err)
+				require.Len(t, trades, 1)
+				require.Equal(t, constant.SellOrder, trades[0].TakerSide)
+				require.Equal(t, uint(2), trades[0].Quantity)
+				require.Equal(t, uint64(1), trades[0].MakerFee)
+				require.Equal(t, uint64(3), trades[0].TakerFee)
+				require.True(t, trades[0].Timestamp.Equal(timestamp))
+			}
+
+			trades, err := store.ListCustomerTrades(10)
+			require.NoError(t, err)
+			require.Empty(t, trades)
+		})
+
+		t.Run(name+"/Customers", func(t *testing.T) {
+			customer := &model.Customer{ID: 7, Name: "Alice", Email: "alice@example.com", CreatedAt: timestamp}
+			require.NoError(t, store.SaveCustomer(customer))
+
+			stored, err := store.GetCustomer(7)
+			require.NoError(t, err)
+			require.Equal(t, "Alice", stored.Name)
+			require.Equal(t, "alice@example.com", stored.Email)
+
+			_, err = store.GetCustomer(8)
+			require.ErrorIs(t, err, storage.ErrNotFound)
+		})
+	}
+}
+
+// TestSQLiteStorage tests what the SQLite file keeps across reopening.
+func TestSQLiteStorage(t *testing.T) {
+	timestamp := time.Date(2024, 6, 1, 10, 0, 0, 500, time.UTC)
+
+	t.Run("Reopen Keeps Every Order Field", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "orderbook.db")
+		store, err := sqlite.NewStorage(path)
+		require.NoError(t, err)
+		record := fullOrder(timestamp)
+		require.NoError(t, store.SaveOrder(record))
+		require.NoError(t, store.Close())
+
+		store, err = sqlite.NewStorage(path)
+		require.NoError(t, err)
+		defer store.Close()
+		stored, err := store.GetOrder(1)
+		require.NoError(t, err)
+		requireSameOrder(t, record, stored)
+	})
+
+	t.Run("Older Database Gains The New Columns", func(t *testing.T) {
+		// An orders table as created before the order options were stored
+		path := filepath.Join(t.TempDir(), "orderbook.db")
+		db, err := sql.Open("sqlite", path)
+		require.NoError(t, err)
+		_, err = db.Exec(`CREATE TABLE orders (
+			id INTEGER PRIMARY KEY, customer_id INTEGER NOT NULL, price INTEGER NOT NULL, stop_price INTEGER NOT NULL DEFAULT 0,
+			quantity INTEGER NOT NULL, remaining INTEGER NOT NULL, side TEXT NOT NULL, timestamp TEXT NOT NULL, gtt TEXT,
+			status TEXT NOT NULL, cancelled_by TEXT NOT NULL DEFAULT '', updated_at TEXT NOT NULL)`)
+		require.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO orders VALUES (2, 7, 100, 0, 5, 5, 'buy', '2024-06-01T10:00:00.000000000Z', NULL, 'open', '', '2024-06-01T10:00:00.000000000Z')`)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		store, err := sqlite.NewStorage(path)
+		require.NoError(t, err)
+		defer store.Close()
+
+		// Existing rows read with the defaults, new rows keep every field
+		old, err := store.GetOrder(2)
+		require.NoError(t, err)
+		require.Equal(t, uint(5), old.Remaining)
+		require.Empty(t, old.Peg)
+		require.False(t, old.AllOrNone)
+
+		record := fullOrder(timestamp)
+		require.NoError(t, store.SaveOrder(record))
+		stored, err := store.GetOrder(1)
+		require.NoError(t, err)
+		requireSameOrder(t, record, stored)
+	})
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// auctioneer uncrosses a book trading in periodic call auctions.
+type auctioneer struct {
+	OrderBook interfaces.OrderBookUCase
+	Interval  time.Duration
+	logger    *zap.Logger
+}
+
+// NewAuctioneer creates a new auctioneer running a call auction every interval.
+func NewAuctioneer(orderBook interfaces.OrderBookUCase, interval time.Duration, logger *zap.Logger) auctioneer {
+	return auctioneer{
+		OrderBook: orderBook,
+		Interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run uncrosses the book every interval while it is collecting orders for
+// an auction. Outside a call period the tick is skipped.
+func (a *auctioneer) Run() {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !a.OrderBook.GetPhase().IsCall() {
+			continue
+		}
+		result, err := a.OrderBook.Uncross(scheduleOperator)
+		if err != nil {
+			a.logger.Error("Failed to run call auction", zap.Error(err))
+			continue
+		}
+		a.logger.Debug("Call auction run", zap.Uint64("price", uint64(result.Price)), zap.Uint("volume", result.Volume))
+	}
+}
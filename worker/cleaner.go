@@ -49,3 +49,17 @@ func (c *cleaner) RemoveExpiredSellOrders() {
 
 	select {} // Block forever to keep the goroutine running
 }
+
+// ExpireAtSessionClose sweeps both sides as soon as each trading session
+// closes, so day and good-till-date orders expire at the close rather than
+// on the next tick.
+func (c *cleaner) ExpireAtSessionClose(calendar interfaces.TradingCalendar) {
+	for {
+		// The sweeps expire orders whose GTT is before the current time
+		closing := calendar.SessionClose(time.Now())
+		time.Sleep(time.Until(closing) + time.Millisecond)
+
+		c.OrderBook.RemoveExpiredBuyOrders()
+		c.OrderBook.RemoveExpiredSellOrders()
+	}
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// haltMonitor resumes the order book when a circuit breaker halt is over.
+type haltMonitor struct {
+	OrderBook interfaces.OrderBookUCase
+	Interval  time.Duration
+	logger    *zap.Logger
+}
+
+// NewHaltMonitor creates a new halt monitor checking every interval.
+func NewHaltMonitor(orderBook interfaces.OrderBookUCase, interval time.Duration, logger *zap.Logger) haltMonitor {
+	return haltMonitor{
+		OrderBook: orderBook,
+		Interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run starts a ticker that resumes the book, through its reopening auction,
+// once the end of a circuit breaker halt has passed. Manual halts are left
+// to the admins.
+func (m *haltMonitor) Run() {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop() // Ensure the ticker is stopped when the function exits
+
+	for now := range ticker.C {
+		until := m.OrderBook.GetHaltedUntil()
+		if until == nil || until.After(now) {
+			continue
+		}
+		if err := m.OrderBook.Resume(constant.CircuitBreakerOperator); err != nil {
+			m.logger.Error("Failed to resume after circuit breaker halt", zap.Error(err))
+		}
+	}
+}
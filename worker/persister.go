@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/model"
+)
+
+// persister copies order history and fills from the order book events into
+// storage, so reporting never runs on the matching path.
+type persister struct {
+	Events  interfaces.EventSubscriber
+	Storage interfaces.Storage
+	logger  *zap.Logger
+}
+
+// NewPersister creates a new persister storing the events published on events.
+func NewPersister(events interfaces.EventSubscriber, storage interfaces.Storage, logger *zap.Logger) persister {
+	return persister{
+		Events:  events,
+		Storage: storage,
+		logger:  logger,
+	}
+}
+
+// Run subscribes to the order book events. The returned function stops the
+// persister once the events already published are stored.
+func (p *persister) Run() (stop func()) {
+	return p.Events.Subscribe(func(e event.Event) {
+		if err := p.Handle(e); err != nil {
+			p.logger.Error("Failed to persist event", zap.String("type", string(e.EventType())), zap.Error(err))
+		}
+	})
+}
+
+// Handle stores the order or trade carried by a single event.
+func (p *persister) Handle(e event.Event) error {
+	switch e := e.(type) {
+	case event.OrderAccepted:
+		return p.saveOrder(e.Order, constant.OrderOpen, e.Header)
+	case event.OrderAmended:
+		return p.saveOrder(e.Order, constant.OrderOpen, e.Header)
+	case event.OrderRepriced:
+		return p.saveOrder(e.Order, constant.OrderOpen, e.Header)
+	case event.StopTriggered:
+		return p.saveOrder(e.Order, constant.OrderOpen, e.Header)
+	case event.OrderPartiallyFilled:
+		return p.saveOrder(e.Order, constant.OrderOpen, e.Header)
+	case event.OrderFilled:
+		return p.saveOrder(e.Order, constant.OrderFilled, e.Header)
+	case event.OrderCancelled:
+		return p.Storage.SaveOrder(&model.OrderRecord{
+			Order:       e.Order,
+			Status:      constant.OrderCancelled,
+			CancelledBy: e.CancelledBy,
+			UpdatedAt:   e.Timestamp,
+		})
+	case event.OrderExpired:
+		return p.saveOrder(e.Order, constant.OrderExpired, e.Header)
+	case event.TradeExecuted:
+		return p.Storage.SaveTrade(&e.Trade)
+	}
+	return nil
+}
+
+// saveOrder writes the order record with its latest status.
+func (p *persister) saveOrder(order model.Order, status constant.OrderStatus, header event.Header) error {
+	return p.Storage.SaveOrder(&model.OrderRecord{Order: order, Status: status, UpdatedAt: header.Timestamp})
+}
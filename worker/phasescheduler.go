@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/constant"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// scheduleOperator is the operator recorded for scheduled phase changes.
+const scheduleOperator = "schedule"
+
+// phaseScheduler moves the order book through the market phases of its calendar.
+type phaseScheduler struct {
+	OrderBook interfaces.OrderBookUCase
+	Calendar  interfaces.TradingCalendar
+	logger    *zap.Logger
+}
+
+// NewPhaseScheduler creates a new phase scheduler for the order book.
+func NewPhaseScheduler(orderBook interfaces.OrderBookUCase, calendar interfaces.TradingCalendar, logger *zap.Logger) phaseScheduler {
+	return phaseScheduler{
+		OrderBook: orderBook,
+		Calendar:  calendar,
+		logger:    logger,
+	}
+}
+
+// Run moves the book to the phase scheduled now, then to each scheduled
+// phase as its time comes. Admin overrides stand until the next change.
+func (s *phaseScheduler) Run() {
+	s.Sync()
+	for {
+		at, phase := s.Calendar.NextPhaseChange(time.Now())
+		time.Sleep(time.Until(at))
+		s.moveTo(phase)
+	}
+}
+
+// Sync moves the book to the phase scheduled now, through the closed phase
+// if the current phase cannot move there directly, e.g. after a restart.
+func (s *phaseScheduler) Sync() {
+	current, scheduled := s.OrderBook.GetPhase(), s.Calendar.PhaseAt(time.Now())
+	if current == scheduled {
+		return
+	}
+	if !current.CanMoveTo(scheduled) && scheduled != constant.ClosedPhase {
+		s.moveTo(constant.ClosedPhase)
+	}
+	s.moveTo(scheduled)
+}
+
+// moveTo sets the phase, logging a refused transition.
+func (s *phaseScheduler) moveTo(phase constant.MarketPhase) {
+	if err := s.OrderBook.SetPhase(phase, scheduleOperator); err != nil {
+		s.logger.Error("Failed to change market phase", zap.String("phase", string(phase)), zap.Error(err))
+	}
+}
@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"time"
+
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// sessionMonitor periodically closes sessions that stopped sending heartbeats.
+type sessionMonitor struct {
+	Sessions interfaces.SessionManager
+	Interval time.Duration
+}
+
+// NewSessionMonitor creates a new session monitor checking every interval.
+func NewSessionMonitor(sessions interfaces.SessionManager, interval time.Duration) sessionMonitor {
+	return sessionMonitor{
+		Sessions: sessions,
+		Interval: interval,
+	}
+}
+
+// Run starts a ticker that expires timed out sessions every interval.
+func (m *sessionMonitor) Run() {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop() // Ensure the ticker is stopped when the function exits
+
+	for range ticker.C {
+		m.Sessions.ExpireSessions()
+	}
+}
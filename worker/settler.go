@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/event"
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+)
+
+// settler posts the settlement entry of every executed trade to the ledger.
+type settler struct {
+	Events interfaces.EventSubscriber
+	Ledger interfaces.SettlementLedger
+	logger *zap.Logger
+}
+
+// NewSettler creates a new settler posting the trades published on events.
+func NewSettler(events interfaces.EventSubscriber, ledger interfaces.SettlementLedger, logger *zap.Logger) settler {
+	return settler{
+		Events: events,
+		Ledger: ledger,
+		logger: logger,
+	}
+}
+
+// Run subscribes to the order book events. The returned function stops the
+// settler once the trades already published are posted.
+func (s *settler) Run() (stop func()) {
+	return s.Events.Subscribe(func(e event.Event) {
+		trade, ok := e.(event.TradeExecuted)
+		if !ok {
+			return
+		}
+		if err := s.Ledger.PostTrade(trade.Trade); err != nil {
+			s.logger.Error("Failed to settle trade", zap.Uint64("tradeID", trade.Trade.ID), zap.Error(err))
+		}
+	})
+}
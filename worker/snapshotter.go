@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/trungnt1811/simple-order-book/internal/interfaces"
+	"github.com/trungnt1811/simple-order-book/internal/journal"
+	"github.com/trungnt1811/simple-order-book/internal/snapshot"
+)
+
+// snapshotter periodically saves the order book state so recovery only
+// has to replay the journal entries written since the last snapshot.
+type snapshotter struct {
+	OrderBook interfaces.OrderBookUCase
+	Journal   *journal.Journal
+	Dir       string
+	Interval  time.Duration
+	lastSeq   uint64
+	mtx       sync.Mutex // Serializes periodic and shutdown snapshots
+	logger    *zap.Logger
+}
+
+// NewSnapshotter creates a new snapshotter writing to dir every interval.
+func NewSnapshotter(orderBook interfaces.OrderBookUCase, j *journal.Journal, dir string, interval time.Duration, logger *zap.Logger) snapshotter {
+	return snapshotter{
+		OrderBook: orderBook,
+		Journal:   j,
+		Dir:       dir,
+		Interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run starts a ticker that takes a snapshot every interval.
+func (s *snapshotter) Run() {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop() // Ensure the ticker is stopped when the function exits
+
+	for range ticker.C {
+		if err := s.TakeSnapshot(); err != nil {
+			s.logger.Error("Failed to take snapshot", zap.Error(err))
+		}
+	}
+}
+
+// TakeSnapshot saves the current state if it changed since the last
+// snapshot, then drops journal segments the snapshot covers.
+func (s *snapshotter) TakeSnapshot() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	state := s.OrderBook.GetState()
+	if state.Seq == s.lastSeq {
+		return nil
+	}
+
+	path, err := snapshot.Save(s.Dir, state)
+	if err != nil {
+		return err
+	}
+	s.lastSeq = state.Seq
+	s.logger.Info("Snapshot saved", zap.String("path", path), zap.Uint64("seq", state.Seq))
+
+	return s.Journal.Compact(state.Seq)
+}